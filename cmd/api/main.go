@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/atharvakonge/stock-trading-simulator/internal/auth"
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
 	"github.com/atharvakonge/stock-trading-simulator/internal/db"
 	"github.com/atharvakonge/stock-trading-simulator/internal/handlers"
+	"github.com/atharvakonge/stock-trading-simulator/internal/logging"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/metrics"
 	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/atharvakonge/stock-trading-simulator/internal/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -17,68 +27,369 @@ func main() {
 		log.Println("No .env file found, using defaults or environment variables")
 	}
 
+	// Structured logs: JSON in release mode, key=value text otherwise
+	logging.Setup(os.Stderr, os.Getenv("LOG_LEVEL"), os.Getenv("GIN_MODE") == "release")
+
+	// Traces go to OTEL_EXPORTER_OTLP_ENDPOINT; unset, tracing is a no-op
+	shutdownTracing, err := tracing.Setup(context.Background(), os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database
 	if err := db.InitDB(); err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer db.CloseDB()
 
-	// Get number of workers from env or default to 5
-	numWorkers := 5
-	if workers := os.Getenv("NUM_WORKERS"); workers != "" {
-		// Parse workers string to int if needed
-		numWorkers = 5 // For simplicity, keeping default
+	// Get number of workers from env (1-100, default 5)
+	numWorkers := config.ParseWorkers(os.Getenv("NUM_WORKERS"))
+	if maxConns := db.DB.Stats().MaxOpenConnections; maxConns < numWorkers {
+		log.Printf("Warning: DB_MAX_OPEN_CONNS (%d) is below NUM_WORKERS (%d); workers will wait for connections", maxConns, numWorkers)
 	}
 
 	// Initialize trade processor
 	tradeProcessor := handlers.NewTradeProcessor(numWorkers)
+	tradeProcessor.Config = config.Load()
+
+	// Ping the database every DB_HEALTH_INTERVAL (default 5s), turning
+	// trades away and failing readiness while it's unreachable
+	healthInterval := 5 * time.Second
+	if v, err := time.ParseDuration(os.Getenv("DB_HEALTH_INTERVAL")); err == nil && v > 0 {
+		healthInterval = v
+	}
+	dbMonitor := db.NewMonitor(db.DB, healthInterval)
+	dbMonitor.Start()
+	defer dbMonitor.Stop()
+	tradeProcessor.DBMonitor = dbMonitor
+
+	// POST each committed trade to TRADE_WEBHOOK_URL, retrying a failed
+	// delivery TRADE_WEBHOOK_RETRIES times (default 2)
+	if url := os.Getenv("TRADE_WEBHOOK_URL"); url != "" {
+		webhook := handlers.NewWebhookNotifier(url)
+		if v, err := strconv.Atoi(os.Getenv("TRADE_WEBHOOK_RETRIES")); err == nil && v >= 0 {
+			webhook.Retries = v
+		}
+		tradeProcessor.Notifier = webhook
+	}
 	tradeProcessor.Start()
 	defer tradeProcessor.Stop()
 
+	// Filled limit orders execute through the same worker pool
+	handlers.SetOrderExecutor(tradeProcessor)
+
+	// Simulated price feed; set PRICE_SEED to replay the same price path and
+	// SIMULATION_CONFIG to a JSON file of per-symbol walk parameters
+	priceSeed := time.Now().UnixNano()
+	if v, err := strconv.ParseInt(os.Getenv("PRICE_SEED"), 10, 64); err == nil {
+		priceSeed = v
+	}
+	simulation := market.DefaultSimulationConfig()
+	if path := os.Getenv("SIMULATION_CONFIG"); path != "" {
+		cfg, err := market.LoadSimulationConfig(path)
+		if err != nil {
+			log.Fatal("Failed to load simulation config:", err)
+		}
+		simulation = cfg
+	}
+	priceProvider := market.NewSimulatedProvider(priceSeed, simulation)
+	priceProvider.Hours = tradeProcessor.Config.MarketHours
+	if tradeProcessor.Config.HaltFreezesPrices {
+		priceProvider.Halts = handlers.Halts
+	}
+	if cb := tradeProcessor.Config.CircuitBreaker; cb.Percent > 0 {
+		priceProvider.Breaker = market.NewCircuitBreaker(cb.Percent, cb.Window, cb.Cooldown, handlers.Halts)
+	}
+	slog.Info("Price simulator seeded", "seed", priceProvider.Seed())
+	priceProvider.Start()
+	defer priceProvider.Stop()
+
+	// Reload resting orders, filling any the price has moved through while
+	// we were down, before the hub starts matching ticks against the book
+	handlers.SetOrderStore(handlers.NewDBOrderStore(db.DB))
+	restored, err := handlers.RestoreOrders(priceProvider)
+	if err != nil {
+		log.Fatal("Failed to restore orders:", err)
+	}
+	slog.Info("Resting orders restored", "count", restored)
+
+	// Record every tick for charting, keeping PRICE_HISTORY_RETENTION (default 7 days)
+	historyRetention := 7 * 24 * time.Hour
+	if v, err := time.ParseDuration(os.Getenv("PRICE_HISTORY_RETENTION")); err == nil && v > 0 {
+		historyRetention = v
+	}
+	priceRecorder := handlers.NewPriceRecorder(priceProvider, historyRetention)
+	priceRecorder.Start()
+	defer priceRecorder.Stop()
+
+	// One hub fans the feed out to every WebSocket client, at most
+	// WS_MAX_CONNECTIONS (default 1000) at a time
+	priceHub := handlers.NewHub(priceProvider)
+	priceHub.MaxConnections = 1000
+	if v, err := strconv.Atoi(os.Getenv("WS_MAX_CONNECTIONS")); err == nil && v > 0 {
+		priceHub.MaxConnections = v
+	}
+	priceHub.Start()
+	defer priceHub.Stop()
+
+	// Cap concurrent in-flight trade requests
+	maxInFlight := int64(200)
+	if v, err := strconv.ParseInt(os.Getenv("MAX_INFLIGHT_TRADES"), 10, 64); err == nil && v > 0 {
+		maxInFlight = v
+	}
+	tradeLimiter := handlers.LimitConcurrency(maxInFlight)
+
+	// Per-user trade rate: TRADE_RATE_LIMIT a second, bursts of TRADE_RATE_BURST
+	tradeRate, tradeBurst := 5.0, 10
+	if v, err := strconv.ParseFloat(os.Getenv("TRADE_RATE_LIMIT"), 64); err == nil && v > 0 {
+		tradeRate = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("TRADE_RATE_BURST")); err == nil && v > 0 {
+		tradeBurst = v
+	}
+	userRateLimit := handlers.NewRateLimiter(tradeRate, tradeBurst).Middleware()
+
+	// Give up on a trade that hasn't executed within this long
+	const tradeTimeout = 10 * time.Second
+
+	// Emit pre-envelope response bodies for clients that haven't migrated
+	handlers.LegacyResponses = os.Getenv("LEGACY_RESPONSES") == "true"
+
 	// Set Gin mode based on environment
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// In release mode only same-host pages and ALLOWED_ORIGINS may open WebSockets
+	handlers.AllowOrigins(os.Getenv("ALLOWED_ORIGINS"), gin.Mode() != gin.ReleaseMode)
+
 	// Create Gin router
 	router := gin.Default()
 
+	// Tag every request with a correlation ID
+	router.Use(handlers.RequestID())
+
 	// API routes
 	api := router.Group("/api")
+	api.POST("/register", handlers.Register(tradeProcessor.Config.StartingBalance))
+
+	// Admin routes take ADMIN_TOKEN instead of a user's token, so the group
+	// is made before RequireAuth is added to api
+	admin := api.Group("/admin", handlers.RequireAdmin(os.Getenv("ADMIN_TOKEN")))
+
+	// Per-user WebSocket streams
+	streams := router.Group("/ws")
+
+	// With JWT_SECRET set, every API route except register and login
+	// requires a bearer token, and per-user routes and streams only serve
+	// the token's user
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		tokenTTL := 24 * time.Hour
+		if v, err := time.ParseDuration(os.Getenv("JWT_TTL")); err == nil && v > 0 {
+			tokenTTL = v
+		}
+		issuer := auth.NewIssuer(secret, tokenTTL)
+
+		api.POST("/login", handlers.Login(issuer))
+		api.Use(handlers.RequireAuth(issuer))
+		streams.Use(handlers.RequireStreamAuth(issuer))
+	} else {
+		log.Println("JWT_SECRET not set, authentication disabled")
+	}
+
 	{
 		// Trading endpoints
-		api.POST("/trades/buy", func(c *gin.Context) {
+		api.POST("/trades/buy", userRateLimit, tradeLimiter, func(c *gin.Context) {
 			var req models.BuyRequest
 			if err := c.ShouldBindJSON(&req); err != nil {
-				c.JSON(400, gin.H{"error": err.Error()})
+				handlers.RespondError(c, 400, err.Error())
+				return
+			}
+			if userID, ok := handlers.AuthenticatedUserID(c); ok {
+				req.UserID = userID
+			}
+			key, ok := handlers.IdempotencyKey(c)
+			if !ok {
+				return
+			}
+			req.IdempotencyKey = key
+			if req.DryRun, ok = handlers.DryRun(c); !ok {
 				return
 			}
 
-			result := tradeProcessor.SubmitTrade(req)
+			req.TradeType = models.TradeTypeBuy
+			if req.OrderType != "" && req.OrderType != models.OrderTypeMarket {
+				if req.DryRun {
+					handlers.RespondError(c, 400, "dryRun is only supported for market trades")
+					return
+				}
+				handlers.PlaceOrder(c, req)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request.Context(), tradeTimeout)
+			defer cancel()
+
+			result := tradeProcessor.SubmitTradeWithContext(ctx, req)
 			if !result.Success {
-				c.JSON(400, gin.H{"error": result.Error})
+				handlers.RespondError(c, result.HTTPStatus(), result.Error)
 				return
 			}
+			if result.Replayed {
+				c.Header(handlers.ReplayedHeader, "true")
+			}
 
-			c.JSON(200, gin.H{
+			response := gin.H{
 				"message":    "Trade executed successfully",
 				"trade_id":   result.TradeID,
+				"price":      result.Price,
 				"total_cost": result.TotalAmount,
-			})
+				"fee":        result.Fee,
+				"position": gin.H{
+					"quantity":           result.PositionQuantity,
+					"avg_purchase_price": result.PositionAvgPrice,
+				},
+			}
+			if result.QuotedPrice != 0 {
+				response["quoted_price"] = result.QuotedPrice
+			}
+			if result.Warning != "" {
+				response["warning"] = result.Warning
+			}
+			if result.DryRun {
+				response["message"] = "Dry run: trade not executed"
+				response["dry_run"] = true
+				response["cash_balance"] = result.CashBalance
+			}
+
+			handlers.Respond(c, 200, response)
 		})
 
-		api.POST("/trades/sell", handlers.SellStock)
+		api.POST("/trades/sell", userRateLimit, tradeLimiter, func(c *gin.Context) {
+			var req models.BuyRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				handlers.RespondError(c, 400, err.Error())
+				return
+			}
+			if userID, ok := handlers.AuthenticatedUserID(c); ok {
+				req.UserID = userID
+			}
+			key, ok := handlers.IdempotencyKey(c)
+			if !ok {
+				return
+			}
+			req.IdempotencyKey = key
+			if req.DryRun, ok = handlers.DryRun(c); !ok {
+				return
+			}
+
+			if req.OrderType != "" && req.OrderType != models.OrderTypeMarket {
+				if req.DryRun {
+					handlers.RespondError(c, 400, "dryRun is only supported for market trades")
+					return
+				}
+				req.TradeType = models.TradeTypeSell
+				handlers.PlaceOrder(c, req)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request.Context(), tradeTimeout)
+			defer cancel()
+
+			req.TradeType = models.TradeTypeSell
+			result := tradeProcessor.SubmitTradeWithContext(ctx, req)
+			if !result.Success {
+				handlers.RespondError(c, result.HTTPStatus(), result.Error)
+				return
+			}
+			if result.Replayed {
+				c.Header(handlers.ReplayedHeader, "true")
+			}
+
+			response := gin.H{
+				"message":        "Stock sold successfully",
+				"trade_id":       result.TradeID,
+				"price":          result.Price,
+				"total_proceeds": result.TotalAmount,
+				"fee":            result.Fee,
+			}
+			if result.QuotedPrice != 0 {
+				response["quoted_price"] = result.QuotedPrice
+			}
+			if result.DryRun {
+				response["message"] = "Dry run: trade not executed"
+				response["dry_run"] = true
+				response["cash_balance"] = result.CashBalance
+				response["position"] = gin.H{
+					"quantity":           result.PositionQuantity,
+					"avg_purchase_price": result.PositionAvgPrice,
+				}
+			}
+
+			handlers.Respond(c, 200, response)
+		})
+		api.POST("/trades/liquidate", userRateLimit, tradeLimiter, tradeProcessor.Liquidate)
+		api.POST("/trades/batch", userRateLimit, tradeLimiter, tradeProcessor.Batch)
+		api.POST("/trades/impact", tradeProcessor.PreviewTradeImpact)
 		api.GET("/trades/:userId", handlers.GetTradeHistory)
+		api.GET("/trades/:userId/attempts", handlers.GetTradeAttempts)
+		api.GET("/trades/:userId/summary", handlers.GetTradeSummary)
 		api.GET("/portfolio/:userId", handlers.GetPortfolio)
+		api.GET("/portfolio/:userId/exposure", handlers.GetExposure)
+		api.GET("/portfolio/:userId/allocation", handlers.GetAllocation)
+		api.GET("/portfolio/:userId/performance", handlers.GetPortfolioPerformance)
+		api.GET("/portfolio/:userId/lots", handlers.GetLots)
+		api.GET("/portfolio/:userId/holdings/:symbol", handlers.GetHolding)
+		api.GET("/portfolio/:userId/:symbol/breakeven", tradeProcessor.GetBreakEven)
+
+		// Market data
+		api.GET("/stocks", handlers.GetStocks)
+		api.GET("/stocks/simulation", handlers.GetSimulationConfig(priceProvider))
+		api.GET("/stocks/:symbol/history", handlers.GetPriceHistory)
+
+		// Resting orders
+		api.GET("/orders/:userId", handlers.GetOpenOrders)
+		api.DELETE("/orders/:orderId", handlers.CancelOrder)
+
+		// Account management
+		api.GET("/leaderboard", handlers.NewLeaderboard(5*time.Second, tradeProcessor.Config.StartingBalance).GetLeaderboard)
+		api.GET("/users/:userId", handlers.GetUser)
+		api.POST("/users/:userId/reset", tradeProcessor.ResetAccount)
+		api.POST("/users/:userId/deposit", tradeProcessor.Deposit)
+		api.POST("/users/:userId/withdraw", tradeProcessor.Withdraw)
+
+		// Operations
+		api.GET("/metrics", func(c *gin.Context) {
+			handlers.Respond(c, 200, tradeProcessor.Stats())
+		})
+
+		// Admin endpoints
+		admin.GET("/users/:userId/export", tradeProcessor.ExportAccount)
+		admin.POST("/users/import", handlers.ImportAccount)
+		admin.GET("/halts", handlers.GetHalts)
+		admin.POST("/halts/:symbol", handlers.HaltSymbol)
+		admin.DELETE("/halts/:symbol", handlers.ResumeSymbol)
+	}
+
+	// Prometheus scrape endpoint
+	registry := metrics.NewRegistry()
+	if err := tradeProcessor.RegisterMetrics(registry); err != nil {
+		log.Fatal("Failed to register metrics:", err)
 	}
+	router.GET("/metrics", gin.WrapH(metrics.Handler(registry)))
 
 	// WebSocket endpoint
-	router.GET("/ws/prices", handlers.HandleWebSocket)
+	router.GET("/ws/prices", handlers.HandleWebSocket(priceHub))
+	streams.GET("/portfolio/:userId", handlers.HandlePortfolioWebSocket(priceHub))
+	streams.GET("/trades/:userId", tradeProcessor.HandleTradeWebSocket(priceHub))
 
-	// Health check
+	// Health checks: /health/live for liveness, /health/ready for load balancers
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy"})
+		handlers.Respond(c, 200, gin.H{"status": "healthy"})
 	})
+	router.GET("/health/live", handlers.Liveness)
+	router.GET("/health/ready", tradeProcessor.Readiness)
 
 	// Serve frontend
 	router.GET("/", func(c *gin.Context) {
@@ -0,0 +1,100 @@
+// Package auth issues and verifies the HS256 JSON Web Tokens that identify
+// API users.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens and bad signatures
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned for well-signed tokens past their expiry
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// header is the only JOSE header we issue or accept, so tokens claiming
+// another algorithm (including "none") fail verification
+var header = encode([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims is the token payload
+type Claims struct {
+	UserID    int   `json:"user_id"`
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+// Issuer signs and verifies tokens with a shared secret
+type Issuer struct {
+	secret []byte
+	ttl    time.Duration
+	clock  clock.Clock
+}
+
+// NewIssuer returns an Issuer whose tokens are valid for ttl
+func NewIssuer(secret string, ttl time.Duration) *Issuer {
+	return &Issuer{secret: []byte(secret), ttl: ttl, clock: clock.Real()}
+}
+
+// Issue returns a signed token for userID and when it expires
+func (i *Issuer) Issue(userID int) (string, time.Time, error) {
+	now := i.clock.Now()
+	expires := now.Add(i.ttl)
+
+	payload, err := json.Marshal(Claims{
+		UserID:    userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := header + "." + encode(payload)
+	return unsigned + "." + encode(i.sign(unsigned)), expires, nil
+}
+
+// Verify checks the token's signature and expiry and returns its claims
+func (i *Issuer) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return Claims{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, i.sign(parts[0]+"."+parts[1])) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.UserID <= 0 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if i.clock.Now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func (i *Issuer) sign(unsigned string) []byte {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
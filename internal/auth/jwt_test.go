@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+)
+
+func TestIssuer_ValidToken(t *testing.T) {
+	issuer := NewIssuer("secret", time.Hour)
+
+	token, _, err := issuer.Issue(42)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	claims, err := issuer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.UserID != 42 {
+		t.Errorf("Expected user 42, got %d", claims.UserID)
+	}
+}
+
+func TestIssuer_ExpiredToken(t *testing.T) {
+	issuer := NewIssuer("secret", time.Hour)
+	fake := clock.NewFake(time.Now())
+	issuer.clock = fake
+
+	token, _, err := issuer.Issue(42)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	fake.Advance(2 * time.Hour)
+	if _, err := issuer.Verify(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Expected ErrExpiredToken, got %v", err)
+	}
+}
+
+func TestIssuer_TamperedToken(t *testing.T) {
+	issuer := NewIssuer("secret", time.Hour)
+	token, _, err := issuer.Issue(42)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	// Claim to be user 1 while keeping the original signature
+	forged, _, _ := NewIssuer("other-secret", time.Hour).Issue(1)
+	payload := strings.Split(forged, ".")[1]
+
+	tests := map[string]string{
+		"swapped payload": parts[0] + "." + payload + "." + parts[2],
+		"wrong secret":    forged,
+		"alg none":        encode([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + ".",
+		"truncated":       parts[0] + "." + parts[1],
+	}
+	for name, token := range tests {
+		if _, err := issuer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
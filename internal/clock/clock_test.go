@@ -0,0 +1,36 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	now := Real().Now()
+	if now.Before(before) || now.After(time.Now()) {
+		t.Errorf("Expected the system time, got %v", now)
+	}
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 17, 9, 30, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	if !f.Now().Equal(start) {
+		t.Errorf("Expected %v, got %v", start, f.Now())
+	}
+	if !f.Now().Equal(start) {
+		t.Error("Expected the fake to stand still")
+	}
+
+	f.Advance(90 * time.Minute)
+	if want := start.Add(90 * time.Minute); !f.Now().Equal(want) {
+		t.Errorf("Expected %v after Advance, got %v", want, f.Now())
+	}
+
+	f.Set(start)
+	if !f.Now().Equal(start) {
+		t.Errorf("Expected %v after Set, got %v", start, f.Now())
+	}
+}
@@ -0,0 +1,455 @@
+package config
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // MARKET_TIMEZONE works without system zone files
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+)
+
+// Config holds tunable trading behavior
+type Config struct {
+	// ConcentrationWarning adds a warning to buys that leave one holding
+	// above ConcentrationWarningPercent of total account value
+	ConcentrationWarning        bool
+	ConcentrationWarningPercent float64
+
+	// MinAccountAge is how old an account must be before it may trade
+	// AgeRestrictedSymbols or place orders above AgeRestrictedOrderValue.
+	// Zero disables the check.
+	MinAccountAge           time.Duration
+	AgeRestrictedSymbols    []string
+	AgeRestrictedOrderValue float64 // Zero means no size restriction
+
+	// Commission is charged on every buy and sell. Zero means no fees.
+	Commission Commission
+
+	// Slippage moves MARKET order fills away from the quoted price. Zero
+	// fills at the quote.
+	Slippage Slippage
+
+	// TradeIsolation is the isolation level for trade transactions. At
+	// SERIALIZABLE, correctness doesn't depend on the per-process user lock,
+	// so several API instances can share one database.
+	TradeIsolation sql.IsolationLevel
+
+	// OptimisticLocking reads holdings without locking them. Trades
+	// instead check the holding's version when writing it back and start
+	// over if another trade changed it in between.
+	OptimisticLocking bool
+
+	// CostBasis decides which purchase cost a sell's realized P&L is
+	// measured against
+	CostBasis CostBasisMethod
+
+	// PriceTolerancePercent is how far a buy's price may stray from the
+	// latest market price, either way. Zero disables the check.
+	PriceTolerancePercent float64
+
+	// IdempotencyTTL is how long a trade's idempotency key is remembered
+	IdempotencyTTL time.Duration
+
+	// UserLockTimeout is how long a trade waits for another trade by the
+	// same user before giving up. Zero waits indefinitely.
+	UserLockTimeout time.Duration
+
+	// StartingBalance is the cash a new account is created with, and what
+	// a reset restores
+	StartingBalance float64
+
+	// ShortSelling lets a sell of a stock the user doesn't hold open a
+	// short position. Opening or adding to a short requires cash of at
+	// least (100 + ShortMarginPercent)% of the account's short exposure.
+	ShortSelling       bool
+	ShortMarginPercent float64
+
+	// MarginMultiplier scales account equity into buying power: at 2, a
+	// buy may borrow up to the account's equity, leaving cash negative.
+	// 1 means no borrowing. With margin on, a buy is also rejected if it
+	// would leave equity under MaintenanceMarginPercent of the account's
+	// long positions.
+	MarginMultiplier         float64
+	MaintenanceMarginPercent float64
+
+	// MarketHours rejects trades, and pauses the price simulator, while
+	// the market is closed. Off by default: the market never closes.
+	MarketHours market.Hours
+
+	// HaltFreezesPrices stops the price simulator moving a symbol while
+	// its trading is halted
+	HaltFreezesPrices bool
+
+	// CircuitBreaker halts symbols whose simulated price moves too far too
+	// fast. Off by default.
+	CircuitBreaker CircuitBreaker
+
+	// PositionLimit caps every holding a buy or short sell may build, shorts
+	// by their size. SymbolPositionLimits replace it for the symbols they
+	// name.
+	PositionLimit        PositionLimit
+	SymbolPositionLimits map[string]PositionLimit
+}
+
+// PositionLimit caps one holding by share count, by percent of total
+// account value, or both. A zero field doesn't limit.
+type PositionLimit struct {
+	MaxShares  int
+	MaxPercent float64
+}
+
+// Allows reports whether a holding of shares, worth value out of an
+// account worth totalValue, is within the limit. Reaching a limit exactly
+// is allowed.
+func (l PositionLimit) Allows(shares int, value, totalValue float64) bool {
+	if l.MaxShares > 0 && shares > l.MaxShares {
+		return false
+	}
+	if l.MaxPercent > 0 && value > 0 {
+		if totalValue <= 0 {
+			return false
+		}
+		// Rounded so a holding priced to land on the limit isn't rejected
+		// over a floating point remainder
+		percent := math.Round(value/totalValue*100*1e6) / 1e6
+		if percent > l.MaxPercent {
+			return false
+		}
+	}
+	return true
+}
+
+// PositionLimitFor returns the limit on holdings of symbol
+func (c Config) PositionLimitFor(symbol string) PositionLimit {
+	if limit, ok := c.SymbolPositionLimits[symbol]; ok {
+		return limit
+	}
+	return c.PositionLimit
+}
+
+// CostBasisMethod selects how sold shares are costed
+type CostBasisMethod string
+
+// Cost basis methods
+const (
+	// CostBasisAverage costs sold shares at the position's average price
+	CostBasisAverage CostBasisMethod = "average"
+	// CostBasisFIFO costs sold shares at the prices of the oldest open lots
+	CostBasisFIFO CostBasisMethod = "fifo"
+)
+
+// Commission is a per-trade fee: a flat amount, plus an amount per share,
+// plus a percentage of the trade value
+type Commission struct {
+	Flat     float64
+	PerShare float64
+	Percent  float64
+}
+
+// Fee returns the commission for trading quantity shares at price, rounded
+// to cents
+func (cm Commission) Fee(quantity int, price float64) float64 {
+	fee := cm.Flat + cm.PerShare*float64(quantity) + cm.Percent/100*price*float64(quantity)
+	return math.Round(fee*100) / 100
+}
+
+// Slippage is how far a market order's fill lands from the quote, against
+// the trader, in basis points: BasisPoints on every order plus
+// PerShareBasisPoints for each share, so larger orders slip further
+type Slippage struct {
+	BasisPoints         float64
+	PerShareBasisPoints float64
+}
+
+// CircuitBreaker halts trading in a symbol for Cooldown once its price
+// moves more than Percent within Window. A zero Percent disables it.
+type CircuitBreaker struct {
+	Percent  float64
+	Window   time.Duration
+	Cooldown time.Duration
+}
+
+// FillPrice returns what quantity shares fill at when quoted at price:
+// above it for a buy, below it for a sell, rounded to cents
+func (s Slippage) FillPrice(quoted float64, quantity int, sell bool) float64 {
+	bps := s.BasisPoints + s.PerShareBasisPoints*float64(quantity)
+	if bps == 0 {
+		return quoted
+	}
+	if sell {
+		bps = -bps
+	}
+	return math.Round(quoted*(1+bps/10000)*100) / 100
+}
+
+// Worker pool sizing for NUM_WORKERS
+const (
+	DefaultWorkers = 5
+	MaxWorkers     = 100
+)
+
+// ParseWorkers parses a NUM_WORKERS value. Empty means DefaultWorkers;
+// invalid or non-positive values fall back to it with a warning, and values
+// above MaxWorkers are clamped.
+func ParseWorkers(value string) int {
+	if value == "" {
+		return DefaultWorkers
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid NUM_WORKERS %q, using %d", value, DefaultWorkers)
+		return DefaultWorkers
+	}
+	if n > MaxWorkers {
+		log.Printf("NUM_WORKERS %d exceeds maximum, using %d", n, MaxWorkers)
+		return MaxWorkers
+	}
+	return n
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() Config {
+	return Config{
+		ConcentrationWarning:        true,
+		ConcentrationWarningPercent: 30.0,
+		TradeIsolation:              sql.LevelSerializable,
+		CostBasis:                   CostBasisAverage,
+		PriceTolerancePercent:       5.0,
+		IdempotencyTTL:              24 * time.Hour,
+		UserLockTimeout:             5 * time.Second,
+		StartingBalance:             10000.0,
+		ShortMarginPercent:          50.0,
+		MarginMultiplier:            1.0,
+		MaintenanceMarginPercent:    25.0,
+		MarketHours: market.Hours{
+			Open:           9*time.Hour + 30*time.Minute,
+			Close:          16 * time.Hour,
+			Location:       newYork,
+			ClosedWeekends: true,
+		},
+		HaltFreezesPrices: true,
+		CircuitBreaker:    CircuitBreaker{Window: time.Minute, Cooldown: 5 * time.Minute},
+		PositionLimit:     PositionLimit{MaxShares: DefaultMaxPositionShares},
+	}
+}
+
+// DefaultMaxPositionShares is the default cap on shares in one holding
+const DefaultMaxPositionShares = 1000000
+
+// newYork is the default market time zone
+var newYork, _ = time.LoadLocation("America/New_York")
+
+// Load returns the default configuration with environment overrides applied
+func Load() Config {
+	cfg := Default()
+
+	cfg.ConcentrationWarning = getEnvBool("CONCENTRATION_WARNING", cfg.ConcentrationWarning)
+	cfg.ConcentrationWarningPercent = getEnvFloat("CONCENTRATION_WARNING_PERCENT", cfg.ConcentrationWarningPercent)
+
+	cfg.MinAccountAge = getEnvDuration("MIN_ACCOUNT_AGE", cfg.MinAccountAge)
+	cfg.AgeRestrictedSymbols = getEnvList("AGE_RESTRICTED_SYMBOLS", cfg.AgeRestrictedSymbols)
+	cfg.AgeRestrictedOrderValue = getEnvFloat("AGE_RESTRICTED_ORDER_VALUE", cfg.AgeRestrictedOrderValue)
+
+	cfg.Commission.Flat = getEnvFloat("COMMISSION_FLAT", cfg.Commission.Flat)
+	cfg.Commission.PerShare = getEnvFloat("COMMISSION_PER_SHARE", cfg.Commission.PerShare)
+	cfg.Commission.Percent = getEnvFloat("COMMISSION_PERCENT", cfg.Commission.Percent)
+
+	cfg.Slippage.BasisPoints = getEnvFloat("SLIPPAGE_BPS", cfg.Slippage.BasisPoints)
+	cfg.Slippage.PerShareBasisPoints = getEnvFloat("SLIPPAGE_BPS_PER_SHARE", cfg.Slippage.PerShareBasisPoints)
+
+	cfg.TradeIsolation = getEnvIsolation("TRADE_ISOLATION", cfg.TradeIsolation)
+	cfg.OptimisticLocking = getEnvBool("OPTIMISTIC_LOCKING", cfg.OptimisticLocking)
+	cfg.PriceTolerancePercent = getEnvFloat("PRICE_TOLERANCE_PERCENT", cfg.PriceTolerancePercent)
+	cfg.IdempotencyTTL = getEnvDuration("IDEMPOTENCY_TTL", cfg.IdempotencyTTL)
+	cfg.UserLockTimeout = getEnvDuration("USER_LOCK_TIMEOUT", cfg.UserLockTimeout)
+
+	cfg.ShortSelling = getEnvBool("ALLOW_SHORT_SELLING", cfg.ShortSelling)
+	cfg.ShortMarginPercent = getEnvFloat("SHORT_MARGIN_PERCENT", cfg.ShortMarginPercent)
+
+	cfg.MaintenanceMarginPercent = getEnvFloat("MAINTENANCE_MARGIN_PERCENT", cfg.MaintenanceMarginPercent)
+	if m := getEnvFloat("MARGIN_MULTIPLIER", cfg.MarginMultiplier); m >= 1 {
+		cfg.MarginMultiplier = m
+	} else {
+		log.Printf("Invalid MARGIN_MULTIPLIER %q, using %.2f", os.Getenv("MARGIN_MULTIPLIER"), cfg.MarginMultiplier)
+	}
+
+	if balance := getEnvFloat("DEFAULT_STARTING_BALANCE", cfg.StartingBalance); balance > 0 {
+		cfg.StartingBalance = balance
+	} else {
+		log.Printf("Invalid DEFAULT_STARTING_BALANCE %q, using %.2f", os.Getenv("DEFAULT_STARTING_BALANCE"), cfg.StartingBalance)
+	}
+
+	cfg.MarketHours = loadMarketHours(cfg.MarketHours)
+	cfg.HaltFreezesPrices = getEnvBool("HALT_FREEZES_PRICES", cfg.HaltFreezesPrices)
+	cfg.CircuitBreaker.Percent = getEnvFloat("CIRCUIT_BREAKER_PERCENT", cfg.CircuitBreaker.Percent)
+	cfg.CircuitBreaker.Window = getEnvDuration("CIRCUIT_BREAKER_WINDOW", cfg.CircuitBreaker.Window)
+	cfg.CircuitBreaker.Cooldown = getEnvDuration("CIRCUIT_BREAKER_COOLDOWN", cfg.CircuitBreaker.Cooldown)
+
+	cfg.PositionLimit.MaxShares = getEnvInt("MAX_POSITION_SHARES", cfg.PositionLimit.MaxShares)
+	cfg.PositionLimit.MaxPercent = getEnvFloat("MAX_POSITION_PERCENT", cfg.PositionLimit.MaxPercent)
+	cfg.SymbolPositionLimits = loadSymbolPositionLimits(cfg.SymbolPositionLimits)
+
+	switch method := CostBasisMethod(strings.ToLower(os.Getenv("COST_BASIS"))); method {
+	case CostBasisAverage, CostBasisFIFO:
+		cfg.CostBasis = method
+	}
+
+	return cfg
+}
+
+// loadMarketHours applies the MARKET_* overrides to hours. A session that
+// doesn't close after it opens is ignored with a warning.
+func loadMarketHours(hours market.Hours) market.Hours {
+	loaded := hours
+	loaded.Enabled = getEnvBool("MARKET_HOURS", hours.Enabled)
+	loaded.Open = getEnvClock("MARKET_OPEN", hours.Open)
+	loaded.Close = getEnvClock("MARKET_CLOSE", hours.Close)
+	loaded.ClosedWeekends = getEnvBool("MARKET_CLOSED_WEEKENDS", hours.ClosedWeekends)
+
+	if name := os.Getenv("MARKET_TIMEZONE"); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			loaded.Location = loc
+		} else {
+			log.Printf("Invalid MARKET_TIMEZONE %q, using %s", name, hours.Location)
+		}
+	}
+
+	if loaded.Close <= loaded.Open {
+		log.Printf("MARKET_CLOSE must be after MARKET_OPEN, using %s to %s", clockString(hours.Open), clockString(hours.Close))
+		loaded.Open, loaded.Close = hours.Open, hours.Close
+	}
+	return loaded
+}
+
+// loadSymbolPositionLimits parses SYMBOL_POSITION_LIMITS, a comma-separated
+// list like "TSLA=500,GME=10%": a plain number caps shares, one ending in %
+// caps percent of account value. Invalid entries are skipped with a warning.
+func loadSymbolPositionLimits(defaultValue map[string]PositionLimit) map[string]PositionLimit {
+	entries := getEnvList("SYMBOL_POSITION_LIMITS", nil)
+	if entries == nil {
+		return defaultValue
+	}
+
+	limits := make(map[string]PositionLimit)
+	for _, entry := range entries {
+		symbol, value, ok := strings.Cut(entry, "=")
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		value = strings.TrimSpace(value)
+		if !ok || symbol == "" {
+			log.Printf("Invalid SYMBOL_POSITION_LIMITS entry %q, skipping", entry)
+			continue
+		}
+
+		var limit PositionLimit
+		if percent, isPercent := strings.CutSuffix(value, "%"); isPercent {
+			p, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+			if err != nil || p <= 0 {
+				log.Printf("Invalid SYMBOL_POSITION_LIMITS entry %q, skipping", entry)
+				continue
+			}
+			limit.MaxPercent = p
+		} else {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				log.Printf("Invalid SYMBOL_POSITION_LIMITS entry %q, skipping", entry)
+				continue
+			}
+			limit.MaxShares = n
+		}
+		limits[symbol] = limit
+	}
+	return limits
+}
+
+// clockString formats a time since midnight like "09:30"
+func clockString(d time.Duration) string {
+	return time.Time{}.Add(d).Format("15:04")
+}
+
+// Helper function to get a time of day environment variable (e.g. "09:30")
+// with default
+func getEnvClock(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := market.ParseClock(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, value, clockString(defaultValue))
+		return defaultValue
+	}
+	return d
+}
+
+// Helper function to get a boolean environment variable with default
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to get an integer environment variable with default
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to get a float environment variable with default
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to get a duration environment variable (e.g. "72h") with default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to get an isolation level environment variable
+// (read_committed, repeatable_read or serializable) with default
+func getEnvIsolation(key string, defaultValue sql.IsolationLevel) sql.IsolationLevel {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "read_committed":
+		return sql.LevelReadCommitted
+	case "repeatable_read":
+		return sql.LevelRepeatableRead
+	case "serializable":
+		return sql.LevelSerializable
+	default:
+		return defaultValue
+	}
+}
+
+// Helper function to get a comma-separated environment variable with default
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
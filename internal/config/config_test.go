@@ -0,0 +1,297 @@
+package config
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad_DefaultsAndOverrides(t *testing.T) {
+	cfg := Load()
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Expected defaults with no env set, got %+v", cfg)
+	}
+
+	t.Setenv("CONCENTRATION_WARNING", "false")
+	t.Setenv("CONCENTRATION_WARNING_PERCENT", "45.5")
+
+	cfg = Load()
+	if cfg.ConcentrationWarning {
+		t.Error("Expected concentration warning disabled")
+	}
+	if cfg.ConcentrationWarningPercent != 45.5 {
+		t.Errorf("Expected threshold 45.5, got %.2f", cfg.ConcentrationWarningPercent)
+	}
+
+	t.Setenv("CONCENTRATION_WARNING_PERCENT", "lots")
+	if cfg = Load(); cfg.ConcentrationWarningPercent != Default().ConcentrationWarningPercent {
+		t.Errorf("Expected invalid override to fall back to default, got %.2f", cfg.ConcentrationWarningPercent)
+	}
+}
+
+func TestLoad_AccountAge(t *testing.T) {
+	t.Setenv("MIN_ACCOUNT_AGE", "72h")
+	t.Setenv("AGE_RESTRICTED_SYMBOLS", "TSLA, GOOGL,")
+	t.Setenv("AGE_RESTRICTED_ORDER_VALUE", "5000")
+
+	cfg := Load()
+	if cfg.MinAccountAge != 72*time.Hour {
+		t.Errorf("Expected min account age 72h, got %v", cfg.MinAccountAge)
+	}
+	if !reflect.DeepEqual(cfg.AgeRestrictedSymbols, []string{"TSLA", "GOOGL"}) {
+		t.Errorf("Expected restricted symbols [TSLA GOOGL], got %v", cfg.AgeRestrictedSymbols)
+	}
+	if cfg.AgeRestrictedOrderValue != 5000 {
+		t.Errorf("Expected restricted order value 5000, got %.2f", cfg.AgeRestrictedOrderValue)
+	}
+}
+
+func TestParseWorkers(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", DefaultWorkers},
+		{"20", 20},
+		{"0", DefaultWorkers},
+		{"-3", DefaultWorkers},
+		{"lots", DefaultWorkers},
+		{"500", MaxWorkers},
+	}
+
+	for _, tt := range tests {
+		if got := ParseWorkers(tt.value); got != tt.want {
+			t.Errorf("ParseWorkers(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCommission_Fee(t *testing.T) {
+	t.Setenv("COMMISSION_FLAT", "4.95")
+	t.Setenv("COMMISSION_PER_SHARE", "0.01")
+	t.Setenv("COMMISSION_PERCENT", "0.1")
+
+	cm := Load().Commission
+	if fee := cm.Fee(100, 50.0); fee != 10.95 {
+		t.Errorf("Expected fee 4.95 + 1.00 + 5.00 = 10.95, got %.2f", fee)
+	}
+
+	if fee := (Commission{}).Fee(100, 50.0); fee != 0 {
+		t.Errorf("Expected no fee by default, got %.2f", fee)
+	}
+}
+
+func TestSlippage_FillPrice(t *testing.T) {
+	t.Setenv("SLIPPAGE_BPS", "10")
+	t.Setenv("SLIPPAGE_BPS_PER_SHARE", "0.05")
+	s := Load().Slippage
+
+	// 10bp + 100 x 0.05bp = 15bp of $200 is $0.30
+	if price := s.FillPrice(200.0, 100, false); price != 200.30 {
+		t.Errorf("Expected a buy to pay 200.30, got %.2f", price)
+	}
+	if price := s.FillPrice(200.0, 100, true); price != 199.70 {
+		t.Errorf("Expected a sell to receive 199.70, got %.2f", price)
+	}
+
+	// Larger orders slip further: 10bp + 1000 x 0.05bp = 60bp
+	if price := s.FillPrice(200.0, 1000, false); price != 201.20 {
+		t.Errorf("Expected a 1000 share buy to pay 201.20, got %.2f", price)
+	}
+
+	if price := (Slippage{}).FillPrice(150.25, 1000, false); price != 150.25 {
+		t.Errorf("Expected no slippage by default, got %.2f", price)
+	}
+}
+
+func TestLoad_TradeIsolation(t *testing.T) {
+	if cfg := Load(); cfg.TradeIsolation != sql.LevelSerializable {
+		t.Errorf("Expected serializable by default, got %v", cfg.TradeIsolation)
+	}
+
+	t.Setenv("TRADE_ISOLATION", "repeatable_read")
+	if cfg := Load(); cfg.TradeIsolation != sql.LevelRepeatableRead {
+		t.Errorf("Expected repeatable read, got %v", cfg.TradeIsolation)
+	}
+}
+
+func TestLoad_CircuitBreaker(t *testing.T) {
+	if cfg := Load(); cfg.CircuitBreaker.Percent != 0 {
+		t.Errorf("Expected the circuit breaker off by default, got %+v", cfg.CircuitBreaker)
+	}
+
+	t.Setenv("CIRCUIT_BREAKER_PERCENT", "7.5")
+	t.Setenv("CIRCUIT_BREAKER_WINDOW", "30s")
+	t.Setenv("CIRCUIT_BREAKER_COOLDOWN", "10m")
+	want := CircuitBreaker{Percent: 7.5, Window: 30 * time.Second, Cooldown: 10 * time.Minute}
+	if cfg := Load(); cfg.CircuitBreaker != want {
+		t.Errorf("Expected %+v, got %+v", want, cfg.CircuitBreaker)
+	}
+}
+
+func TestLoad_OptimisticLocking(t *testing.T) {
+	if cfg := Load(); cfg.OptimisticLocking {
+		t.Error("Expected row locks by default")
+	}
+
+	t.Setenv("OPTIMISTIC_LOCKING", "true")
+	if cfg := Load(); !cfg.OptimisticLocking {
+		t.Error("Expected optimistic locking to be enabled")
+	}
+}
+
+func TestLoad_CostBasis(t *testing.T) {
+	if cfg := Load(); cfg.CostBasis != CostBasisAverage {
+		t.Errorf("Expected average cost by default, got %q", cfg.CostBasis)
+	}
+
+	t.Setenv("COST_BASIS", "FIFO")
+	if cfg := Load(); cfg.CostBasis != CostBasisFIFO {
+		t.Errorf("Expected fifo, got %q", cfg.CostBasis)
+	}
+
+	t.Setenv("COST_BASIS", "lifo")
+	if cfg := Load(); cfg.CostBasis != CostBasisAverage {
+		t.Errorf("Expected an unknown method to keep the default, got %q", cfg.CostBasis)
+	}
+}
+
+func TestLoad_PriceTolerance(t *testing.T) {
+	if cfg := Load(); cfg.PriceTolerancePercent != 5.0 {
+		t.Errorf("Expected 5%% tolerance by default, got %.2f", cfg.PriceTolerancePercent)
+	}
+
+	t.Setenv("PRICE_TOLERANCE_PERCENT", "0")
+	if cfg := Load(); cfg.PriceTolerancePercent != 0 {
+		t.Errorf("Expected tolerance disabled, got %.2f", cfg.PriceTolerancePercent)
+	}
+}
+
+func TestLoad_StartingBalance(t *testing.T) {
+	if cfg := Load(); cfg.StartingBalance != 10000.0 {
+		t.Errorf("Expected a 10000 starting balance by default, got %.2f", cfg.StartingBalance)
+	}
+
+	t.Setenv("DEFAULT_STARTING_BALANCE", "25000")
+	if cfg := Load(); cfg.StartingBalance != 25000.0 {
+		t.Errorf("Expected starting balance 25000, got %.2f", cfg.StartingBalance)
+	}
+
+	t.Setenv("DEFAULT_STARTING_BALANCE", "-5")
+	if cfg := Load(); cfg.StartingBalance != Default().StartingBalance {
+		t.Errorf("Expected a non-positive balance to fall back to default, got %.2f", cfg.StartingBalance)
+	}
+}
+
+func TestLoad_ShortSelling(t *testing.T) {
+	if cfg := Load(); cfg.ShortSelling || cfg.ShortMarginPercent != 50.0 {
+		t.Errorf("Expected shorting off with 50%% margin by default, got %v and %.2f", cfg.ShortSelling, cfg.ShortMarginPercent)
+	}
+
+	t.Setenv("ALLOW_SHORT_SELLING", "true")
+	t.Setenv("SHORT_MARGIN_PERCENT", "100")
+	if cfg := Load(); !cfg.ShortSelling || cfg.ShortMarginPercent != 100.0 {
+		t.Errorf("Expected shorting on with 100%% margin, got %v and %.2f", cfg.ShortSelling, cfg.ShortMarginPercent)
+	}
+}
+
+func TestLoad_Margin(t *testing.T) {
+	if cfg := Load(); cfg.MarginMultiplier != 1.0 || cfg.MaintenanceMarginPercent != 25.0 {
+		t.Errorf("Expected no margin and 25%% maintenance by default, got %.2f and %.2f", cfg.MarginMultiplier, cfg.MaintenanceMarginPercent)
+	}
+
+	t.Setenv("MARGIN_MULTIPLIER", "2")
+	t.Setenv("MAINTENANCE_MARGIN_PERCENT", "30")
+	if cfg := Load(); cfg.MarginMultiplier != 2.0 || cfg.MaintenanceMarginPercent != 30.0 {
+		t.Errorf("Expected 2x margin and 30%% maintenance, got %.2f and %.2f", cfg.MarginMultiplier, cfg.MaintenanceMarginPercent)
+	}
+
+	t.Setenv("MARGIN_MULTIPLIER", "0.5")
+	if cfg := Load(); cfg.MarginMultiplier != 1.0 {
+		t.Errorf("Expected a multiplier below 1 to fall back to 1, got %.2f", cfg.MarginMultiplier)
+	}
+}
+
+func TestLoad_MarketHours(t *testing.T) {
+	if cfg := Load(); cfg.MarketHours.Enabled {
+		t.Error("Expected the market to be always open by default")
+	}
+
+	t.Setenv("MARKET_HOURS", "true")
+	t.Setenv("MARKET_OPEN", "08:00")
+	t.Setenv("MARKET_CLOSE", "20:00")
+	t.Setenv("MARKET_TIMEZONE", "Europe/London")
+	t.Setenv("MARKET_CLOSED_WEEKENDS", "false")
+	hours := Load().MarketHours
+	if !hours.Enabled || hours.Open != 8*time.Hour || hours.Close != 20*time.Hour || hours.ClosedWeekends {
+		t.Errorf("Expected 08:00-20:00 every day, got %+v", hours)
+	}
+	if hours.Location == nil || hours.Location.String() != "Europe/London" {
+		t.Errorf("Expected Europe/London, got %v", hours.Location)
+	}
+
+	// A session that closes before it opens keeps the defaults
+	t.Setenv("MARKET_OPEN", "17:00")
+	t.Setenv("MARKET_CLOSE", "09:00")
+	t.Setenv("MARKET_TIMEZONE", "Mars/Olympus")
+	hours = Load().MarketHours
+	if hours.Open != 9*time.Hour+30*time.Minute || hours.Close != 16*time.Hour || hours.Location.String() != "America/New_York" {
+		t.Errorf("Expected the default 09:30-16:00 New York session, got %+v", hours)
+	}
+}
+
+func TestPositionLimit_Allows(t *testing.T) {
+	shares := PositionLimit{MaxShares: 100}
+	if !shares.Allows(100, 0, 0) {
+		t.Error("Expected a holding of exactly 100 shares to be allowed")
+	}
+	if shares.Allows(101, 0, 0) {
+		t.Error("Expected 101 shares to exceed a 100 share limit")
+	}
+
+	percent := PositionLimit{MaxPercent: 25}
+	if !percent.Allows(1000000, 2500.0, 10000.0) {
+		t.Error("Expected a holding of exactly 25% to be allowed")
+	}
+	if !percent.Allows(3, 0.3*100, 0.1*1200) {
+		t.Error("Expected 25% reached through floating point arithmetic to be allowed")
+	}
+	if percent.Allows(26, 2600.0, 10000.0) {
+		t.Error("Expected 26% to exceed a 25% limit")
+	}
+	if percent.Allows(1, 100.0, 0) {
+		t.Error("Expected any holding to exceed a percent limit when the account is worth nothing")
+	}
+
+	if !(PositionLimit{}).Allows(1000000000, 1e12, 1.0) {
+		t.Error("Expected a zero limit not to limit")
+	}
+}
+
+func TestLoad_PositionLimits(t *testing.T) {
+	cfg := Load()
+	if cfg.PositionLimit.MaxShares != DefaultMaxPositionShares || cfg.PositionLimit.MaxPercent != 0 {
+		t.Errorf("Expected the default share limit and no percent limit, got %+v", cfg.PositionLimit)
+	}
+	if limit := cfg.PositionLimitFor("AAPL"); limit != cfg.PositionLimit {
+		t.Errorf("Expected AAPL to get the default limit, got %+v", limit)
+	}
+
+	t.Setenv("MAX_POSITION_SHARES", "5000")
+	t.Setenv("MAX_POSITION_PERCENT", "40")
+	t.Setenv("SYMBOL_POSITION_LIMITS", "tsla=500, GME=10%, BAD=-3, NOEQUALS")
+	cfg = Load()
+	if cfg.PositionLimit != (PositionLimit{MaxShares: 5000, MaxPercent: 40}) {
+		t.Errorf("Expected 5000 shares and 40%%, got %+v", cfg.PositionLimit)
+	}
+	if limit := cfg.PositionLimitFor("TSLA"); limit != (PositionLimit{MaxShares: 500}) {
+		t.Errorf("Expected TSLA limited to 500 shares, got %+v", limit)
+	}
+	if limit := cfg.PositionLimitFor("GME"); limit != (PositionLimit{MaxPercent: 10}) {
+		t.Errorf("Expected GME limited to 10%%, got %+v", limit)
+	}
+	if len(cfg.SymbolPositionLimits) != 2 {
+		t.Errorf("Expected invalid entries to be skipped, got %+v", cfg.SymbolPositionLimits)
+	}
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pingTimeout bounds each of the monitor's pings, so a hung connection
+// counts as an outage rather than stalling the monitor
+const pingTimeout = 2 * time.Second
+
+// Monitor pings the database every interval and tracks whether it is up.
+// When a ping fails it marks the database down and resets the pool,
+// dropping idle connections that died with the server, so the next ping
+// dials afresh. The first ping that succeeds marks it up again.
+type Monitor struct {
+	// Ping and Reset default to the pool's; must be set before Start
+	Ping  func(context.Context) error
+	Reset func()
+
+	interval time.Duration
+	up       atomic.Bool
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	doneCh   chan struct{}
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+// NewMonitor creates a monitor for database that checks it every interval.
+// The database is assumed up until a check says otherwise.
+func NewMonitor(database *sql.DB, interval time.Duration) *Monitor {
+	idle := LoadPoolConfig().MaxIdleConns
+	m := &Monitor{
+		Ping: database.PingContext,
+		Reset: func() {
+			// Shrinking the idle pool to nothing closes every idle connection
+			database.SetMaxIdleConns(0)
+			database.SetMaxIdleConns(idle)
+		},
+		interval: interval,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	m.up.Store(true)
+	return m
+}
+
+// Start begins checking in the background
+func (m *Monitor) Start() {
+	go func() {
+		defer close(m.doneCh)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.Check(context.Background())
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the checks and waits for one in progress. Safe to call more
+// than once, but only after Start.
+func (m *Monitor) Stop() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+	<-m.doneCh
+}
+
+// Check pings the database once, updating Up and Err, and resets the pool
+// if the ping fails
+func (m *Monitor) Check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	err := m.Ping(ctx)
+	m.mu.Lock()
+	m.lastErr = err
+	m.mu.Unlock()
+
+	if err != nil {
+		if m.up.Swap(false) {
+			slog.Error("Database unreachable, reconnecting", "error", err)
+		}
+		m.Reset()
+		return
+	}
+	if !m.up.Swap(true) {
+		slog.Info("Database reachable again")
+	}
+}
+
+// Up reports whether the last check reached the database. A nil monitor
+// is always up.
+func (m *Monitor) Up() bool {
+	return m == nil || m.up.Load()
+}
+
+// Err returns the last check's error, or nil if it succeeded
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastErr
+}
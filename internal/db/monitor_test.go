@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMonitor_DetectsOutageAndRecovery(t *testing.T) {
+	var failing atomic.Bool
+	var resets atomic.Int64
+
+	m := NewMonitor(nil, 5*time.Millisecond)
+	m.Ping = func(context.Context) error {
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	m.Reset = func() { resets.Add(1) }
+	m.Start()
+	defer m.Stop()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if !m.Up() {
+		t.Fatal("Expected the database assumed up before the first check")
+	}
+
+	failing.Store(true)
+	waitFor("the outage to be noticed", func() bool { return !m.Up() })
+	if m.Err() == nil {
+		t.Error("Expected the ping error to be kept")
+	}
+	waitFor("the pool to be reset", func() bool { return resets.Load() > 0 })
+
+	failing.Store(false)
+	waitFor("the recovery to be noticed", m.Up)
+	if err := m.Err(); err != nil {
+		t.Errorf("Expected no error once recovered, got %v", err)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/atharvakonge/stock-trading-simulator/internal/migrations"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
@@ -40,10 +42,15 @@ func InitDB() error {
         return fmt.Errorf("error connecting to database: %w", err)
     }
 
+    if err = migrations.RunMigrations(DB); err != nil {
+        return fmt.Errorf("error migrating database: %w", err)
+    }
+
     // Set connection pool settings
-    DB.SetMaxOpenConns(25)
-    DB.SetMaxIdleConns(5)
-    DB.SetConnMaxLifetime(5 * time.Minute)
+    pool := LoadPoolConfig()
+    DB.SetMaxOpenConns(pool.MaxOpenConns)
+    DB.SetMaxIdleConns(pool.MaxIdleConns)
+    DB.SetConnMaxLifetime(pool.ConnMaxLifetime)
 
     log.Println("✅ Database connected successfully")
     return nil
@@ -58,6 +65,41 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// PoolConfig holds connection pool limits
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// LoadPoolConfig reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and
+// DB_CONN_MAX_LIFETIME, defaulting to 25, 5 and 5m
+func LoadPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+	}
+}
+
+// Helper function to get a positive integer environment variable with default
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to get a duration environment variable (e.g. "5m") with default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
 // CloseDB closes database connection
 func CloseDB() {
 	if DB != nil {
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadPoolConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("DB_MAX_OPEN_CONNS", "")
+		t.Setenv("DB_MAX_IDLE_CONNS", "")
+		t.Setenv("DB_CONN_MAX_LIFETIME", "")
+
+		got := LoadPoolConfig()
+		want := PoolConfig{MaxOpenConns: 25, MaxIdleConns: 5, ConnMaxLifetime: 5 * time.Minute}
+		if got != want {
+			t.Errorf("LoadPoolConfig() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		t.Setenv("DB_MAX_OPEN_CONNS", "50")
+		t.Setenv("DB_MAX_IDLE_CONNS", "10")
+		t.Setenv("DB_CONN_MAX_LIFETIME", "30s")
+
+		got := LoadPoolConfig()
+		want := PoolConfig{MaxOpenConns: 50, MaxIdleConns: 10, ConnMaxLifetime: 30 * time.Second}
+		if got != want {
+			t.Errorf("LoadPoolConfig() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("invalid values fall back", func(t *testing.T) {
+		t.Setenv("DB_MAX_OPEN_CONNS", "lots")
+		t.Setenv("DB_MAX_IDLE_CONNS", "-1")
+		t.Setenv("DB_CONN_MAX_LIFETIME", "forever")
+
+		got := LoadPoolConfig()
+		want := PoolConfig{MaxOpenConns: 25, MaxIdleConns: 5, ConnMaxLifetime: 5 * time.Minute}
+		if got != want {
+			t.Errorf("LoadPoolConfig() = %+v, want %+v", got, want)
+		}
+	})
+}
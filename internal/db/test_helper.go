@@ -3,9 +3,11 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/migrations"
 )
 
 // SetupTestDB creates a test database connection
@@ -28,20 +30,66 @@ func SetupTestDB(t *testing.T) *sql.DB {
 		t.Fatalf("Failed to ping test database: %v", err)
 	}
 
+	if err = migrations.RunMigrations(db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
 	// Set global DB for handlers
 	DB = db
 
 	return db
 }
 
-// CleanupTestDB cleans up test data
+// testTables lists every table tests write to, children before parents so
+// deletes respect foreign keys
+var testTables = []string{"orders", "idempotency_keys", "cash_transactions", "trade_attempts", "trade_stats", "trades", "portfolio_lots", "portfolios", "users"}
+
+// CleanupTestDB cleans up test data in a single transaction
 func CleanupTestDB(t *testing.T, db *sql.DB) {
-	// Delete all test data
-	tables := []string{"trades", "portfolios", "users"}
-	for _, table := range tables {
-		_, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id > 0", table))
+	t.Helper()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to start cleanup transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range testTables {
+		_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id > 0", table))
+		if err != nil {
+			t.Fatalf("Failed to cleanup table %s: %v", table, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit cleanup: %v", err)
+	}
+}
+
+// ResetTestDB truncates all test tables and restarts their ID sequences,
+// for tests that need deterministic IDs
+func ResetTestDB(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	_, err := db.Exec(fmt.Sprintf("TRUNCATE %s RESTART IDENTITY CASCADE",
+		strings.Join(testTables, ", ")))
+	if err != nil {
+		t.Fatalf("Failed to reset test database: %v", err)
+	}
+}
+
+// AssertCleanDB fails the test if any test table still has rows
+func AssertCleanDB(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	for _, table := range testTables {
+		var count int
+		err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count)
 		if err != nil {
-			log.Printf("Warning: Failed to cleanup table %s: %v", table, err)
+			t.Fatalf("Failed to count rows in %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("Expected table %s to be empty, found %d rows", table, count)
 		}
 	}
 }
@@ -0,0 +1,44 @@
+package db
+
+import "testing"
+
+func TestCleanupTestDB_LeavesCleanSlate(t *testing.T) {
+	database := SetupTestDB(t)
+	defer database.Close()
+
+	userID := CreateTestUser(t, database, "cleanup_user", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 10, 150.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	_, err = database.Exec(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount)
+        VALUES ($1, 'AAPL', 'BUY', 10, 150.0, 1500.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup trade: %v", err)
+	}
+
+	CleanupTestDB(t, database)
+	AssertCleanDB(t, database)
+}
+
+func TestResetTestDB_RestartsIdentity(t *testing.T) {
+	database := SetupTestDB(t)
+	defer database.Close()
+	defer CleanupTestDB(t, database)
+
+	CreateTestUser(t, database, "before_reset", 10000.0)
+	ResetTestDB(t, database)
+	AssertCleanDB(t, database)
+
+	userID := CreateTestUser(t, database, "after_reset", 10000.0)
+	if userID != 1 {
+		t.Errorf("Expected first user after reset to have ID 1, got %d", userID)
+	}
+}
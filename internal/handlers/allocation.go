@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// cashSlice names the cash slice of an allocation
+const cashSlice = "CASH"
+
+// GetAllocation handles GET /api/portfolio/:userId/allocation
+func GetAllocation(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	cash, positions, err := loadPortfolio(userID)
+	if err == sql.ErrNoRows {
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+
+	Respond(c, http.StatusOK, allocate(cash, positions, Prices))
+}
+
+// allocate splits an account into a slice per position plus one for cash,
+// largest first, valuing positions at one snapshot of the latest prices.
+// Percentages are rounded to hundredths, and the rounding error is given to
+// the largest slice so they add up to exactly 100. Shorts, and cash borrowed on
+// margin, are negative slices.
+func allocate(cash float64, positions []models.Portfolio, prices *market.PriceStore) models.AllocationResponse {
+	slices := make([]models.AllocationSlice, 0, len(positions)+1)
+	slices = append(slices, models.AllocationSlice{Name: cashSlice, MarketValue: cash})
+	total := cash
+
+	valued := valueHoldings(positions, prices)
+	for _, p := range positions {
+		h := valued[holdingKey{UserID: p.UserID, Symbol: p.StockSymbol}]
+		slices = append(slices, models.AllocationSlice{Name: p.StockSymbol, MarketValue: h.CurrentValue})
+		total += h.CurrentValue
+	}
+
+	sort.Slice(slices, func(i, j int) bool {
+		if slices[i].MarketValue != slices[j].MarketValue {
+			return slices[i].MarketValue > slices[j].MarketValue
+		}
+		return slices[i].Name < slices[j].Name
+	})
+
+	// A wiped-out account has nothing to divide
+	if total > 0 {
+		sum := 0.0
+		for i := range slices {
+			slices[i].Percent = math.Round(slices[i].MarketValue/total*10000) / 100
+			sum += slices[i].Percent
+		}
+		slices[0].Percent = math.Round((slices[0].Percent+100-sum)*100) / 100
+	}
+
+	return models.AllocationResponse{Slices: slices, TotalValue: total}
+}
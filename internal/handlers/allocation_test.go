@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestAllocate(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 200.0, Timestamp: time.Now()})
+	prices.Set(market.Quote{Symbol: "MSFT", Price: 100.0, Timestamp: time.Now()})
+
+	positions := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 150.0}, // $2000 at the latest price
+		{StockSymbol: "MSFT", Quantity: 5, AvgPurchasePrice: 90.0},   // $500
+		{StockSymbol: "TSLA", Quantity: 2, AvgPurchasePrice: 250.0},  // Unquoted: $500 at cost
+	}
+
+	allocation := allocate(2000.0, positions, prices)
+
+	if allocation.TotalValue != 5000.0 {
+		t.Errorf("Expected total value 5000, got %.2f", allocation.TotalValue)
+	}
+
+	expected := []models.AllocationSlice{
+		{Name: "AAPL", MarketValue: 2000.0, Percent: 40.0},
+		{Name: cashSlice, MarketValue: 2000.0, Percent: 40.0},
+		{Name: "MSFT", MarketValue: 500.0, Percent: 10.0},
+		{Name: "TSLA", MarketValue: 500.0, Percent: 10.0},
+	}
+	if len(allocation.Slices) != len(expected) {
+		t.Fatalf("Expected %d slices, got %+v", len(expected), allocation.Slices)
+	}
+	for i, want := range expected {
+		if got := allocation.Slices[i]; got != want {
+			t.Errorf("Slice %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestAllocate_AllCash(t *testing.T) {
+	allocation := allocate(10000.0, nil, market.NewPriceStore())
+
+	if len(allocation.Slices) != 1 || allocation.Slices[0] != (models.AllocationSlice{Name: cashSlice, MarketValue: 10000.0, Percent: 100.0}) {
+		t.Errorf("Expected a single 100%% cash slice, got %+v", allocation.Slices)
+	}
+}
+
+func TestAllocate_RoundsToHundred(t *testing.T) {
+	// Three equal thirds round to 33.33 each; the largest takes the rest
+	positions := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 1, AvgPurchasePrice: 100.0},
+		{StockSymbol: "MSFT", Quantity: 1, AvgPurchasePrice: 100.0},
+	}
+
+	allocation := allocate(100.0, positions, market.NewPriceStore())
+
+	sum := 0.0
+	for _, s := range allocation.Slices {
+		sum += s.Percent
+	}
+	if !approxEqual(sum, 100.0) {
+		t.Errorf("Expected percentages to add up to 100, got %.4f from %+v", sum, allocation.Slices)
+	}
+	if allocation.Slices[0].Percent != 33.34 || allocation.Slices[1].Percent != 33.33 {
+		t.Errorf("Expected 33.34, 33.33, 33.33, got %+v", allocation.Slices)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// recordTradeAttempt writes the outcome of an executed trade to the audit
+// table. It runs on the shared pool after the trade transaction has
+// committed or rolled back, so failures are kept too. An audit write
+// failure is logged but doesn't change the trade's result.
+func recordTradeAttempt(req models.BuyRequest, result TradeResult) {
+	var reason, tradeID interface{}
+	quantity := req.Quantity
+	if result.Success {
+		tradeID = result.TradeID
+		quantity = result.Quantity
+	} else {
+		reason = result.Error
+	}
+
+	_, err := db.DB.Exec(`
+        INSERT INTO trade_attempts (user_id, stock_symbol, trade_type, quantity, price, success, reason, trade_id, request_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
+    `, req.UserID, req.StockSymbol, req.TradeType, quantity, req.Price,
+		result.Success, reason, tradeID, req.RequestID)
+
+	if err != nil {
+		slog.Error("Failed to record trade attempt", "user_id", req.UserID,
+			"symbol", req.StockSymbol, "request_id", req.RequestID, "error", err)
+	}
+}
+
+// GetTradeAttempts handles GET /api/trades/:userId/attempts, listing the
+// user's most recent failed trade attempts
+func GetTradeAttempts(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	rows, err := db.DB.Query(`
+        SELECT id, user_id, stock_symbol, trade_type, quantity, price, success, COALESCE(reason, ''), COALESCE(request_id, ''), created_at
+        FROM trade_attempts
+        WHERE user_id = $1 AND NOT success
+        ORDER BY created_at DESC, id DESC
+        LIMIT 50
+    `, userID)
+
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch trade attempts")
+		return
+	}
+	defer rows.Close()
+
+	attempts := make([]models.TradeAttempt, 0)
+	for rows.Next() {
+		var a models.TradeAttempt
+		err := rows.Scan(&a.ID, &a.UserID, &a.StockSymbol, &a.TradeType, &a.Quantity,
+			&a.Price, &a.Success, &a.Reason, &a.RequestID, &a.CreatedAt)
+		if err != nil {
+			continue
+		}
+		attempts = append(attempts, a)
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"attempts": attempts,
+		"count":    len(attempts),
+	})
+}
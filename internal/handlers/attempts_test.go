@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTradeAttempts_RecordsFailures(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "audited", 1000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	// One success, then a buy the rolled-back transaction can't afford
+	if result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0}); !result.Success {
+		t.Fatalf("Setup buy failed: %s", result.Error)
+	}
+	if result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 100, Price: 100.0}); result.Success {
+		t.Fatal("Expected the oversized buy to fail")
+	}
+
+	var total int
+	database.QueryRow("SELECT COUNT(*) FROM trade_attempts WHERE user_id = $1", userID).Scan(&total)
+	if total != 2 {
+		t.Errorf("Expected both attempts audited, got %d", total)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/trades/:userId/attempts", GetTradeAttempts)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/trades/%d/attempts", userID), nil))
+
+	var body struct {
+		Data struct {
+			Attempts []models.TradeAttempt `json:"attempts"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	if w.Code != http.StatusOK || len(body.Data.Attempts) != 1 {
+		t.Fatalf("Expected 200 with 1 failed attempt, got %d with %d", w.Code, len(body.Data.Attempts))
+	}
+	attempt := body.Data.Attempts[0]
+	if attempt.Reason != "Insufficient funds" || attempt.Quantity != 100 || attempt.Success {
+		t.Errorf("Unexpected attempt %+v", attempt)
+	}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/auth"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AdminTokenHeader carries ADMIN_TOKEN on the /api/admin routes
+const AdminTokenHeader = "X-Admin-Token"
+
+// authUserIDKey is the gin context key RequireAuth stores the
+// authenticated user ID under
+const authUserIDKey = "auth_user_id"
+
+// errInvalidCredentials is the one login failure message, so responses
+// don't reveal whether a username exists
+const errInvalidCredentials = "invalid credentials"
+
+// dummyHash is compared against when the user doesn't exist or has no
+// password, so those logins take as long as a wrong password
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// validUsername limits usernames to something safe to store and display
+var validUsername = regexp.MustCompile(`^[A-Za-z0-9._-]{3,32}$`)
+
+// errInvalidUsername is the response to a username validUsername rejects
+const errInvalidUsername = "username must be 3 to 32 letters, digits, '.', '_' or '-'"
+
+// RegisterRequest is the body of POST /api/register
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required"` // Checked against validUsername
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required,min=8,max=72"` // bcrypt ignores bytes past 72
+}
+
+// Register handles POST /api/register, creating a user with a hashed
+// password and startingBalance in cash
+func Register(startingBalance float64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !validUsername.MatchString(req.Username) {
+			RespondError(c, http.StatusBadRequest, errInvalidUsername)
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to hash password")
+			return
+		}
+
+		var userID int
+		var currency string
+		err = db.DB.QueryRow(`
+            INSERT INTO users (username, email, password_hash, cash_balance)
+            VALUES ($1, $2, $3, $4)
+            RETURNING id, currency
+        `, req.Username, req.Email, string(hash), startingBalance).Scan(&userID, &currency)
+
+		if isUniqueViolation(err) {
+			RespondError(c, http.StatusConflict, "username or email already taken")
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to create user")
+			return
+		}
+
+		Respond(c, http.StatusCreated, gin.H{
+			"user_id":      userID,
+			"username":     req.Username,
+			"cash_balance": startingBalance,
+			"currency":     currency,
+		})
+	}
+}
+
+// LoginRequest is the body of POST /api/login
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login handles POST /api/login, issuing a token when the username and
+// password match
+func Login(issuer *auth.Issuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var userID int
+		var hash sql.NullString
+		err := db.DB.QueryRow(
+			"SELECT id, password_hash FROM users WHERE username = $1",
+			req.Username,
+		).Scan(&userID, &hash)
+		if err != nil && err != sql.ErrNoRows {
+			RespondError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		if err == sql.ErrNoRows || !hash.Valid {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(req.Password))
+			RespondError(c, http.StatusUnauthorized, errInvalidCredentials)
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(req.Password)) != nil {
+			RespondError(c, http.StatusUnauthorized, errInvalidCredentials)
+			return
+		}
+
+		respondWithToken(c, issuer, userID)
+	}
+}
+
+func respondWithToken(c *gin.Context, issuer *auth.Issuer, userID int) {
+	token, expires, err := issuer.Issue(userID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"token":      token,
+		"user_id":    userID,
+		"expires_at": expires,
+	})
+}
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer"
+// token with 401, and records the token's user for AuthenticatedUserID
+func RequireAuth(issuer *auth.Issuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			token = ""
+		}
+		if !authenticate(c, issuer, token) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireStreamAuth guards the per-user WebSocket routes. Browsers can't
+// set headers on a WebSocket handshake, so the token may also come as a
+// ?token= query parameter. Requests without a valid token for the
+// :userId in the path get 401.
+func RequireStreamAuth(issuer *auth.Issuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			token = c.Query("token")
+		}
+		if !authenticate(c, issuer, token) {
+			c.Abort()
+			return
+		}
+
+		authID, _ := AuthenticatedUserID(c)
+		if userID, err := strconv.Atoi(c.Param("userId")); err == nil && userID != authID {
+			RespondError(c, http.StatusUnauthorized, "token is for another user")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin guards the admin routes. Requests whose X-Admin-Token
+// header doesn't match token get 401. With no token configured every
+// request gets 403, so the admin routes stay off until one is set.
+func RequireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			RespondError(c, http.StatusForbidden, "admin endpoints disabled")
+			c.Abort()
+			return
+		}
+		given := c.GetHeader(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			RespondError(c, http.StatusUnauthorized, "invalid admin token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticate verifies token and records its user for
+// AuthenticatedUserID. Otherwise it responds 401 and returns false.
+func authenticate(c *gin.Context, issuer *auth.Issuer, token string) bool {
+	if token == "" {
+		RespondError(c, http.StatusUnauthorized, "missing bearer token")
+		return false
+	}
+
+	claims, err := issuer.Verify(token)
+	if errors.Is(err, auth.ErrExpiredToken) {
+		RespondError(c, http.StatusUnauthorized, "token expired")
+		return false
+	}
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "invalid token")
+		return false
+	}
+
+	c.Set(authUserIDKey, claims.UserID)
+	return true
+}
+
+// AuthenticatedUserID returns the user RequireAuth verified, if any
+func AuthenticatedUserID(c *gin.Context) (int, bool) {
+	userID, ok := c.Get(authUserIDKey)
+	if !ok {
+		return 0, false
+	}
+	return userID.(int), true
+}
+
+// authorizeUser responds 403 and returns false when the request is
+// authenticated as someone other than userID. Unauthenticated requests
+// pass, since RequireAuth isn't installed when auth is disabled.
+func authorizeUser(c *gin.Context, userID int) bool {
+	if authID, ok := AuthenticatedUserID(c); ok && authID != userID {
+		RespondError(c, http.StatusForbidden, "forbidden")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/auth"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	issuer := auth.NewIssuer("secret", time.Hour)
+
+	router := gin.New()
+	router.Use(RequireAuth(issuer))
+	router.GET("/api/portfolio/:userId", func(c *gin.Context) {
+		if _, ok := parseUserID(c); ok {
+			c.Status(http.StatusOK)
+		}
+	})
+
+	valid, _, _ := issuer.Issue(7)
+	expired, _, _ := auth.NewIssuer("secret", -time.Minute).Issue(7)
+	forged, _, _ := auth.NewIssuer("other-secret", time.Hour).Issue(7)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"valid token", "/api/portfolio/7", "Bearer " + valid, http.StatusOK},
+		{"another user's account", "/api/portfolio/8", "Bearer " + valid, http.StatusForbidden},
+		{"missing token", "/api/portfolio/7", "", http.StatusUnauthorized},
+		{"expired token", "/api/portfolio/7", "Bearer " + expired, http.StatusUnauthorized},
+		{"tampered token", "/api/portfolio/7", "Bearer " + forged, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestRequireStreamAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	issuer := auth.NewIssuer("secret", time.Hour)
+
+	router := gin.New()
+	router.Use(RequireStreamAuth(issuer))
+	router.GET("/ws/portfolio/:userId", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/ws/trades/:userId", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	valid, _, _ := issuer.Issue(7)
+	expired, _, _ := auth.NewIssuer("secret", -time.Minute).Issue(7)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"header token", "/ws/portfolio/7", "Bearer " + valid, http.StatusOK},
+		{"query token", "/ws/portfolio/7?token=" + valid, "", http.StatusOK},
+		{"another user's stream", "/ws/portfolio/8?token=" + valid, "", http.StatusUnauthorized},
+		{"missing token", "/ws/portfolio/7", "", http.StatusUnauthorized},
+		{"expired token", "/ws/portfolio/7?token=" + expired, "", http.StatusUnauthorized},
+		{"own trades", "/ws/trades/7?token=" + valid, "", http.StatusOK},
+		{"another user's trades", "/ws/trades/8", "Bearer " + valid, http.StatusUnauthorized},
+		{"trades without a token", "/ws/trades/7", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		configured string
+		header     string
+		want       int
+	}{
+		{"matching token", "admin-secret", "admin-secret", http.StatusOK},
+		{"wrong token", "admin-secret", "guess", http.StatusUnauthorized},
+		{"missing token", "admin-secret", "", http.StatusUnauthorized},
+		{"no token configured", "", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		router := gin.New()
+		router.GET("/api/admin/halts", RequireAdmin(tt.configured), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/halts", nil)
+		if tt.header != "" {
+			req.Header.Set(AdminTokenHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	gin.SetMode(gin.TestMode)
+	issuer := auth.NewIssuer("secret", time.Hour)
+	router := gin.New()
+	router.POST("/api/register", Register(10000.0))
+	router.POST("/api/login", Login(issuer))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/register", `{"username":"login_user","email":"login@example.com","password":"correct horse"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 from register, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "$2a$") {
+		t.Error("Register response leaked the password hash")
+	}
+
+	t.Run("correct password", func(t *testing.T) {
+		w := post("/api/login", `{"username":"login_user","password":"correct horse"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			Data struct {
+				Token  string `json:"token"`
+				UserID int    `json:"user_id"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		claims, err := issuer.Verify(resp.Data.Token)
+		if err != nil {
+			t.Fatalf("Issued token doesn't verify: %v", err)
+		}
+		if claims.UserID != resp.Data.UserID {
+			t.Errorf("Token is for user %d, response says %d", claims.UserID, resp.Data.UserID)
+		}
+	})
+
+	for name, body := range map[string]string{
+		"wrong password": `{"username":"login_user","password":"battery staple"}`,
+		"unknown user":   `{"username":"nobody_here","password":"correct horse"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := post("/api/login", body)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401, got %d", w.Code)
+			}
+
+			var env Envelope
+			json.Unmarshal(w.Body.Bytes(), &env)
+			if env.Error == nil || *env.Error != errInvalidCredentials {
+				t.Errorf("Expected %q, got %v", errInvalidCredentials, env.Error)
+			}
+		})
+	}
+}
+
+func TestRegister_RejectsInvalidUsernames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/register", Register(10000.0))
+
+	// Rejected before the database is touched
+	for _, username := range []string{"ab", strings.Repeat("a", 33), strings.Repeat("a", 10000), "has space", "<script>", "zoë", "emoji😀"} {
+		body := fmt.Sprintf(`{"username":%q,"email":"user@example.com","password":"correct horse"}`, username)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		var resp Envelope
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusBadRequest || resp.Error == nil || *resp.Error != errInvalidUsername {
+			t.Errorf("%.20q: expected 400 %q, got %d %s", username, errInvalidUsername, w.Code, w.Body.String())
+		}
+	}
+
+	for _, username := range []string{"abc", "jane.doe-99", "Trader_1", strings.Repeat("a", 32)} {
+		if !validUsername.MatchString(username) {
+			t.Errorf("Expected %q to be a valid username", username)
+		}
+	}
+}
+
+func TestRegister_StartingBalance(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/register", Register(25000.0))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/register",
+		strings.NewReader(`{"username":"rich_user","email":"rich@example.com","password":"correct horse"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 from register, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			UserID int `json:"user_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	var balance float64
+	var currency string
+	err := database.QueryRow("SELECT cash_balance, currency FROM users WHERE id = $1", resp.Data.UserID).Scan(&balance, &currency)
+	if err != nil {
+		t.Fatalf("Failed to query new user: %v", err)
+	}
+	if balance != 25000.0 || currency != "USD" {
+		t.Errorf("Expected 25000 USD, got %.2f %s", balance, currency)
+	}
+}
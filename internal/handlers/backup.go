@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportAccount handles GET /api/admin/users/:userId/export
+func (tp *TradeProcessor) ExportAccount(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	// Read everything from one snapshot so holdings and trades agree
+	tx, err := db.DB.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	backup := models.AccountBackup{
+		Version:    models.BackupVersion,
+		ExportedAt: tp.clock.Now(),
+		Holdings:   make([]models.Portfolio, 0),
+		Lots:       make([]models.Lot, 0),
+		Trades:     make([]models.Trade, 0),
+		// The book, not the snapshot, is the authority on resting orders
+		Orders:           Orders.OpenOrders(userID),
+		CashTransactions: make([]models.CashTransaction, 0),
+	}
+
+	u := &backup.User
+	err = tx.QueryRow(
+		"SELECT id, username, email, cash_balance, currency, created_at FROM users WHERE id = $1",
+		userID,
+	).Scan(&u.ID, &u.Username, &u.Email, &u.CashBalance, &u.Currency, &u.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	rows, err := tx.Query(`
+        SELECT id, user_id, stock_symbol, quantity, avg_purchase_price, margin_used, updated_at
+        FROM portfolios
+        WHERE user_id = $1
+        ORDER BY stock_symbol
+    `, userID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.ID, &p.UserID, &p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice, &p.MarginUsed, &p.UpdatedAt); err != nil {
+			rows.Close()
+			RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+			return
+		}
+		backup.Holdings = append(backup.Holdings, p)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`
+        SELECT id, user_id, stock_symbol, quantity, price, bought_at
+        FROM portfolio_lots
+        WHERE user_id = $1
+        ORDER BY stock_symbol, bought_at, id
+    `, userID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch lots")
+		return
+	}
+	for rows.Next() {
+		var lot models.Lot
+		if err := rows.Scan(&lot.ID, &lot.UserID, &lot.StockSymbol, &lot.Quantity, &lot.Price, &lot.BoughtAt); err != nil {
+			rows.Close()
+			RespondError(c, http.StatusInternalServerError, "Failed to fetch lots")
+			return
+		}
+		backup.Lots = append(backup.Lots, lot)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`
+        SELECT id, user_id, stock_symbol, trade_type, quantity, price, quoted_price, total_amount, fee, realized_pl, status, COALESCE(note, ''), created_at
+        FROM trades
+        WHERE user_id = $1
+        ORDER BY seq NULLS FIRST, created_at, id
+    `, userID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch trades")
+		return
+	}
+	for rows.Next() {
+		var t models.Trade
+		if err := rows.Scan(&t.ID, &t.UserID, &t.StockSymbol, &t.TradeType, &t.Quantity,
+			&t.Price, &t.QuotedPrice, &t.TotalAmount, &t.Fee, &t.RealizedPL, &t.Status, &t.Note, &t.CreatedAt); err != nil {
+			rows.Close()
+			RespondError(c, http.StatusInternalServerError, "Failed to fetch trades")
+			return
+		}
+		backup.Trades = append(backup.Trades, t)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`
+        SELECT id, user_id, type, amount, balance_after, created_at
+        FROM cash_transactions
+        WHERE user_id = $1
+        ORDER BY id
+    `, userID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch cash transactions")
+		return
+	}
+	for rows.Next() {
+		var ct models.CashTransaction
+		if err := rows.Scan(&ct.ID, &ct.UserID, &ct.Type, &ct.Amount, &ct.BalanceAfter, &ct.CreatedAt); err != nil {
+			rows.Close()
+			RespondError(c, http.StatusInternalServerError, "Failed to fetch cash transactions")
+			return
+		}
+		backup.CashTransactions = append(backup.CashTransactions, ct)
+	}
+	rows.Close()
+
+	Respond(c, http.StatusOK, backup)
+}
+
+// ImportAccount handles POST /api/admin/users/import
+func ImportAccount(c *gin.Context) {
+	var req models.ImportRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !validUsername.MatchString(req.Username) {
+		RespondError(c, http.StatusBadRequest, errInvalidUsername)
+		return
+	}
+
+	if err := validateBackup(req.Backup); err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Transaction failed")
+		return
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(
+		// Backups from before currencies get the column default
+		"INSERT INTO users (username, email, cash_balance, currency) VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'USD')) RETURNING id",
+		req.Username, req.Email, req.Backup.User.CashBalance, req.Backup.User.Currency,
+	).Scan(&userID)
+
+	if isUniqueViolation(err) {
+		RespondError(c, http.StatusConflict, "Username or email already exists")
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+
+	for _, h := range req.Backup.Holdings {
+		_, err = tx.Exec(`
+            INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price, margin_used)
+            VALUES ($1, $2, $3, $4, $5)
+        `, userID, h.StockSymbol, h.Quantity, h.AvgPurchasePrice, h.MarginUsed)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to restore portfolio")
+			return
+		}
+	}
+
+	// Lots keep their purchase times, which decide the order sells close
+	// them in. The trades that opened them get new IDs, so the link is
+	// dropped.
+	for _, lot := range req.Backup.Lots {
+		_, err = tx.Exec(`
+            INSERT INTO portfolio_lots (user_id, stock_symbol, quantity, price, bought_at)
+            VALUES ($1, $2, $3, $4, $5)
+        `, userID, lot.StockSymbol, lot.Quantity, lot.Price, lot.BoughtAt)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to restore lots")
+			return
+		}
+	}
+
+	// Trades are exported oldest first, so fresh sequence numbers keep their order
+	for _, t := range req.Backup.Trades {
+		_, err = tx.Exec(`
+            INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, realized_pl, status, created_at, seq, note, quoted_price)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13)
+        `, userID, t.StockSymbol, t.TradeType, t.Quantity, t.Price, t.TotalAmount, t.Fee, t.RealizedPL, t.Status, t.CreatedAt, nextTradeSeq(), t.Note, t.QuotedPrice)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to restore trades")
+			return
+		}
+	}
+
+	// The ledger is exported oldest first, so fresh IDs keep its order and
+	// the latest reset stays the latest
+	for _, ct := range req.Backup.CashTransactions {
+		_, err = tx.Exec(`
+            INSERT INTO cash_transactions (user_id, type, amount, balance_after, created_at)
+            VALUES ($1, $2, $3, $4, $5)
+        `, userID, ct.Type, ct.Amount, ct.BalanceAfter, ct.CreatedAt)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to restore cash transactions")
+			return
+		}
+	}
+
+	// Persisted orders are restored with the rest and rested in the book
+	// once committed. Without a store they live in the book alone.
+	orders := make([]models.Order, 0, len(req.Backup.Orders))
+	for _, o := range req.Backup.Orders {
+		o.ID, o.UserID = 0, userID
+		if orderStore != nil {
+			err = tx.QueryRow(`
+                INSERT INTO orders (user_id, stock_symbol, quantity, trade_type, order_type, limit_price, trigger_price, created_at)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+                RETURNING id
+            `, userID, o.StockSymbol, o.Quantity, o.TradeType, o.OrderType, o.LimitPrice, o.TriggerPrice, o.CreatedAt).Scan(&o.ID)
+			if err != nil {
+				RespondError(c, http.StatusInternalServerError, "Failed to restore orders")
+				return
+			}
+		}
+		orders = append(orders, o)
+	}
+
+	if err = tx.Commit(); err != nil {
+		RespondError(c, http.StatusInternalServerError, "Transaction commit failed")
+		return
+	}
+
+	for _, o := range orders {
+		Orders.AddOrder(o)
+	}
+
+	Respond(c, http.StatusCreated, gin.H{
+		"message":  "Account imported successfully",
+		"user_id":  userID,
+		"holdings": len(req.Backup.Holdings),
+		"trades":   len(req.Backup.Trades),
+		"orders":   len(orders),
+	})
+}
+
+// validateBackup rejects malformed or partial backups before anything is
+// written
+func validateBackup(b *models.AccountBackup) error {
+	if b.Version != models.BackupVersion {
+		return fmt.Errorf("unsupported backup version %d", b.Version)
+	}
+	if b.User.ID == 0 {
+		return fmt.Errorf("backup is missing the user record")
+	}
+	if b.User.CashBalance < 0 {
+		return fmt.Errorf("backup has a negative cash balance")
+	}
+
+	held := make(map[string]int)
+	for i, h := range b.Holdings {
+		if h.UserID != b.User.ID {
+			return fmt.Errorf("holding %d belongs to user %d, not %d", i, h.UserID, b.User.ID)
+		}
+		// Shorts are held as negative quantities
+		if !validBackupSymbol(h.StockSymbol) || h.AvgPurchasePrice < 0 || h.MarginUsed < 0 {
+			return fmt.Errorf("holding %d is malformed", i)
+		}
+		if _, ok := held[h.StockSymbol]; ok {
+			return fmt.Errorf("holding %d duplicates symbol %s", i, h.StockSymbol)
+		}
+		held[h.StockSymbol] = h.Quantity
+	}
+
+	// A long holding's lots must add up to it. One with no lots at all
+	// was bought before lots were tracked.
+	lotShares := make(map[string]int)
+	for i, lot := range b.Lots {
+		if lot.UserID != b.User.ID {
+			return fmt.Errorf("lot %d belongs to user %d, not %d", i, lot.UserID, b.User.ID)
+		}
+		if !validBackupSymbol(lot.StockSymbol) || lot.Quantity <= 0 || lot.Price < 0 {
+			return fmt.Errorf("lot %d is malformed", i)
+		}
+		lotShares[lot.StockSymbol] += lot.Quantity
+	}
+	for symbol, shares := range lotShares {
+		if shares != held[symbol] {
+			return fmt.Errorf("lots for %s add up to %d shares, but %d are held", symbol, shares, held[symbol])
+		}
+	}
+
+	for i, t := range b.Trades {
+		if t.UserID != b.User.ID {
+			return fmt.Errorf("trade %d belongs to user %d, not %d", i, t.UserID, b.User.ID)
+		}
+		if !validBackupSymbol(t.StockSymbol) || t.Quantity <= 0 || t.Price < 0 || t.Fee < 0 {
+			return fmt.Errorf("trade %d is malformed", i)
+		}
+		if t.TradeType != models.TradeTypeBuy && t.TradeType != models.TradeTypeSell {
+			return fmt.Errorf("trade %d has unknown type %q", i, t.TradeType)
+		}
+	}
+
+	// Orders are held to the rules PlaceOrder applies
+	for i, o := range b.Orders {
+		if o.UserID != b.User.ID {
+			return fmt.Errorf("order %d belongs to user %d, not %d", i, o.UserID, b.User.ID)
+		}
+		if !validBackupSymbol(o.StockSymbol) || o.Quantity <= 0 {
+			return fmt.Errorf("order %d is malformed", i)
+		}
+		if _, ok := market.NormalizeSymbol(o.StockSymbol); !ok {
+			return fmt.Errorf("order %d is in unknown symbol %s", i, o.StockSymbol)
+		}
+		switch {
+		case o.TradeType != models.TradeTypeBuy && o.TradeType != models.TradeTypeSell:
+			return fmt.Errorf("order %d has unknown type %q", i, o.TradeType)
+		case o.OrderType == models.OrderTypeLimit:
+			if o.LimitPrice <= 0 {
+				return fmt.Errorf("order %d has no limit price", i)
+			}
+		case o.OrderType == models.OrderTypeStopLoss || o.OrderType == models.OrderTypeTakeProfit:
+			if o.TradeType != models.TradeTypeSell || o.TriggerPrice <= 0 {
+				return fmt.Errorf("order %d is malformed", i)
+			}
+		default:
+			return fmt.Errorf("order %d has unknown order type %q", i, o.OrderType)
+		}
+	}
+
+	for i, ct := range b.CashTransactions {
+		if ct.UserID != b.User.ID {
+			return fmt.Errorf("cash transaction %d belongs to user %d, not %d", i, ct.UserID, b.User.ID)
+		}
+		switch ct.Type {
+		case models.CashDeposit, models.CashWithdraw:
+			if ct.Amount <= 0 {
+				return fmt.Errorf("cash transaction %d is malformed", i)
+			}
+		case models.CashReset:
+			if ct.Amount < 0 {
+				return fmt.Errorf("cash transaction %d is malformed", i)
+			}
+		default:
+			return fmt.Errorf("cash transaction %d has unknown type %q", i, ct.Type)
+		}
+	}
+
+	return nil
+}
+
+// validBackupSymbol reports whether symbol is a well-formed, upper-case
+// symbol, by the rules trade requests are held to. Delisted symbols are
+// allowed, since history may hold them.
+func validBackupSymbol(symbol string) bool {
+	cleaned, ok := market.CleanSymbol(symbol)
+	return ok && cleaned == symbol
+}
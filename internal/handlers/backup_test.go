@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateBackup_RejectsMalformed(t *testing.T) {
+	valid := func() *models.AccountBackup {
+		return &models.AccountBackup{
+			Version:  models.BackupVersion,
+			User:     models.User{ID: 7, CashBalance: 500.0},
+			Holdings: []models.Portfolio{{UserID: 7, StockSymbol: "AAPL", Quantity: 1, AvgPurchasePrice: 100.0}},
+			Lots:     []models.Lot{{UserID: 7, StockSymbol: "AAPL", Quantity: 1, Price: 100.0}},
+			Trades:   []models.Trade{{UserID: 7, StockSymbol: "AAPL", TradeType: "BUY", Quantity: 1, Price: 100.0}},
+			Orders: []models.Order{
+				{UserID: 7, StockSymbol: "MSFT", Quantity: 1, TradeType: "BUY", OrderType: "LIMIT", LimitPrice: 350.0},
+				{UserID: 7, StockSymbol: "AAPL", Quantity: 1, TradeType: "SELL", OrderType: "STOP_LOSS", TriggerPrice: 90.0},
+			},
+			CashTransactions: []models.CashTransaction{
+				{UserID: 7, Type: "RESET", Amount: 0, BalanceAfter: 0},
+				{UserID: 7, Type: "DEPOSIT", Amount: 600.0, BalanceAfter: 600.0},
+			},
+		}
+	}
+
+	if err := validateBackup(valid()); err != nil {
+		t.Fatalf("Expected valid backup to pass, got: %v", err)
+	}
+
+	short := valid()
+	short.Holdings[0].Quantity = -5
+	short.Lots = nil
+	if err := validateBackup(short); err != nil {
+		t.Errorf("Expected a short holding to pass, got: %v", err)
+	}
+
+	tests := map[string]func(b *models.AccountBackup){
+		"wrong version":     func(b *models.AccountBackup) { b.Version = 99 },
+		"missing user":      func(b *models.AccountBackup) { b.User = models.User{} },
+		"foreign holding":   func(b *models.AccountBackup) { b.Holdings[0].UserID = 8 },
+		"duplicate holding": func(b *models.AccountBackup) { b.Holdings = append(b.Holdings, b.Holdings[0]) },
+		"foreign trade":     func(b *models.AccountBackup) { b.Trades[0].UserID = 8 },
+		"bad trade type":    func(b *models.AccountBackup) { b.Trades[0].TradeType = "HOLD" },
+		"zero quantity":     func(b *models.AccountBackup) { b.Trades[0].Quantity = 0 },
+		"foreign lot":       func(b *models.AccountBackup) { b.Lots[0].UserID = 8 },
+		"lots over holding": func(b *models.AccountBackup) { b.Lots[0].Quantity = 2 },
+		"lot without holding": func(b *models.AccountBackup) {
+			b.Lots = append(b.Lots, models.Lot{UserID: 7, StockSymbol: "MSFT", Quantity: 1, Price: 380.0})
+		},
+		"lower-case symbol":    func(b *models.AccountBackup) { b.Holdings[0].StockSymbol = "aapl" },
+		"long symbol":          func(b *models.AccountBackup) { b.Trades[0].StockSymbol = "ABCDEFGHIJK" },
+		"punctuated symbol":    func(b *models.AccountBackup) { b.Trades[0].StockSymbol = "AA;PL" },
+		"negative margin":      func(b *models.AccountBackup) { b.Holdings[0].MarginUsed = -1 },
+		"foreign order":        func(b *models.AccountBackup) { b.Orders[0].UserID = 8 },
+		"unknown order symbol": func(b *models.AccountBackup) { b.Orders[0].StockSymbol = "ZZZZ" },
+		"limit without price":  func(b *models.AccountBackup) { b.Orders[0].LimitPrice = 0 },
+		"stop buy":             func(b *models.AccountBackup) { b.Orders[1].TradeType = "BUY" },
+		"bad order type":       func(b *models.AccountBackup) { b.Orders[0].OrderType = "MARKET" },
+		"foreign ledger":       func(b *models.AccountBackup) { b.CashTransactions[0].UserID = 8 },
+		"bad ledger type":      func(b *models.AccountBackup) { b.CashTransactions[1].Type = "GIFT" },
+		"zero deposit":         func(b *models.AccountBackup) { b.CashTransactions[1].Amount = 0 },
+	}
+
+	for name, corrupt := range tests {
+		b := valid()
+		corrupt(b)
+		if err := validateBackup(b); err == nil {
+			t.Errorf("%s: expected backup to be rejected", name)
+		}
+	}
+}
+
+// exportBackup fetches a user's backup through the export handler
+func exportBackup(t *testing.T, router *gin.Engine, userID int) models.AccountBackup {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		fmt.Sprintf("/api/admin/users/%d/export", userID), nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export failed with %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data models.AccountBackup `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode backup: %v", err)
+	}
+	return body.Data
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "backup_source", 8500.0)
+
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price, margin_used)
+        VALUES ($1, 'AAPL', 10, 150.0, 500.0), ($1, 'MSFT', 2, 380.0, 0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	// MSFT was bought before lots were tracked
+	_, err = database.Exec(`
+        INSERT INTO portfolio_lots (user_id, stock_symbol, quantity, price, bought_at)
+        VALUES ($1, 'AAPL', 4, 140.0, NOW() - INTERVAL '2 days'), ($1, 'AAPL', 6, 156.67, NOW() - INTERVAL '1 day')
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup lots: %v", err)
+	}
+
+	_, err = database.Exec(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount)
+        VALUES ($1, 'AAPL', 'BUY', 10, 150.0, 1500.0), ($1, 'MSFT', 'BUY', 2, 380.0, 760.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup trades: %v", err)
+	}
+
+	_, err = database.Exec(`
+        INSERT INTO cash_transactions (user_id, type, amount, balance_after, created_at)
+        VALUES ($1, 'DEPOSIT', 1000.0, 11000.0, NOW() - INTERVAL '3 days'),
+               ($1, 'RESET', 10000.0, 10000.0, NOW() - INTERVAL '2 days'),
+               ($1, 'WITHDRAW', 1500.0, 8500.0, NOW() - INTERVAL '1 day')
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup cash transactions: %v", err)
+	}
+
+	savedBook, savedStore := Orders, orderStore
+	Orders = NewOrderBook()
+	orderStore = NewDBOrderStore(database)
+	defer func() { Orders, orderStore = savedBook, savedStore }()
+
+	for _, o := range []models.Order{
+		{UserID: userID, StockSymbol: "MSFT", Quantity: 1, TradeType: "BUY", OrderType: "LIMIT", LimitPrice: 350.0},
+		{UserID: userID, StockSymbol: "AAPL", Quantity: 5, TradeType: "SELL", OrderType: "STOP_LOSS", TriggerPrice: 120.0},
+	} {
+		order, err := orderStore.Insert(o)
+		if err != nil {
+			t.Fatalf("Failed to setup order: %v", err)
+		}
+		Orders.AddOrder(order)
+	}
+
+	exportedAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	tp := NewTradeProcessor(1)
+	tp.clock = clock.NewFake(exportedAt)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/admin/users/:userId/export", tp.ExportAccount)
+	router.POST("/api/admin/users/import", ImportAccount)
+
+	original := exportBackup(t, router, userID)
+	if !original.ExportedAt.Equal(exportedAt) {
+		t.Errorf("Expected the export stamped %v, got %v", exportedAt, original.ExportedAt)
+	}
+
+	payload, _ := json.Marshal(models.ImportRequest{
+		Username: "backup_restored",
+		Email:    "backup_restored@test.com",
+		Backup:   &original,
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/users/import", bytes.NewReader(payload)))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Import failed with %d: %s", w.Code, w.Body.String())
+	}
+
+	var imported struct {
+		Data struct {
+			UserID int `json:"user_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &imported)
+
+	restored := exportBackup(t, router, imported.Data.UserID)
+
+	if restored.User.CashBalance != original.User.CashBalance {
+		t.Errorf("Expected balance %.2f, got %.2f", original.User.CashBalance, restored.User.CashBalance)
+	}
+
+	if len(restored.Holdings) != len(original.Holdings) {
+		t.Fatalf("Expected %d holdings, got %d", len(original.Holdings), len(restored.Holdings))
+	}
+	for i, h := range original.Holdings {
+		r := restored.Holdings[i]
+		if r.StockSymbol != h.StockSymbol || r.Quantity != h.Quantity || r.AvgPurchasePrice != h.AvgPurchasePrice || r.MarginUsed != h.MarginUsed {
+			t.Errorf("Holding %d mismatch: expected %+v, got %+v", i, h, r)
+		}
+	}
+
+	if len(original.Lots) != 2 || len(restored.Lots) != len(original.Lots) {
+		t.Fatalf("Expected 2 lots restored, exported %d and restored %d", len(original.Lots), len(restored.Lots))
+	}
+	for i, lot := range original.Lots {
+		r := restored.Lots[i]
+		if r.StockSymbol != lot.StockSymbol || r.Quantity != lot.Quantity || r.Price != lot.Price || !r.BoughtAt.Equal(lot.BoughtAt) {
+			t.Errorf("Lot %d mismatch: expected %+v, got %+v", i, lot, r)
+		}
+	}
+
+	if len(restored.Trades) != len(original.Trades) {
+		t.Fatalf("Expected %d trades, got %d", len(original.Trades), len(restored.Trades))
+	}
+	for i, tr := range original.Trades {
+		r := restored.Trades[i]
+		if r.StockSymbol != tr.StockSymbol || r.TradeType != tr.TradeType ||
+			r.Quantity != tr.Quantity || r.TotalAmount != tr.TotalAmount || !r.CreatedAt.Equal(tr.CreatedAt) {
+			t.Errorf("Trade %d mismatch: expected %+v, got %+v", i, tr, r)
+		}
+	}
+
+	if original.Holdings[0].MarginUsed != 500.0 {
+		t.Errorf("Expected AAPL exported with 500 of margin, got %.2f", original.Holdings[0].MarginUsed)
+	}
+
+	if len(original.CashTransactions) != 3 || len(restored.CashTransactions) != 3 {
+		t.Fatalf("Expected 3 ledger entries, exported %d and restored %d", len(original.CashTransactions), len(restored.CashTransactions))
+	}
+	for i, ct := range original.CashTransactions {
+		r := restored.CashTransactions[i]
+		if r.Type != ct.Type || r.Amount != ct.Amount || r.BalanceAfter != ct.BalanceAfter || !r.CreatedAt.Equal(ct.CreatedAt) {
+			t.Errorf("Ledger entry %d mismatch: expected %+v, got %+v", i, ct, r)
+		}
+	}
+
+	if len(original.Orders) != 2 || len(restored.Orders) != 2 {
+		t.Fatalf("Expected 2 resting orders, exported %d and restored %d", len(original.Orders), len(restored.Orders))
+	}
+	for i, o := range original.Orders {
+		r := restored.Orders[i]
+		if r.UserID != imported.Data.UserID || r.ID == o.ID || r.StockSymbol != o.StockSymbol || r.Quantity != o.Quantity ||
+			r.TradeType != o.TradeType || r.OrderType != o.OrderType || r.LimitPrice != o.LimitPrice ||
+			r.TriggerPrice != o.TriggerPrice || !r.CreatedAt.Equal(o.CreatedAt) {
+			t.Errorf("Order %d mismatch: expected %+v, got %+v", i, o, r)
+		}
+	}
+
+	// Restored orders are persisted too, so they survive a restart
+	var persisted int
+	database.QueryRow("SELECT COUNT(*) FROM orders WHERE user_id = $1 AND status = 'OPEN'", imported.Data.UserID).Scan(&persisted)
+	if persisted != 2 {
+		t.Errorf("Expected 2 open orders stored for the restored user, got %d", persisted)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// MaxBatchSize caps the trades in one batch request
+const MaxBatchSize = 50
+
+// BatchItemResult is the outcome of one trade in a batch
+type BatchItemResult struct {
+	Index       int     `json:"index"` // Position in the request
+	Success     bool    `json:"success"`
+	TradeID     int     `json:"trade_id,omitempty"`
+	Quantity    int     `json:"quantity,omitempty"`
+	Price       float64 `json:"price,omitempty"`
+	TotalAmount float64 `json:"total_amount,omitempty"`
+	Fee         float64 `json:"fee,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// Batch handles POST /api/trades/batch. Items are independent: each is
+// validated and executed on its own, and one failing doesn't undo or skip
+// the others. A user's items execute one after another in request order,
+// so a sell can fund a later buy; different users' items run in parallel.
+// Only market trades are accepted, not resting orders.
+func (tp *TradeProcessor) Batch(c *gin.Context) {
+	var req models.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Trades) > MaxBatchSize {
+		RespondError(c, http.StatusBadRequest, fmt.Sprintf("A batch may hold at most %d trades", MaxBatchSize))
+		return
+	}
+
+	authUserID, authenticated := AuthenticatedUserID(c)
+
+	results := make([]BatchItemResult, len(req.Trades))
+	byUser := make(map[int][]int) // user ID -> item indexes, in order
+	var users []int
+	for i, item := range req.Trades {
+		results[i].Index = i
+		if authenticated {
+			item.UserID = authUserID
+			req.Trades[i] = item
+		}
+
+		if err := binding.Validator.ValidateStruct(&item); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if item.OrderType != "" && item.OrderType != models.OrderTypeMarket {
+			results[i].Error = "Only MARKET trades can be batched"
+			continue
+		}
+
+		if _, seen := byUser[item.UserID]; !seen {
+			users = append(users, item.UserID)
+		}
+		byUser[item.UserID] = append(byUser[item.UserID], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, userID := range users {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			for _, i := range indexes {
+				results[i] = batchItemResult(i, tp.SubmitTradeWithContext(c.Request.Context(), req.Trades[i]))
+			}
+		}(byUser[userID])
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
+
+// batchItemResult converts a trade result for the batch response
+func batchItemResult(index int, result TradeResult) BatchItemResult {
+	if !result.Success {
+		return BatchItemResult{Index: index, Error: result.Error}
+	}
+	return BatchItemResult{
+		Index:       index,
+		Success:     true,
+		TradeID:     result.TradeID,
+		Quantity:    result.Quantity,
+		Price:       result.Price,
+		TotalAmount: result.TotalAmount,
+		Fee:         result.Fee,
+	}
+}
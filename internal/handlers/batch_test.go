@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+type batchResponse struct {
+	Data struct {
+		Results   []BatchItemResult `json:"results"`
+		Succeeded int               `json:"succeeded"`
+		Failed    int               `json:"failed"`
+	} `json:"data"`
+}
+
+func postBatch(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/trades/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBatch_PartialSuccess(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "batcher", 2000.0)
+	otherID := db.CreateTestUser(t, database, "batcher_other", 2000.0)
+
+	tp := NewTradeProcessor(4)
+	tp.Start()
+	defer tp.Stop()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/trades/batch", tp.Batch)
+
+	// The sell at index 3 depends on the buy at index 0 running first
+	w := postBatch(router, fmt.Sprintf(`{"trades": [
+		{"user_id": %[1]d, "stock_symbol": "AAPL", "quantity": 10, "price": 100},
+		{"user_id": %[1]d, "stock_symbol": "MSFT", "quantity": 1, "price": 300, "trade_type": "SELL"},
+		{"user_id": %[1]d, "stock_symbol": "AAPL", "quantity": 0, "price": 100},
+		{"user_id": %[1]d, "stock_symbol": "AAPL", "quantity": 5, "price": 110, "trade_type": "SELL"},
+		{"user_id": %[1]d, "stock_symbol": "AAPL", "quantity": 1, "price": 90, "order_type": "LIMIT"},
+		{"user_id": %[2]d, "stock_symbol": "MSFT", "quantity": 100, "price": 300}
+	]}`, userID, otherID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp batchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	wantSuccess := []bool{true, false, false, true, false, false}
+	if len(resp.Data.Results) != len(wantSuccess) {
+		t.Fatalf("Expected %d results, got %+v", len(wantSuccess), resp.Data.Results)
+	}
+	for i, want := range wantSuccess {
+		r := resp.Data.Results[i]
+		if r.Index != i || r.Success != want {
+			t.Errorf("Item %d: expected success=%v, got %+v", i, want, r)
+		}
+		if !r.Success && r.Error == "" {
+			t.Errorf("Item %d: expected an error message", i)
+		}
+	}
+	if resp.Data.Succeeded != 2 || resp.Data.Failed != 4 {
+		t.Errorf("Expected 2 succeeded and 4 failed, got %d and %d", resp.Data.Succeeded, resp.Data.Failed)
+	}
+
+	var balance float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if balance != 1550.0 {
+		t.Errorf("Expected balance 1550 after buying 10 at 100 and selling 5 at 110, got %.2f", balance)
+	}
+}
+
+func TestBatch_SizeCap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tp := NewTradeProcessor(1)
+
+	router := gin.New()
+	router.POST("/api/trades/batch", tp.Batch)
+
+	item := `{"user_id": 1, "stock_symbol": "AAPL", "quantity": 1, "price": 100}`
+	items := make([]string, MaxBatchSize+1)
+	for i := range items {
+		items[i] = item
+	}
+
+	w := postBatch(router, `{"trades": [`+strings.Join(items, ",")+`]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for %d trades, got %d", len(items), w.Code)
+	}
+
+	w = postBatch(router, `{"trades": []}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty batch, got %d", w.Code)
+	}
+}
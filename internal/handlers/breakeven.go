@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetBreakEven handles GET /api/portfolio/:userId/:symbol/breakeven
+func (tp *TradeProcessor) GetBreakEven(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	symbol, ok := parseSymbol(c, c.Param("symbol"))
+	if !ok {
+		return
+	}
+
+	response := models.BreakEvenResponse{StockSymbol: symbol}
+	err := db.DB.QueryRow(`
+        SELECT quantity, avg_purchase_price
+        FROM portfolios
+        WHERE user_id = $1 AND stock_symbol = $2 AND quantity > 0
+    `, userID, symbol).Scan(&response.Quantity, &response.AvgPurchasePrice)
+
+	if err == sql.ErrNoRows {
+		RespondError(c, http.StatusNotFound, ErrNoPosition)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	response.BreakEvenPrice = breakEvenPrice(tp.Config.Commission, response.AvgPurchasePrice, response.Quantity)
+	Respond(c, http.StatusOK, response)
+}
+
+// breakEvenPrice returns the sell price at which quantity shares bought at
+// avgPrice recover their cost after the sell commission. Solves
+// p*q - (flat + perShare*q + percent*p*q) = avgPrice*q for p.
+func breakEvenPrice(cm config.Commission, avgPrice float64, quantity int) float64 {
+	if quantity <= 0 || cm.Percent >= 100 {
+		return 0
+	}
+
+	q := float64(quantity)
+	return (avgPrice*q + cm.Flat + cm.PerShare*q) / (q * (1 - cm.Percent/100))
+}
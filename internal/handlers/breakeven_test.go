@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBreakEvenPrice_NoFees(t *testing.T) {
+	if price := breakEvenPrice(config.Commission{}, 150.25, 10); price != 150.25 {
+		t.Errorf("Expected break-even at basis 150.25, got %.4f", price)
+	}
+
+	if price := breakEvenPrice(config.Commission{}, 150.25, 0); price != 0 {
+		t.Errorf("Expected 0 for an empty position, got %.4f", price)
+	}
+}
+
+func TestBreakEvenPrice_ExceedsBasisWhenSellFeesApply(t *testing.T) {
+	cm := config.Commission{Flat: 5.0, PerShare: 0.1, Percent: 1.0}
+
+	price := breakEvenPrice(cm, 100.0, 10)
+	if price <= 100.0 {
+		t.Fatalf("Expected break-even above basis, got %.4f", price)
+	}
+
+	// Selling at break-even nets exactly the cost basis
+	net := price*10 - (cm.Flat + cm.PerShare*10 + cm.Percent/100*price*10)
+	if !approxEqual(net, 1000.0) {
+		t.Errorf("Expected net proceeds 1000 at break-even, got %.4f", net)
+	}
+}
+
+func TestGetBreakEven(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "breakeven", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 3, 101.33)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/portfolio/:userId/:symbol/breakeven", NewTradeProcessor(1).GetBreakEven)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		fmt.Sprintf("/api/portfolio/%d/AAPL/breakeven", userID), nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data models.BreakEvenResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	if body.Data.BreakEvenPrice != 101.33 {
+		t.Errorf("Expected break-even 101.33, got %.4f", body.Data.BreakEvenPrice)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		fmt.Sprintf("/api/portfolio/%d/TSLA/breakeven", userID), nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unheld symbol, got %d", w.Code)
+	}
+}
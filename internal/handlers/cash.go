@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// errInsufficientCash is returned for a withdrawal larger than the balance
+var errInsufficientCash = errors.New("insufficient funds")
+
+// Deposit handles POST /api/users/:userId/deposit
+func (tp *TradeProcessor) Deposit(c *gin.Context) {
+	tp.handleCash(c, models.CashDeposit)
+}
+
+// Withdraw handles POST /api/users/:userId/withdraw
+func (tp *TradeProcessor) Withdraw(c *gin.Context) {
+	tp.handleCash(c, models.CashWithdraw)
+}
+
+// handleCash serves both cash endpoints
+func (tp *TradeProcessor) handleCash(c *gin.Context, kind string) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CashRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	txn, err := tp.MoveCash(userID, kind, req.Amount)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
+		return
+	case errors.Is(err, errInsufficientCash):
+		RespondError(c, http.StatusBadRequest, ErrInsufficientFunds)
+		return
+	case err != nil:
+		RespondError(c, http.StatusInternalServerError, "Failed to update balance")
+		return
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"transaction_id": txn.ID,
+		"type":           txn.Type,
+		"amount":         txn.Amount,
+		"new_balance":    txn.BalanceAfter,
+	})
+}
+
+// MoveCash deposits or withdraws amount (rounded to cents) and records it
+// in cash_transactions, in one transaction under the user's lock so it
+// can't interleave with a trade. A withdrawal that would leave the balance
+// negative fails with errInsufficientCash; an unknown user with
+// sql.ErrNoRows.
+func (tp *TradeProcessor) MoveCash(userID int, kind string, amount float64) (models.CashTransaction, error) {
+	txn := models.CashTransaction{
+		UserID: userID,
+		Type:   kind,
+		Amount: math.Round(amount*100) / 100,
+	}
+	if txn.Amount <= 0 {
+		return txn, errors.New("amount must be at least 0.01")
+	}
+
+	tp.portfolioMgr.LockUser(userID)
+	defer tp.portfolioMgr.UnlockUser(userID)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return txn, err
+	}
+	defer tx.Rollback()
+
+	var balance float64
+	err = tx.QueryRow(
+		"SELECT cash_balance FROM users WHERE id = $1 FOR UPDATE",
+		userID,
+	).Scan(&balance)
+	if err != nil {
+		return txn, err
+	}
+
+	delta := txn.Amount
+	if kind == models.CashWithdraw {
+		if txn.Amount > balance {
+			return txn, errInsufficientCash
+		}
+		delta = -txn.Amount
+	}
+
+	err = tx.QueryRow(
+		"UPDATE users SET cash_balance = cash_balance + $1 WHERE id = $2 RETURNING cash_balance",
+		delta, userID,
+	).Scan(&txn.BalanceAfter)
+	if err != nil {
+		return txn, err
+	}
+
+	err = tx.QueryRow(`
+        INSERT INTO cash_transactions (user_id, type, amount, balance_after)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `, userID, kind, txn.Amount, txn.BalanceAfter).Scan(&txn.ID, &txn.CreatedAt)
+	if err != nil {
+		return txn, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return txn, err
+	}
+
+	slog.Info("Cash moved", "user_id", userID, "type", kind, "amount", txn.Amount, "balance", txn.BalanceAfter)
+	return txn, nil
+}
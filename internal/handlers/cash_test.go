@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMoveCash_DepositAndWithdraw(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "cash", 1000.0)
+
+	tp := NewTradeProcessor(1)
+
+	txn, err := tp.MoveCash(userID, models.CashDeposit, 250.5)
+	if err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	if txn.BalanceAfter != 1250.5 {
+		t.Errorf("Expected balance 1250.50 after deposit, got %.2f", txn.BalanceAfter)
+	}
+
+	if _, err := tp.MoveCash(userID, models.CashWithdraw, 1250.51); err != errInsufficientCash {
+		t.Errorf("Expected errInsufficientCash for an over-withdrawal, got %v", err)
+	}
+
+	txn, err = tp.MoveCash(userID, models.CashWithdraw, 1250.5)
+	if err != nil {
+		t.Fatalf("Withdrawing the whole balance failed: %v", err)
+	}
+	if txn.BalanceAfter != 0 {
+		t.Errorf("Expected balance 0, got %.2f", txn.BalanceAfter)
+	}
+
+	// Only the two that went through are recorded
+	var count int
+	database.QueryRow("SELECT COUNT(*) FROM cash_transactions WHERE user_id = $1", userID).Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 cash transactions, got %d", count)
+	}
+
+	if _, err := tp.MoveCash(99999, models.CashDeposit, 10); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+}
+
+func TestMoveCash_ConcurrentDeposits(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "cash_concurrent", 0.0)
+
+	tp := NewTradeProcessor(1)
+
+	const deposits = 20
+	var wg sync.WaitGroup
+	for i := 0; i < deposits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tp.MoveCash(userID, models.CashDeposit, 12.34); err != nil {
+				t.Errorf("Deposit failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var balance float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if balance != 246.8 {
+		t.Errorf("Expected balance 246.80 after %d deposits, got %.2f", deposits, balance)
+	}
+}
+
+func TestDeposit_RejectsNonPositiveAmount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tp := NewTradeProcessor(1)
+
+	router := gin.New()
+	router.POST("/api/users/:userId/deposit", tp.Deposit)
+
+	for _, body := range []string{`{"amount": 0}`, `{"amount": -5}`, `{}`} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/users/1/deposit", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
@@ -1,181 +1,1180 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
 	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/metrics"
 	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxUpsertAttempts bounds how often a portfolio upsert is retried after
+// losing a first-buy race on the (user_id, stock_symbol) constraint
+const maxUpsertAttempts = 3
+
+// ErrorKind classifies why a trade failed, so callers can react without
+// matching on the message
+type ErrorKind int
+
+// Trade failure kinds. KindNone is the zero value, set on successes.
+const (
+	KindNone               ErrorKind = iota
+	KindBadRequest                   // The trade can't be made as asked
+	KindNotFound                     // The user doesn't exist
+	KindInsufficientFunds            // Not enough cash for the buy
+	KindInternal                     // Database failure
+	KindTimeout                      // The caller's context expired
+	KindUnavailable                  // Busy or stopped; retrying later may succeed
+	KindNoPosition                   // Selling a stock the user doesn't hold
+	KindInsufficientShares           // Selling more shares than held
+	KindNoMarketPrice                // A MARKET order in a symbol not yet quoted
+	KindInsufficientMargin           // Not enough equity to back a short or margin buy
+	KindMarketClosed                 // Outside Config.MarketHours
+	KindPositionLimit                // The buy would grow a holding past its limit
+	KindHalted                       // Trading in the symbol is halted
 )
 
 // TradeResult represents result of a trade operation
 type TradeResult struct {
 	TradeID     int
 	Success     bool
-	Error       string
+	Kind        ErrorKind // Set when Success is false
+	Error       string    // Human-readable message for the API response
+	Quantity    int       // Shares traded
+	Price       float64   // Per-share fill price
+	QuotedPrice float64   // MARKET orders: the market price before slippage
 	TotalAmount float64
+	Fee         float64 // Commission charged on top of (buy) or out of (sell) TotalAmount
+	Warning     string  // Non-blocking advisory, e.g. concentration
+	Seq         int64   // Submission order
+	RequestID   string  // Correlation ID of the submitting call
+	Replayed    bool    // Returned from an earlier trade with the same idempotency key
+	DryRun      bool    // Nothing was committed; TradeID is 0
+
+	// Buys and dry runs: the holding as the trade left it, zero if it
+	// closed the position
+	PositionQuantity int
+	PositionAvgPrice float64
+
+	CashBalance float64 // Dry runs: the cash the trade would leave
+}
+
+// HTTPStatus returns the status code a handler should send for this result
+func (r TradeResult) HTTPStatus() int {
+	if r.Success {
+		return http.StatusOK
+	}
+	switch r.Kind {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindInternal:
+		return http.StatusInternalServerError
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
 }
 
 // TradeRequest represents a trade to be processed
 type TradeRequest struct {
 	Request  models.BuyRequest
+	Seq      int64            // Assigned at submission, orders trade history
+	Ctx      context.Context  // Trades whose caller gave up are skipped
 	ResultCh chan TradeResult // Channel to send result back
+
+	queueSpan trace.Span // Open from enqueue until a worker picks the trade up
+}
+
+// tracerName identifies this package's spans
+const tracerName = "github.com/atharvakonge/stock-trading-simulator/internal/handlers"
+
+// tradeSeq hands out submission sequence numbers. It is seeded from the
+// clock so numbers keep increasing across restarts.
+var tradeSeq atomic.Int64
+
+func init() {
+	tradeSeq.Store(time.Now().UnixNano())
 }
 
+// nextTradeSeq returns the next submission sequence number
+func nextTradeSeq() int64 {
+	return tradeSeq.Add(1)
+}
+
+// errProcessorStopped is returned for trades submitted after Stop
+const errProcessorStopped = "processor stopped"
+
+// ErrTradeTimedOut is returned when the caller's context expires before the
+// trade is queued or executed
+const ErrTradeTimedOut = "request timed out"
+
+// ErrSystemBusy is returned when the trade queue is full
+const ErrSystemBusy = "system busy, try again"
+
+// ErrUserBusy is returned when another trade by the same user holds the
+// user's lock past Config.UserLockTimeout
+const ErrUserBusy = "user busy, try again"
+
+// ErrUnknownSymbol is returned for trades in a symbol the market doesn't list
+const ErrUnknownSymbol = "unknown symbol"
+
+// ErrPriceOutOfRange is returned for buys priced too far from the market
+const ErrPriceOutOfRange = "price out of range"
+
+// ErrNoMarketPrice is returned for MARKET orders in a symbol that hasn't
+// been quoted yet
+const ErrNoMarketPrice = "no market price available"
+
+// ErrMarketClosed is returned for trades submitted outside
+// Config.MarketHours
+const ErrMarketClosed = "market closed"
+
+// ErrTradingHalted is returned for trades in a symbol listed in Halts
+const ErrTradingHalted = "trading halted"
+
+// ErrDatabaseUnavailable is returned for trades while the database can't
+// be reached
+const ErrDatabaseUnavailable = "database unavailable"
+
+// Messages of the business rejections. Callers should switch on
+// TradeResult.Kind; these are the wording sent to clients.
+const (
+	ErrUserNotFound       = "User not found"
+	ErrInsufficientFunds  = "Insufficient funds"
+	ErrNoPosition         = "You don't own this stock"
+	ErrInsufficientShares = "Insufficient shares"
+	ErrInsufficientMargin = "Insufficient margin"
+	ErrMaintenanceMargin  = "Trade would breach maintenance margin"
+	ErrPositionLimit      = "position limit exceeded"
+)
+
 // TradeProcessor handles concurrent trade processing
 type TradeProcessor struct {
-	workers      int
+	Config config.Config // Must be set before Start
+
+	// Trades receives a confirmation for every trade that commits
+	Trades *TradeFeed
+
+	// Notifier is sent the same confirmations, each on its own goroutine so
+	// a slow notifier never holds up a worker. Defaults to telling no one;
+	// must be set before Start.
+	Notifier Notifier
+
+	// Tracer starts the spans of each trade. Defaults to the otel global
+	// provider's as of NewTradeProcessor; must be set before Start.
+	Tracer trace.Tracer
+
+	// DBMonitor, if set, reports database outages, during which trades
+	// are turned away and readiness fails. Must be set before Start.
+	DBMonitor *db.Monitor
+
+	// clock tells the time for market hours, idempotency expiry and
+	// confirmations
+	clock clock.Clock
+
 	tradeQueue   chan TradeRequest
 	stopCh       chan struct{}
+	retireCh     chan struct{} // Each receive retires one idle worker
+	doneCh       chan struct{} // Closed once every worker has exited
+	stopped      atomic.Bool
 	wg           sync.WaitGroup
 	portfolioMgr *models.PortfolioManager
+
+	// Pool size, changed by AddWorkers and RemoveWorkers
+	poolMu       sync.Mutex
+	workers      int
+	nextWorkerID int
+
+	// Worker metrics, read by Stats
+	activeWorkers   atomic.Int64
+	tradesProcessed atomic.Int64
+	tradesFailed    atomic.Int64
+}
+
+// ProcessorStats is a point-in-time view of the worker pool
+type ProcessorStats struct {
+	QueueLength     int   `json:"queue_length"`
+	QueueCapacity   int   `json:"queue_capacity"`
+	Workers         int   `json:"workers"`        // Pool size; a retiring worker may still be finishing a trade
+	ActiveWorkers   int64 `json:"active_workers"` // Workers currently executing a trade
+	TradesProcessed int64 `json:"trades_processed"`
+	TradesFailed    int64 `json:"trades_failed"`
 }
 
 // NewTradeProcessor creates a new trade processor with worker pool
 func NewTradeProcessor(workers int) *TradeProcessor {
 	return &TradeProcessor{
+		Config:       config.Default(),
+		Trades:       NewTradeFeed(),
+		Notifier:     noopNotifier{},
+		Tracer:       otel.Tracer(tracerName),
+		clock:        clock.Real(),
 		workers:      workers,
 		tradeQueue:   make(chan TradeRequest, 100), // Buffer of 100 trades
 		stopCh:       make(chan struct{}),
+		retireCh:     make(chan struct{}),
+		doneCh:       make(chan struct{}),
 		portfolioMgr: models.NewPortfolioManager(),
 	}
 }
 
 // Start starts the worker pool
 func (tp *TradeProcessor) Start() {
+	tp.poolMu.Lock()
+	defer tp.poolMu.Unlock()
+
 	for i := 0; i < tp.workers; i++ {
-		tp.wg.Add(1)
-		go tp.worker(i)
+		tp.spawnWorker()
+	}
+	slog.Info("Trade workers started", "workers", tp.workers)
+}
+
+// spawnWorker starts one worker goroutine. The caller holds poolMu.
+func (tp *TradeProcessor) spawnWorker() {
+	tp.wg.Add(1)
+	go tp.worker(tp.nextWorkerID)
+	tp.nextWorkerID++
+}
+
+// AddWorkers grows a started pool by n workers and returns the new pool
+// size. It does nothing once Stop has been called.
+func (tp *TradeProcessor) AddWorkers(n int) int {
+	tp.poolMu.Lock()
+	defer tp.poolMu.Unlock()
+
+	// Checked under poolMu, which Stop takes before waiting on the
+	// workers, so no worker is added to a WaitGroup being waited on
+	if tp.stopped.Load() {
+		return tp.workers
+	}
+	for i := 0; i < n; i++ {
+		tp.spawnWorker()
+	}
+	tp.workers += max(n, 0)
+	slog.Info("Trade workers added", "added", max(n, 0), "workers", tp.workers)
+	return tp.workers
+}
+
+// RemoveWorkers shrinks the pool by n workers, keeping at least one, and
+// returns the new pool size. A worker only retires between trades, so a
+// trade it's executing always completes; RemoveWorkers waits until every
+// retiring worker has picked up its signal.
+func (tp *TradeProcessor) RemoveWorkers(n int) int {
+	tp.poolMu.Lock()
+	n = min(n, tp.workers-1)
+	if n <= 0 || tp.stopped.Load() {
+		size := tp.workers
+		tp.poolMu.Unlock()
+		return size
+	}
+	tp.workers -= n
+	size := tp.workers
+	tp.poolMu.Unlock()
+
+	for i := 0; i < n; i++ {
+		select {
+		case tp.retireCh <- struct{}{}:
+		case <-tp.stopCh:
+			// Every worker exits anyway
+			return size
+		}
 	}
-	log.Printf("✅ Started %d trade workers", tp.workers)
+	slog.Info("Trade workers removed", "removed", n, "workers", size)
+	return size
 }
 
-// Stop gracefully stops all workers
+// Stop gracefully stops all workers. Later calls are no-ops.
+//
+// Ordering: new submissions are refused first; workers then execute every
+// trade already in the queue before exiting, so each queued caller gets its
+// real result. Anything that slips into the queue after the workers have
+// exited is answered with "processor stopped" rather than left waiting.
 func (tp *TradeProcessor) Stop() {
+	if !tp.stopped.CompareAndSwap(false, true) {
+		return
+	}
+
+	// Under poolMu so AddWorkers can't race the wait below
+	tp.poolMu.Lock()
 	close(tp.stopCh)
+	tp.poolMu.Unlock()
 	tp.wg.Wait()
-	log.Println("Trade processor stopped")
+
+	// Workers are gone, so nothing else receives from the queue; answer
+	// anything that raced in after they drained
+	for len(tp.tradeQueue) > 0 {
+		tradeReq := <-tp.tradeQueue
+		tradeReq.ResultCh <- TradeResult{Success: false, Kind: KindUnavailable, Error: errProcessorStopped}
+	}
+
+	close(tp.doneCh)
+	slog.Info("Trade processor stopped")
+}
+
+// Stats returns current queue and worker metrics. Safe to call while
+// trades are flowing.
+func (tp *TradeProcessor) Stats() ProcessorStats {
+	tp.poolMu.Lock()
+	workers := tp.workers
+	tp.poolMu.Unlock()
+
+	return ProcessorStats{
+		QueueLength:     len(tp.tradeQueue),
+		QueueCapacity:   cap(tp.tradeQueue),
+		Workers:         workers,
+		ActiveWorkers:   tp.activeWorkers.Load(),
+		TradesProcessed: tp.tradesProcessed.Load(),
+		TradesFailed:    tp.tradesFailed.Load(),
+	}
 }
 
-// worker processes trades from the queue
+// RegisterMetrics adds the queue_depth and active_workers gauges to reg,
+// read from Stats at each scrape
+func (tp *TradeProcessor) RegisterMetrics(reg prometheus.Registerer) error {
+	queueDepth := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "queue_depth",
+		Help: "Trades waiting in the queue for a worker.",
+	}, func() float64 { return float64(len(tp.tradeQueue)) })
+
+	activeWorkers := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "active_workers",
+		Help: "Workers currently executing a trade.",
+	}, func() float64 { return float64(tp.activeWorkers.Load()) })
+
+	if err := reg.Register(queueDepth); err != nil {
+		return err
+	}
+	return reg.Register(activeWorkers)
+}
+
+// worker processes trades from the queue until Stop, then drains it, or
+// until RemoveWorkers retires it
 func (tp *TradeProcessor) worker(id int) {
 	defer tp.wg.Done()
 
-	log.Printf("Worker %d started", id)
+	slog.Debug("Worker started", "worker_id", id)
 
 	for {
 		select {
 		case <-tp.stopCh:
-			log.Printf("Worker %d stopping", id)
+			tp.drain(id)
+			slog.Debug("Worker stopping", "worker_id", id)
+			return
+
+		case <-tp.retireCh:
+			slog.Debug("Worker retired", "worker_id", id)
 			return
 
 		case tradeReq := <-tp.tradeQueue:
-			log.Printf("Worker %d processing trade for User %d: %s x%d",
-				id, tradeReq.Request.UserID, tradeReq.Request.StockSymbol, tradeReq.Request.Quantity)
+			tp.execute(id, tradeReq)
+		}
+	}
+}
 
-			result := tp.processTrade(tradeReq.Request)
-			tradeReq.ResultCh <- result
+// drain executes whatever is still queued once Stop has been called
+func (tp *TradeProcessor) drain(id int) {
+	for {
+		select {
+		case tradeReq := <-tp.tradeQueue:
+			tp.execute(id, tradeReq)
+		default:
+			return
 		}
 	}
 }
 
-// processTrade executes a single trade with per-user locking
-func (tp *TradeProcessor) processTrade(req models.BuyRequest) TradeResult {
+// execute runs one queued trade and sends its result to the caller.
+// MARKET orders are priced here, at the latest quote, rather than at
+// submission. Halts are checked here too, so a trade queued just before
+// its symbol halted doesn't slip through.
+func (tp *TradeProcessor) execute(id int, tradeReq TradeRequest) {
+	if tradeReq.queueSpan != nil {
+		tradeReq.queueSpan.End()
+	}
+	start := time.Now()
+	req := tradeReq.Request
+	quoted := true
+	if req.OrderType == models.OrderTypeMarket {
+		var quote market.Quote
+		quote, quoted = Prices.Get(req.StockSymbol)
+		req.QuotedPrice = quote.Price
+		req.Price = tp.Config.Slippage.FillPrice(quote.Price, req.Quantity, req.TradeType == models.TradeTypeSell)
+	}
+
+	logger := slog.With(
+		"worker_id", id,
+		"user_id", req.UserID,
+		"symbol", req.StockSymbol,
+		"trade_type", req.TradeType,
+		"quantity", req.Quantity,
+		"price", req.Price,
+		"request_id", req.RequestID,
+	)
+	logger.Debug("Processing trade")
+
+	tp.activeWorkers.Add(1)
+	var result TradeResult
+	executed := false
+	switch {
+	case tradeReq.Ctx.Err() != nil:
+		// Caller already gave up; don't execute behind its back
+		result = TradeResult{Success: false, Kind: KindTimeout, Error: ErrTradeTimedOut}
+	case Halts.IsHalted(req.StockSymbol):
+		result = TradeResult{Success: false, Kind: KindHalted, Error: ErrTradingHalted}
+	case !quoted:
+		result = TradeResult{Success: false, Kind: KindNoMarketPrice, Error: ErrNoMarketPrice}
+	case req.TradeType == models.TradeTypeSell:
+		result = tp.processSellTrade(tradeReq.Ctx, req, tradeReq.Seq)
+		executed = true
+	default:
+		result = tp.processBuyTrade(tradeReq.Ctx, req, tradeReq.Seq)
+		executed = true
+	}
+	// Replays were recorded when they first ran; dry runs leave no trace
+	if executed && !result.Replayed && !req.DryRun {
+		recordTradeAttempt(req, result)
+	}
+	tp.activeWorkers.Add(-1)
+
+	// Dry runs are rolled back, so they count towards no trade metrics
+	if !req.DryRun {
+		tp.tradesProcessed.Add(1)
+		metrics.TradeDuration.Observe(time.Since(start).Seconds())
+		outcome := metrics.OutcomeSuccess
+		if !result.Success {
+			tp.tradesFailed.Add(1)
+			outcome = metrics.OutcomeFailure
+		}
+		metrics.TradeTotal.WithLabelValues(metricTradeType(req), outcome).Inc()
+	}
+
+	if !result.Success {
+		logger.Info("Trade rejected", "error", result.Error)
+	} else {
+		logger.Info("Trade executed", "trade_id", result.TradeID, "total_amount", result.TotalAmount, "fee", result.Fee)
+		if !result.Replayed && !result.DryRun {
+			confirmation := newTradeConfirmation(req, result, tp.clock.Now())
+			tp.Trades.Publish(confirmation)
+			go tp.notify(confirmation)
+		}
+	}
+	tradeReq.ResultCh <- result
+}
+
+// metricTradeType is the trade_total "type" label for req
+func metricTradeType(req models.BuyRequest) string {
+	if req.TradeType == models.TradeTypeSell {
+		return "sell"
+	}
+	return "buy"
+}
+
+// processBuyTrade executes a single buy with per-user locking, retrying
+// transient database errors
+func (tp *TradeProcessor) processBuyTrade(ctx context.Context, req models.BuyRequest, seq int64) TradeResult {
 	// Lock portfolio for THIS USER ONLY (not global!)
-	tp.portfolioMgr.LockUser(req.UserID)
+	if !tp.lockUser(req.UserID) {
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: ErrUserBusy}
+	}
 	defer tp.portfolioMgr.UnlockUser(req.UserID)
 
+	return tp.traceTransaction(ctx, req, func() TradeResult {
+		return tp.idempotent(req, func() TradeResult {
+			return withRetry(req.UserID, func() (TradeResult, error) {
+				return tp.buyOnce(req, seq)
+			})
+		})
+	})
+}
+
+// buyOnce runs one buy transaction. The error is set only for database
+// failures, so the caller can tell what's worth retrying.
+func (tp *TradeProcessor) buyOnce(req models.BuyRequest, seq int64) (TradeResult, error) {
 	// Start database transaction
-	tx, err := db.DB.Begin()
+	tx, err := tp.beginTradeTx()
 	if err != nil {
-		return TradeResult{Success: false, Error: "Transaction failed"}
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: ErrDatabaseUnavailable}, err
 	}
 	defer tx.Rollback()
 
 	totalCost := req.Price * float64(req.Quantity)
+	fee := tp.Config.Commission.Fee(req.Quantity, req.Price)
 
 	// 1. Check user has enough cash
 	var cashBalance float64
+	var createdAt, now time.Time
 	err = tx.QueryRow(
-		"SELECT cash_balance FROM users WHERE id = $1 FOR UPDATE",
+		"SELECT cash_balance, created_at, LOCALTIMESTAMP FROM users WHERE id = $1 FOR UPDATE",
 		req.UserID,
-	).Scan(&cashBalance)
+	).Scan(&cashBalance, &createdAt, &now)
 
 	if err == sql.ErrNoRows {
-		return TradeResult{Success: false, Error: "User not found"}
+		return TradeResult{Success: false, Kind: KindNotFound, Error: ErrUserNotFound}, nil
 	}
 	if err != nil {
-		return TradeResult{Success: false, Error: "Database error"}
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
 	}
 
-	if cashBalance < totalCost {
-		return TradeResult{Success: false, Error: "Insufficient funds"}
+	if msg := accountAgeError(tp.Config, createdAt, now, req.StockSymbol, totalCost); msg != "" {
+		return TradeResult{Success: false, Kind: KindBadRequest, Error: msg}, nil
+	}
+
+	// A buy against a short position covers it
+	position, positionAvg, version, err := selectPosition(tx, tp.Config, req.UserID, req.StockSymbol)
+	if err != nil && err != sql.ErrNoRows {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+	}
+	if position < 0 {
+		result, err := coverShort(tx, tp.Config, req, seq, cashBalance, position, positionAvg, version)
+		if !result.Success {
+			return result, err
+		}
+		return tp.commitTrade(tx, req, result)
+	}
+
+	limit := tp.Config.PositionLimitFor(req.StockSymbol)
+	if !limit.Allows(position+req.Quantity, 0, 0) {
+		return TradeResult{Success: false, Kind: KindPositionLimit, Error: ErrPositionLimit}, nil
+	}
+
+	// Without margin the buy must fit in cash. With it, it must fit in
+	// buying power, borrowing what cash doesn't cover. A percent limit
+	// needs the account valued either way.
+	var account marginAccount
+	if tp.Config.MarginMultiplier > 1 || limit.MaxPercent > 0 {
+		account, err = loadMarginAccount(tx, req.UserID, cashBalance)
+		if err != nil {
+			return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+		}
 	}
 
-	// 2. Deduct cash
-	_, err = tx.Exec(
+	var marginUsed float64
+	if tp.Config.MarginMultiplier <= 1 {
+		if cashBalance < totalCost+fee {
+			return TradeResult{Success: false, Kind: KindInsufficientFunds, Error: ErrInsufficientFunds}, nil
+		}
+	} else {
+		if account.buyingPower(tp.Config.MarginMultiplier) < totalCost+fee {
+			return TradeResult{Success: false, Kind: KindInsufficientFunds, Error: ErrInsufficientFunds}, nil
+		}
+		after := account.afterBuy(req.StockSymbol, req.Quantity, req.Price, totalCost, fee)
+		if !after.meetsMaintenance(tp.Config.MaintenanceMarginPercent) {
+			return TradeResult{Success: false, Kind: KindInsufficientMargin, Error: ErrMaintenanceMargin}, nil
+		}
+		marginUsed = math.Max(totalCost+fee-math.Max(cashBalance, 0), 0)
+	}
+
+	if limit.MaxPercent > 0 {
+		after := account.afterBuy(req.StockSymbol, req.Quantity, req.Price, totalCost, fee)
+		holding := valueHolding(models.Portfolio{
+			StockSymbol:      req.StockSymbol,
+			Quantity:         position + req.Quantity,
+			AvgPurchasePrice: req.Price,
+		}, Prices)
+		if !limit.Allows(position+req.Quantity, holding.CurrentValue, after.equity()) {
+			return TradeResult{Success: false, Kind: KindPositionLimit, Error: ErrPositionLimit}, nil
+		}
+	}
+
+	// 2. Deduct cash, commission included
+	err = execOne(tx,
 		"UPDATE users SET cash_balance = cash_balance - $1 WHERE id = $2",
-		totalCost, req.UserID,
+		totalCost+fee, req.UserID,
 	)
 	if err != nil {
-		return TradeResult{Success: false, Error: "Failed to update balance"}
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update balance"}, err
 	}
 
 	// 3. Update portfolio
-	_, err = tx.Exec(`
-        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
-        VALUES ($1, $2, $3, $4)
-        ON CONFLICT (user_id, stock_symbol) 
-        DO UPDATE SET 
-            quantity = portfolios.quantity + $3,
-            avg_purchase_price = (
-                (portfolios.avg_purchase_price * portfolios.quantity) + ($4 * $3)
-            ) / (portfolios.quantity + $3),
-            updated_at = NOW()
-    `, req.UserID, req.StockSymbol, req.Quantity, req.Price)
+	err = upsertPortfolio(tx, req.UserID, req.StockSymbol, req.Quantity, req.Price, version)
+	if err == nil && marginUsed > 0 {
+		err = execOne(tx,
+			"UPDATE portfolios SET margin_used = margin_used + $1 WHERE user_id = $2 AND stock_symbol = $3",
+			marginUsed, req.UserID, req.StockSymbol,
+		)
+	}
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update portfolio"}, err
+	}
 
+	// Read the holding back so the caller sees what this trade committed
+	var newQuantity int
+	var newAvgPrice float64
+	err = tx.QueryRow(
+		"SELECT quantity, avg_purchase_price FROM portfolios WHERE user_id = $1 AND stock_symbol = $2",
+		req.UserID, req.StockSymbol,
+	).Scan(&newQuantity, &newAvgPrice)
 	if err != nil {
-		return TradeResult{Success: false, Error: "Failed to update portfolio"}
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update portfolio"}, err
 	}
 
 	// 4. Record trade
 	var tradeID int
 	err = tx.QueryRow(`
-        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount)
-        VALUES ($1, $2, 'BUY', $3, $4, $5)
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, seq, note, request_id, quoted_price)
+        VALUES ($1, $2, 'BUY', $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10::numeric, 0))
         RETURNING id
-    `, req.UserID, req.StockSymbol, req.Quantity, req.Price, totalCost).Scan(&tradeID)
+    `, req.UserID, req.StockSymbol, req.Quantity, req.Price, totalCost, fee, seq, req.Note, req.RequestID, req.QuotedPrice).Scan(&tradeID)
 
 	if err != nil {
-		return TradeResult{Success: false, Error: "Failed to record trade"}
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to record trade"}, err
+	}
+
+	if err = recordLot(tx, req.UserID, req.StockSymbol, req.Quantity, req.Price, tradeID); err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to record lot"}, err
+	}
+
+	// 5. Check concentration against the committed state, valued at the
+	// latest prices
+	var warning string
+	if tp.Config.ConcentrationWarning {
+		holdingsValue, positionValue, err := valueCommittedPositions(tx, req.UserID, req.StockSymbol)
+		if err != nil {
+			return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to value portfolio"}, err
+		}
+
+		warning = concentrationWarning(req.StockSymbol, positionValue,
+			cashBalance-totalCost-fee+holdingsValue, tp.Config.ConcentrationWarningPercent)
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		return TradeResult{Success: false, Error: "Transaction commit failed"}
+	return tp.commitTrade(tx, req, TradeResult{
+		TradeID:          tradeID,
+		Success:          true,
+		Quantity:         req.Quantity,
+		Price:            req.Price,
+		QuotedPrice:      req.QuotedPrice,
+		TotalAmount:      totalCost,
+		Fee:              fee,
+		Warning:          warning,
+		Seq:              seq,
+		PositionQuantity: newQuantity,
+		PositionAvgPrice: newAvgPrice,
+	})
+}
+
+// processSellTrade executes a single sell with per-user locking, retrying
+// transient database errors
+func (tp *TradeProcessor) processSellTrade(ctx context.Context, req models.BuyRequest, seq int64) TradeResult {
+	if !tp.lockUser(req.UserID) {
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: ErrUserBusy}
 	}
+	defer tp.portfolioMgr.UnlockUser(req.UserID)
+
+	return tp.traceTransaction(ctx, req, func() TradeResult {
+		return tp.idempotent(req, func() TradeResult {
+			return withRetry(req.UserID, func() (TradeResult, error) {
+				return tp.sellOnce(req, seq)
+			})
+		})
+	})
+}
 
-	log.Printf("Worker completed trade %d for User %d", tradeID, req.UserID)
+// traceTransaction runs fn, the trade's database work and its retries, in
+// a span under ctx's
+func (tp *TradeProcessor) traceTransaction(ctx context.Context, req models.BuyRequest, fn func() TradeResult) TradeResult {
+	_, span := tp.Tracer.Start(ctx, "trade.transaction", trace.WithAttributes(tradeAttributes(req)...))
+	defer span.End()
+
+	result := fn()
+	endTradeSpan(span, result)
+	return result
+}
+
+// tradeAttributes describes req on a span
+func tradeAttributes(req models.BuyRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int("user_id", req.UserID),
+		attribute.String("symbol", req.StockSymbol),
+		attribute.String("trade_type", metricTradeType(req)),
+	}
+}
+
+// endTradeSpan records the outcome of a trade on span
+func endTradeSpan(span trace.Span, result TradeResult) {
+	if result.Success {
+		span.SetAttributes(attribute.String("outcome", metrics.OutcomeSuccess))
+		return
+	}
+	span.SetAttributes(attribute.String("outcome", metrics.OutcomeFailure))
+	span.SetStatus(codes.Error, result.Error)
+}
+
+// sellOnce runs one sell transaction; see buyOnce
+func (tp *TradeProcessor) sellOnce(req models.BuyRequest, seq int64) (TradeResult, error) {
+	tx, err := tp.beginTradeTx()
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: ErrDatabaseUnavailable}, err
+	}
+	defer tx.Rollback()
+
+	result, err := executeSell(tx, tp.Config, req, seq)
+	if !result.Success {
+		return result, err
+	}
+	return tp.commitTrade(tx, req, result)
+}
+
+// executeSell does a sell's work inside tx, which the caller commits only
+// if the result is a success. Like sellOnce, the error is set only for
+// database failures. It takes no locks of its own, so callers that can race
+// with other trades by the same user must hold the user's lock.
+func executeSell(tx *sql.Tx, cfg config.Config, req models.BuyRequest, seq int64) (TradeResult, error) {
+	// 1. Check user owns enough shares
+	currentQuantity, avgPrice, version, err := selectPosition(tx, cfg, req.UserID, req.StockSymbol)
+
+	held := err != sql.ErrNoRows
+	if err != nil && held {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+	}
+
+	// Selling what isn't held long opens or adds to a short, if allowed.
+	// Selling everything never does.
+	if cfg.ShortSelling && !req.SellAll && currentQuantity <= 0 {
+		return executeShortSell(tx, cfg, req, seq, held, currentQuantity, avgPrice, version)
+	}
+	if cfg.ShortSelling && !req.SellAll && currentQuantity < req.Quantity {
+		return sellThroughZero(tx, cfg, req, seq, currentQuantity)
+	}
+	if !held || (req.SellAll && currentQuantity <= 0) {
+		return TradeResult{Success: false, Kind: KindNoPosition, Error: ErrNoPosition}, nil
+	}
+
+	// The locked row decides how much "everything" is
+	if req.SellAll {
+		req.Quantity = currentQuantity
+	}
+
+	if currentQuantity < req.Quantity {
+		return TradeResult{
+			Success: false,
+			Kind:    KindInsufficientShares,
+			Error: fmt.Sprintf("%s. You own %d, trying to sell %d",
+				ErrInsufficientShares, currentQuantity, req.Quantity),
+		}, nil
+	}
+
+	totalProceeds := req.Price * float64(req.Quantity)
+	fee := cfg.Commission.Fee(req.Quantity, req.Price)
+
+	// 2. Update portfolio (reduce quantity)
+	newQuantity := currentQuantity - req.Quantity
+	if newQuantity == 0 {
+		err = execVersioned(tx,
+			"DELETE FROM portfolios WHERE user_id = $1 AND stock_symbol = $2 AND version = $3",
+			req.UserID, req.StockSymbol, version,
+		)
+	} else {
+		// The sold shares' share of the margin loan goes with them
+		err = execVersioned(tx, `
+            UPDATE portfolios
+            SET quantity = $1, margin_used = ROUND(margin_used * $4 / quantity, 2), version = version + 1, updated_at = NOW()
+            WHERE user_id = $2 AND stock_symbol = $3 AND version = $5
+        `, newQuantity, req.UserID, req.StockSymbol, float64(newQuantity), version)
+	}
+
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update portfolio"}, err
+	}
+
+	// 3. Add proceeds to cash, less commission
+	err = execOne(tx,
+		"UPDATE users SET cash_balance = cash_balance + $1 WHERE id = $2",
+		totalProceeds-fee, req.UserID,
+	)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update balance"}, err
+	}
+
+	// 4. Close lots oldest first, then record the trade with its realized
+	// P&L under the configured cost basis
+	lotCost, lotShares, err := consumeLots(tx, req.UserID, req.StockSymbol, req.Quantity)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update lots"}, err
+	}
+	realizedPL := sellRealizedPL(cfg.CostBasis, totalProceeds, fee, avgPrice, req.Quantity, lotCost, lotShares)
+
+	var tradeID int
+	err = tx.QueryRow(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, realized_pl, seq, note, request_id, quoted_price)
+        VALUES ($1, $2, 'SELL', $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11::numeric, 0))
+        RETURNING id
+    `, req.UserID, req.StockSymbol, req.Quantity, req.Price, totalProceeds, fee, realizedPL, seq, req.Note, req.RequestID, req.QuotedPrice).Scan(&tradeID)
+
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to record trade"}, err
+	}
 
 	return TradeResult{
 		TradeID:     tradeID,
 		Success:     true,
-		TotalAmount: totalCost,
+		Quantity:    req.Quantity,
+		Price:       req.Price,
+		QuotedPrice: req.QuotedPrice,
+		TotalAmount: totalProceeds,
+		Fee:         fee,
+		Seq:         seq,
+	}, nil
+}
+
+// lockUser takes userID's lock for a trade, waiting at most
+// Config.UserLockTimeout, and reports whether it got it
+func (tp *TradeProcessor) lockUser(userID int) bool {
+	if tp.Config.UserLockTimeout <= 0 {
+		tp.portfolioMgr.LockUser(userID)
+		return true
+	}
+	return tp.portfolioMgr.TryLockUser(userID, tp.Config.UserLockTimeout)
+}
+
+// beginTradeTx starts a trade transaction at the configured isolation level.
+// Serialization failures it causes are retried by withRetry.
+func (tp *TradeProcessor) beginTradeTx() (*sql.Tx, error) {
+	return db.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: tp.Config.TradeIsolation})
+}
+
+// accountAgeError returns why an account created at createdAt may not place
+// this order yet, or "" if it may. Times come from the database clock.
+func accountAgeError(cfg config.Config, createdAt, now time.Time, symbol string, orderValue float64) string {
+	if cfg.MinAccountAge <= 0 {
+		return ""
+	}
+
+	allowedAt := createdAt.Add(cfg.MinAccountAge)
+	if !now.Before(allowedAt) {
+		return ""
+	}
+
+	for _, restricted := range cfg.AgeRestrictedSymbols {
+		if restricted == symbol {
+			return fmt.Sprintf("Account too new to trade %s until %s",
+				symbol, allowedAt.Format(time.RFC3339))
+		}
 	}
+
+	if cfg.AgeRestrictedOrderValue > 0 && orderValue > cfg.AgeRestrictedOrderValue {
+		return fmt.Sprintf("Account too new for orders over $%.2f until %s",
+			cfg.AgeRestrictedOrderValue, allowedAt.Format(time.RFC3339))
+	}
+
+	return ""
+}
+
+// priceInRange reports whether price is within tolerancePercent of the
+// market price. A zero tolerance accepts any price.
+func priceInRange(price, marketPrice, tolerancePercent float64) bool {
+	if tolerancePercent <= 0 || marketPrice <= 0 {
+		return true
+	}
+	// Small epsilon so a price exactly on the boundary isn't lost to float noise
+	return math.Abs(price-marketPrice) <= marketPrice*tolerancePercent/100+1e-9
+}
+
+// valueCommittedPositions values userID's positions as tx sees them against
+// one snapshot of Prices, returning their total and symbol's share of it
+func valueCommittedPositions(tx *sql.Tx, userID int, symbol string) (total, position float64, err error) {
+	rows, err := tx.Query(`
+        SELECT stock_symbol, quantity, avg_purchase_price
+        FROM portfolios
+        WHERE user_id = $1 AND quantity <> 0
+    `, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var positions []models.Portfolio
+	for rows.Next() {
+		p := models.Portfolio{UserID: userID}
+		if err := rows.Scan(&p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice); err != nil {
+			return 0, 0, err
+		}
+		positions = append(positions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for key, h := range valueHoldings(positions, Prices) {
+		total += h.CurrentValue
+		if key.Symbol == symbol {
+			position = h.CurrentValue
+		}
+	}
+	return total, position, nil
 }
 
-// SubmitTrade submits a trade to the processing queue
+// concentrationWarning returns a warning when one position exceeds
+// thresholdPercent of the account's total value, or "" otherwise
+func concentrationWarning(symbol string, positionValue, totalValue, thresholdPercent float64) string {
+	if totalValue <= 0 {
+		return ""
+	}
+
+	percent := positionValue / totalValue * 100
+	if percent <= thresholdPercent {
+		return ""
+	}
+
+	return fmt.Sprintf("%s now %.0f%% of your portfolio", symbol, percent)
+}
+
+// selectPosition reads userID's holding of symbol and its version, or
+// returns sql.ErrNoRows if there is none. The row is locked FOR UPDATE
+// unless cfg.OptimisticLocking; then the writes that follow assert the
+// version instead.
+func selectPosition(tx *sql.Tx, cfg config.Config, userID int, symbol string) (int, float64, int, error) {
+	query := "SELECT quantity, avg_purchase_price, version FROM portfolios WHERE user_id = $1 AND stock_symbol = $2"
+	if !cfg.OptimisticLocking {
+		query += " FOR UPDATE"
+	}
+
+	var quantity, version int
+	var avgPrice float64
+	err := tx.QueryRow(query, userID, symbol).Scan(&quantity, &avgPrice, &version)
+	positionReadHook(userID, symbol)
+	return quantity, avgPrice, version, err
+}
+
+// positionReadHook runs after a trade reads a holding; swapped out in tests
+var positionReadHook = func(userID int, symbol string) {}
+
+// anyVersion tells upsertPortfolio not to check the holding's version
+const anyVersion = -1
+
+// upsertPortfolio adds shares to a holding, creating the row on first buy.
+// version is the holding's version when read, 0 if there was none; if it
+// has moved on the result is errVersionConflict.
+// Two first-buys of the same symbol can both miss the conflict check and the
+// loser gets a unique violation; that attempt is rolled back to a savepoint
+// and retried so it lands on the ON CONFLICT update path instead.
+func upsertPortfolio(tx *sql.Tx, userID int, symbol string, quantity int, price float64, version int) error {
+	for attempt := 1; ; attempt++ {
+		if _, err := tx.Exec("SAVEPOINT portfolio_upsert"); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, stock_symbol) 
+        DO UPDATE SET 
+            quantity = portfolios.quantity + $3,
+            avg_purchase_price = (
+                (portfolios.avg_purchase_price * portfolios.quantity) + ($4 * $3)
+            ) / (portfolios.quantity + $3),
+            version = portfolios.version + 1,
+            updated_at = NOW()
+        WHERE $5 < 0 OR portfolios.version = $5
+    `, userID, symbol, quantity, price, version)
+
+		if err == nil {
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errVersionConflict
+			}
+			_, err = tx.Exec("RELEASE SAVEPOINT portfolio_upsert")
+			return err
+		}
+
+		if !isUniqueViolation(err) || attempt >= maxUpsertAttempts {
+			return err
+		}
+
+		slog.Debug("Retrying portfolio upsert", "user_id", userID, "symbol", symbol, "attempt", attempt)
+		if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT portfolio_upsert"); rbErr != nil {
+			return rbErr
+		}
+	}
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation (23505)
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// errUnexpectedRows is returned when a statement meant to change exactly
+// one row changed none or several, so the transaction can't be trusted
+var errUnexpectedRows = errors.New("statement did not affect exactly one row")
+
+// errVersionConflict is returned when a holding changed between a trade
+// reading it and writing it back. withRetry starts the trade over.
+var errVersionConflict = errors.New("holding changed concurrently")
+
+// execVersioned runs a statement on one holding that asserts the version
+// it was read at, returning errVersionConflict if it matched no row
+func execVersioned(tx *sql.Tx, query string, args ...interface{}) error {
+	err := execOne(tx, query, args...)
+	if errors.Is(err, errUnexpectedRows) {
+		return errVersionConflict
+	}
+	return err
+}
+
+// execOne runs a statement that must change exactly one row. Guards
+// against, say, crediting cash for shares that weren't actually removed.
+func execOne(tx *sql.Tx, query string, args ...interface{}) error {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("%w: %d rows", errUnexpectedRows, n)
+	}
+	return nil
+}
+
+// SubmitSellTrade submits a sell to the processing queue
+func (tp *TradeProcessor) SubmitSellTrade(req models.BuyRequest) TradeResult {
+	req.TradeType = models.TradeTypeSell
+	return tp.SubmitTrade(req)
+}
+
+// SubmitTrade submits a trade to the processing queue. Buys and sells share
+// one queue so a user's trades serialize under the same lock either way; an
+// empty TradeType is a buy. After Stop it returns a "processor stopped"
+// result instead of blocking.
 func (tp *TradeProcessor) SubmitTrade(req models.BuyRequest) TradeResult {
-	resultCh := make(chan TradeResult)
+	return tp.SubmitTradeWithContext(context.Background(), req)
+}
+
+// SubmitTradeWithContext is SubmitTrade bounded by ctx. If ctx ends before
+// the trade is queued, or while it waits in the queue, the trade is not
+// executed and the result is ErrTradeTimedOut. If ctx ends while a worker
+// is already executing it, the trade still completes. A full queue is
+// rejected immediately with ErrSystemBusy rather than waited on.
+//
+// The trade is tagged with ctx's request ID (see WithRequestID), or a new
+// one, which is logged, stored on the trade and returned in the result.
+// The symbol is upper-cased, and unknown symbols are rejected with
+// ErrUnknownSymbol before the trade is queued, as is every trade while
+// DBMonitor reports the database down, with ErrDatabaseUnavailable, or the
+// market is closed, with ErrMarketClosed. So are buys priced outside
+// Config.PriceTolerancePercent of the latest quote, with ErrPriceOutOfRange;
+// symbols that haven't been quoted yet, and MARKET orders, skip that check.
+//
+// The call is traced as a SubmitTrade span under ctx's, with children for
+// the time spent in the queue and for the database transaction.
+func (tp *TradeProcessor) SubmitTradeWithContext(ctx context.Context, req models.BuyRequest) (result TradeResult) {
+	ctx, span := tp.Tracer.Start(ctx, "SubmitTrade")
+	defer func() {
+		endTradeSpan(span, result)
+		span.End()
+	}()
+
+	symbol, ok := market.NormalizeSymbol(req.StockSymbol)
+	req.StockSymbol = symbol
+
+	if req.RequestID == "" {
+		req.RequestID = RequestIDFromContext(ctx)
+	}
+	if req.RequestID == "" {
+		req.RequestID = newRequestID()
+	}
+	span.SetAttributes(tradeAttributes(req)...)
+	span.SetAttributes(attribute.String("request_id", req.RequestID))
+	if !ok {
+		return TradeResult{Success: false, Kind: KindBadRequest, Error: ErrUnknownSymbol, RequestID: req.RequestID}
+	}
+	if !tp.DBMonitor.Up() {
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: ErrDatabaseUnavailable, RequestID: req.RequestID}
+	}
+	if !tp.Config.MarketHours.IsOpen(tp.clock.Now()) {
+		return TradeResult{Success: false, Kind: KindMarketClosed, Error: ErrMarketClosed, RequestID: req.RequestID}
+	}
+	if req.TradeType != models.TradeTypeSell && req.OrderType != models.OrderTypeMarket {
+		quote, quoted := Prices.Get(req.StockSymbol)
+		if quoted && !priceInRange(req.Price, quote.Price, tp.Config.PriceTolerancePercent) {
+			return TradeResult{Success: false, Kind: KindBadRequest, Error: ErrPriceOutOfRange, RequestID: req.RequestID}
+		}
+	}
+
+	result = tp.submit(ctx, req)
+	result.RequestID = req.RequestID
+	return result
+}
+
+// submit queues req and waits for its result
+func (tp *TradeProcessor) submit(ctx context.Context, req models.BuyRequest) TradeResult {
+	if req.TradeType == "" {
+		req.TradeType = models.TradeTypeBuy
+	}
+
+	if tp.stopped.Load() {
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: errProcessorStopped}
+	}
+
+	// Buffered so a worker never blocks on a caller that gave up
+	resultCh := make(chan TradeResult, 1)
+
+	// The worker ends the span once it picks the trade up, so a long one
+	// shows the queue backing up
+	_, queueSpan := tp.Tracer.Start(ctx, "trade.queue_wait")
 
 	// Send trade to queue
-	tp.tradeQueue <- TradeRequest{
-		Request:  req,
-		ResultCh: resultCh,
+	select {
+	case tp.tradeQueue <- TradeRequest{
+		Request:   req,
+		Seq:       nextTradeSeq(),
+		Ctx:       ctx,
+		ResultCh:  resultCh,
+		queueSpan: queueSpan,
+	}:
+	case <-tp.stopCh:
+		queueSpan.End()
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: errProcessorStopped}
+	case <-ctx.Done():
+		queueSpan.End()
+		return TradeResult{Success: false, Kind: KindTimeout, Error: ErrTradeTimedOut}
+	default:
+		// Queue is saturated; push back instead of stalling the caller
+		queueSpan.End()
+		return TradeResult{Success: false, Kind: KindUnavailable, Error: ErrSystemBusy}
 	}
 
 	// Wait for result
-	result := <-resultCh
-	return result
+	select {
+	case result := <-resultCh:
+		return result
+	case <-ctx.Done():
+		return TradeResult{Success: false, Kind: KindTimeout, Error: ErrTradeTimedOut}
+	case <-tp.doneCh:
+		// Workers have exited: the trade either finished just before they
+		// did or was never picked up
+		select {
+		case result := <-resultCh:
+			return result
+		default:
+			return TradeResult{Success: false, Kind: KindUnavailable, Error: errProcessorStopped}
+		}
+	}
 }
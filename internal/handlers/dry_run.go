@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// DryRun reads the dryRun query parameter. A dry run validates and prices
+// a trade in a transaction that is always rolled back, and returns the
+// cash and position it would leave. If the value isn't a boolean it
+// responds 400 and returns ok false.
+func DryRun(c *gin.Context) (dryRun bool, ok bool) {
+	value := c.Query("dryRun")
+	if value == "" {
+		return false, true
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "dryRun must be true or false")
+		return false, false
+	}
+	return dryRun, true
+}
+
+// commitTrade commits a successful trade's transaction, saving its
+// idempotency key, if it has one, in the same transaction. For a dry run it
+// commits nothing: it reads back what the trade would leave and returns
+// that, and the caller's deferred Rollback undoes the lot.
+func (tp *TradeProcessor) commitTrade(tx *sql.Tx, req models.BuyRequest, result TradeResult) (TradeResult, error) {
+	if req.DryRun {
+		return projectTrade(tx, req, result)
+	}
+	if req.IdempotencyKey != "" {
+		if err := tp.saveIdempotencyKey(tx, req, result); err != nil {
+			if isUniqueViolation(err) {
+				return TradeResult{Success: false, Kind: KindUnavailable, Error: errIdempotencyKeyTaken}, nil
+			}
+			return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to save idempotency key"}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Transaction commit failed"}, err
+	}
+	return result, nil
+}
+
+// projectTrade fills in a dry run's result with the user's cash and
+// position as the uncommitted trade left them in tx
+func projectTrade(tx *sql.Tx, req models.BuyRequest, result TradeResult) (TradeResult, error) {
+	err := tx.QueryRow("SELECT cash_balance FROM users WHERE id = $1", req.UserID).Scan(&result.CashBalance)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+	}
+
+	result.PositionQuantity, result.PositionAvgPrice = 0, 0
+	err = tx.QueryRow(
+		"SELECT quantity, avg_purchase_price FROM portfolios WHERE user_id = $1 AND stock_symbol = $2",
+		req.UserID, req.StockSymbol,
+	).Scan(&result.PositionQuantity, &result.PositionAvgPrice)
+	if err != nil && err != sql.ErrNoRows {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+	}
+
+	result.TradeID = 0
+	result.DryRun = true
+	return result, nil
+}
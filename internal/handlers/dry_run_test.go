@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestDryRunBuy_LeavesDatabaseUnchanged(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "dryrunner", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	result := tp.SubmitTrade(models.BuyRequest{
+		UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 150.0,
+		IdempotencyKey: "dry-1", DryRun: true,
+	})
+	if !result.Success {
+		t.Fatalf("Expected dry run to succeed, got error: %s", result.Error)
+	}
+	if !result.DryRun || result.TradeID != 0 {
+		t.Errorf("Expected a dry run with no trade ID, got %+v", result)
+	}
+	if result.PositionQuantity != 10 || result.PositionAvgPrice != 150.0 {
+		t.Errorf("Expected projected position 10 @ 150.00, got %d @ %.2f", result.PositionQuantity, result.PositionAvgPrice)
+	}
+	if want := 10000.0 - result.TotalAmount - result.Fee; result.CashBalance != want {
+		t.Errorf("Expected projected cash %.2f, got %.2f", want, result.CashBalance)
+	}
+
+	var cash float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&cash)
+	if cash != 10000.0 {
+		t.Errorf("Expected cash unchanged at 10000.00, got %.2f", cash)
+	}
+	for _, table := range []string{"portfolios", "portfolio_lots", "trades", "trade_attempts", "idempotency_keys"} {
+		var n int
+		if err := database.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE user_id = $1", userID).Scan(&n); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		if n != 0 {
+			t.Errorf("Expected no %s rows after a dry run, got %d", table, n)
+		}
+	}
+
+	// Validation still applies, and the key a dry run used is still free
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1000, Price: 150.0, DryRun: true})
+	if result.Success || result.Error != ErrInsufficientFunds {
+		t.Errorf("Expected dry run rejected with %q, got %+v", ErrInsufficientFunds, result)
+	}
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 150.0, IdempotencyKey: "dry-1"})
+	if !result.Success || result.Replayed || result.TradeID == 0 {
+		t.Errorf("Expected a real trade under the same key, got %+v", result)
+	}
+}
+
+func TestDryRun_SkipsTradeMetrics(t *testing.T) {
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	// With no quote a market order is rejected before touching the database
+	req := models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, OrderType: models.OrderTypeMarket, DryRun: true}
+	if result := tp.SubmitTrade(req); result.Error != ErrNoMarketPrice {
+		t.Fatalf("Expected %q, got %+v", ErrNoMarketPrice, result)
+	}
+	if stats := tp.Stats(); stats.TradesProcessed != 0 || stats.TradesFailed != 0 {
+		t.Errorf("Expected a dry run to leave the counters alone, got %d processed, %d failed", stats.TradesProcessed, stats.TradesFailed)
+	}
+
+	req.DryRun = false
+	tp.SubmitTrade(req)
+	if stats := tp.Stats(); stats.TradesProcessed != 1 || stats.TradesFailed != 1 {
+		t.Errorf("Expected the real trade counted, got %d processed, %d failed", stats.TradesProcessed, stats.TradesFailed)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetExposure handles GET /api/portfolio/:userId/exposure?by=sector|symbol
+func GetExposure(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	by := c.DefaultQuery("by", "sector")
+	if by != "sector" && by != "symbol" {
+		RespondError(c, http.StatusBadRequest, "by must be 'sector' or 'symbol'")
+		return
+	}
+
+	rows, err := db.DB.Query(`
+        SELECT stock_symbol, quantity, avg_purchase_price
+        FROM portfolios
+        WHERE user_id = $1 AND quantity > 0
+    `, userID)
+
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	defer rows.Close()
+
+	holdings := make([]models.Portfolio, 0)
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice); err != nil {
+			continue
+		}
+		holdings = append(holdings, p)
+	}
+
+	Respond(c, http.StatusOK, groupExposure(holdings, by, Prices))
+}
+
+// groupExposure sums holdings' market value per symbol or per sector,
+// largest group first. Holdings are valued at one snapshot of the latest
+// prices, or at cost where a symbol has no quote yet.
+func groupExposure(holdings []models.Portfolio, by string, prices *market.PriceStore) models.ExposureResponse {
+	totals := make(map[string]float64)
+	totalValue := 0.0
+
+	valued := valueHoldings(holdings, prices)
+	for _, h := range holdings {
+		group := h.StockSymbol
+		if by == "sector" {
+			group = market.Sector(h.StockSymbol)
+		}
+
+		value := valued[holdingKey{UserID: h.UserID, Symbol: h.StockSymbol}].CurrentValue
+		totals[group] += value
+		totalValue += value
+	}
+
+	groups := make([]models.ExposureGroup, 0, len(totals))
+	for group, value := range totals {
+		g := models.ExposureGroup{Group: group, MarketValue: value}
+		if totalValue > 0 {
+			g.Percent = value / totalValue * 100
+		}
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].MarketValue != groups[j].MarketValue {
+			return groups[i].MarketValue > groups[j].MarketValue
+		}
+		return groups[i].Group < groups[j].Group
+	})
+
+	return models.ExposureResponse{
+		By:         by,
+		Groups:     groups,
+		TotalValue: totalValue,
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestGroupExposure_BySector(t *testing.T) {
+	holdings := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 150.0}, // $1500 Technology
+		{StockSymbol: "MSFT", Quantity: 5, AvgPurchasePrice: 300.0},  // $1500 Technology
+		{StockSymbol: "TSLA", Quantity: 4, AvgPurchasePrice: 250.0},  // $1000 Consumer Discretionary
+		{StockSymbol: "XYZ", Quantity: 10, AvgPurchasePrice: 100.0},  // $1000 no metadata
+	}
+
+	exposure := groupExposure(holdings, "sector", market.NewPriceStore())
+
+	if exposure.TotalValue != 5000.0 {
+		t.Errorf("Expected total value 5000, got %.2f", exposure.TotalValue)
+	}
+
+	expected := []models.ExposureGroup{
+		{Group: "Technology", MarketValue: 3000.0, Percent: 60.0},
+		{Group: "Consumer Discretionary", MarketValue: 1000.0, Percent: 20.0},
+		{Group: market.UnknownSector, MarketValue: 1000.0, Percent: 20.0},
+	}
+
+	if len(exposure.Groups) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d: %+v", len(expected), len(exposure.Groups), exposure.Groups)
+	}
+
+	for i, want := range expected {
+		got := exposure.Groups[i]
+		if got.Group != want.Group || !approxEqual(got.MarketValue, want.MarketValue) || !approxEqual(got.Percent, want.Percent) {
+			t.Errorf("Group %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestGroupExposure_BySymbol(t *testing.T) {
+	holdings := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 150.0},
+		{StockSymbol: "MSFT", Quantity: 5, AvgPurchasePrice: 100.0},
+	}
+
+	exposure := groupExposure(holdings, "symbol", market.NewPriceStore())
+
+	if len(exposure.Groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(exposure.Groups))
+	}
+	if exposure.Groups[0].Group != "AAPL" || !approxEqual(exposure.Groups[0].Percent, 75.0) {
+		t.Errorf("Expected AAPL at 75%%, got %+v", exposure.Groups[0])
+	}
+	if exposure.Groups[1].Group != "MSFT" || !approxEqual(exposure.Groups[1].Percent, 25.0) {
+		t.Errorf("Expected MSFT at 25%%, got %+v", exposure.Groups[1])
+	}
+}
+
+func TestGroupExposure_Empty(t *testing.T) {
+	exposure := groupExposure(nil, "sector", market.NewPriceStore())
+
+	if exposure.TotalValue != 0 || len(exposure.Groups) != 0 {
+		t.Errorf("Expected empty exposure, got %+v", exposure)
+	}
+}
+
+func TestGroupExposure_LivePrices(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 300.0, Timestamp: time.Now()})
+
+	holdings := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 150.0}, // $3000 at the quote, $1500 at cost
+		{StockSymbol: "TSLA", Quantity: 4, AvgPurchasePrice: 250.0},  // No quote: $1000 at cost
+	}
+
+	exposure := groupExposure(holdings, "sector", prices)
+
+	if !approxEqual(exposure.TotalValue, 4000.0) {
+		t.Errorf("Expected total value 4000 at the latest prices, got %.2f", exposure.TotalValue)
+	}
+	if len(exposure.Groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v", exposure.Groups)
+	}
+	if got := exposure.Groups[0]; got.Group != "Technology" || !approxEqual(got.MarketValue, 3000.0) || !approxEqual(got.Percent, 75.0) {
+		t.Errorf("Expected Technology to follow AAPL's price to 3000 (75%%), got %+v", got)
+	}
+	if got := exposure.Groups[1]; !approxEqual(got.MarketValue, 1000.0) || !approxEqual(got.Percent, 25.0) {
+		t.Errorf("Expected the unquoted group at cost, 1000 (25%%), got %+v", got)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/gin-gonic/gin"
+)
+
+// Halts lists the symbols trading is halted in. Trades in them are
+// rejected with ErrTradingHalted and their resting orders don't fill.
+var Halts = market.NewHalts()
+
+// GetHalts handles GET /api/admin/halts, listing the halted symbols
+func GetHalts(c *gin.Context) {
+	Respond(c, http.StatusOK, gin.H{"halted": Halts.Halted()})
+}
+
+// HaltSymbol handles POST /api/admin/halts/:symbol, halting trading in the
+// symbol until it is resumed
+func HaltSymbol(c *gin.Context) {
+	symbol, ok := haltSymbol(c)
+	if !ok {
+		return
+	}
+	Halts.Halt(symbol)
+	Respond(c, http.StatusOK, gin.H{"symbol": symbol, "halted": true})
+}
+
+// ResumeSymbol handles DELETE /api/admin/halts/:symbol, letting the symbol
+// trade again
+func ResumeSymbol(c *gin.Context) {
+	symbol, ok := haltSymbol(c)
+	if !ok {
+		return
+	}
+	Halts.Resume(symbol)
+	Respond(c, http.StatusOK, gin.H{"symbol": symbol, "halted": false})
+}
+
+// haltSymbol reads the :symbol param, responding 400 for one the market
+// doesn't list
+func haltSymbol(c *gin.Context) (string, bool) {
+	symbol, ok := market.NormalizeSymbol(c.Param("symbol"))
+	if !ok {
+		RespondError(c, http.StatusBadRequest, ErrUnknownSymbol)
+		return "", false
+	}
+	return symbol, true
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHaltEndpoints(t *testing.T) {
+	saved := Halts
+	Halts = market.NewHalts()
+	defer func() { Halts = saved }()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/admin/halts", GetHalts)
+	router.POST("/api/admin/halts/:symbol", HaltSymbol)
+	router.DELETE("/api/admin/halts/:symbol", ResumeSymbol)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := do(http.MethodPost, "/api/admin/halts/aapl"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 halting AAPL, got %d: %s", w.Code, w.Body.String())
+	}
+	if !Halts.IsHalted("AAPL") {
+		t.Error("Expected AAPL halted")
+	}
+
+	w := do(http.MethodGet, "/api/admin/halts")
+	var body struct {
+		Data struct {
+			Halted []string `json:"halted"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || !reflect.DeepEqual(body.Data.Halted, []string{"AAPL"}) {
+		t.Errorf("Expected 200 listing [AAPL], got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPost, "/api/admin/halts/NOPE"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown symbol, got %d", w.Code)
+	}
+
+	if w := do(http.MethodDelete, "/api/admin/halts/AAPL"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 resuming AAPL, got %d: %s", w.Code, w.Body.String())
+	}
+	if Halts.IsHalted("AAPL") {
+		t.Error("Expected AAPL resumed")
+	}
+}
+
+func TestSubmitTrade_Halted(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	saved := Halts
+	Halts = market.NewHalts()
+	defer func() { Halts = saved }()
+
+	userID := db.CreateTestUser(t, database, "halted", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	Halts.Halt("AAPL")
+	buy := models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 150.0}
+	result := tp.SubmitTrade(buy)
+	if result.Success || result.Kind != KindHalted || result.Error != ErrTradingHalted {
+		t.Fatalf("Expected %q, got success=%v error=%q", ErrTradingHalted, result.Success, result.Error)
+	}
+	if result := tp.SubmitSellTrade(buy); result.Kind != KindHalted {
+		t.Errorf("Expected sells halted too, got %q", result.Error)
+	}
+
+	// Other symbols still trade
+	if result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "MSFT", Quantity: 1, Price: 380.0}); !result.Success {
+		t.Errorf("Expected MSFT to trade, got error: %s", result.Error)
+	}
+
+	Halts.Resume("AAPL")
+	if result := tp.SubmitTrade(buy); !result.Success {
+		t.Errorf("Expected the buy to succeed once resumed, got error: %s", result.Error)
+	}
+}
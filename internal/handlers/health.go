@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds the database ping so a hung connection can't
+// hang the probe
+const readinessTimeout = 2 * time.Second
+
+// HealthStatus is the body of the health endpoints. Checks maps each
+// dependency to "ok" or the reason it failed.
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness handles GET /health/live. It only confirms the process is up.
+func Liveness(c *gin.Context) {
+	Respond(c, http.StatusOK, HealthStatus{Status: "alive"})
+}
+
+// Readiness handles GET /health/ready, answering 503 when the database is
+// unreachable, or DBMonitor last found it so, or the trade processor can't
+// accept trades
+func (tp *TradeProcessor) Readiness(c *gin.Context) {
+	tp.readiness(c, db.DB.PingContext)
+}
+
+func (tp *TradeProcessor) readiness(c *gin.Context, ping func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{"database": "ok", "trade_processor": "ok"}
+	ready := true
+
+	if !tp.DBMonitor.Up() {
+		checks["database"] = ErrDatabaseUnavailable
+		ready = false
+	} else if err := ping(ctx); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	}
+
+	switch {
+	case tp.stopped.Load():
+		checks["trade_processor"] = errProcessorStopped
+		ready = false
+	case len(tp.tradeQueue) == cap(tp.tradeQueue):
+		checks["trade_processor"] = "queue full"
+		ready = false
+	}
+
+	if !ready {
+		Respond(c, http.StatusServiceUnavailable, HealthStatus{Status: "unavailable", Checks: checks})
+		return
+	}
+	Respond(c, http.StatusOK, HealthStatus{Status: "ready", Checks: checks})
+}
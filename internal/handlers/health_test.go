@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func serveReadiness(t *testing.T, tp *TradeProcessor, ping func(context.Context) error) (int, HealthStatus) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/health/ready", func(c *gin.Context) { tp.readiness(c, ping) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var body struct {
+		Data HealthStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w.Code, body.Data
+}
+
+func TestReadiness(t *testing.T) {
+	tp := NewTradeProcessor(1)
+
+	code, status := serveReadiness(t, tp, func(context.Context) error { return nil })
+	if code != http.StatusOK || status.Status != "ready" {
+		t.Errorf("Expected 200 ready, got %d %q", code, status.Status)
+	}
+
+	code, status = serveReadiness(t, tp, func(context.Context) error {
+		return errors.New("connection refused")
+	})
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when the database is down, got %d", code)
+	}
+	if status.Checks["database"] != "connection refused" {
+		t.Errorf("Expected database check to report the ping error, got %q", status.Checks["database"])
+	}
+	if status.Checks["trade_processor"] != "ok" {
+		t.Errorf("Expected trade_processor ok, got %q", status.Checks["trade_processor"])
+	}
+}
+
+func TestReadiness_ProcessorStopped(t *testing.T) {
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	tp.Stop()
+
+	code, status := serveReadiness(t, tp, func(context.Context) error { return nil })
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after Stop, got %d", code)
+	}
+	if status.Checks["trade_processor"] != errProcessorStopped {
+		t.Errorf("Expected trade_processor %q, got %q", errProcessorStopped, status.Checks["trade_processor"])
+	}
+}
+
+func TestReadiness_DatabaseMonitor(t *testing.T) {
+	failing := true
+	resets := 0
+	monitor := db.NewMonitor(nil, time.Hour)
+	monitor.Ping = func(context.Context) error {
+		if failing {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	monitor.Reset = func() { resets++ }
+
+	tp := NewTradeProcessor(1)
+	tp.DBMonitor = monitor
+	tp.Start()
+	defer tp.Stop()
+	ping := func(context.Context) error { return nil }
+
+	// The monitor's failed ping marks the database down and resets the pool
+	monitor.Check(context.Background())
+	if resets != 1 {
+		t.Errorf("Expected the pool reset once, got %d", resets)
+	}
+	code, status := serveReadiness(t, tp, ping)
+	if code != http.StatusServiceUnavailable || status.Checks["database"] != ErrDatabaseUnavailable {
+		t.Errorf("Expected 503 with database %q, got %d %q", ErrDatabaseUnavailable, code, status.Checks["database"])
+	}
+
+	// Trades are turned away with a clear error, not a generic 500
+	result := tp.SubmitTrade(models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, Price: 150.0})
+	if result.Success || result.Error != ErrDatabaseUnavailable || result.HTTPStatus() != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 %q, got success=%v %d %q", ErrDatabaseUnavailable, result.Success, result.HTTPStatus(), result.Error)
+	}
+
+	// Ready again once a ping gets through
+	failing = false
+	monitor.Check(context.Background())
+	if code, status := serveReadiness(t, tp, ping); code != http.StatusOK || status.Status != "ready" {
+		t.Errorf("Expected 200 ready after recovery, got %d %q", code, status.Status)
+	}
+}
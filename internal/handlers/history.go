@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/gin-gonic/gin"
+)
+
+// History query bounds
+const (
+	defaultHistoryWindow = time.Hour
+	maxHistoryPoints     = 10000
+)
+
+// historyCleanupInterval is how often rows past retention are deleted
+const historyCleanupInterval = time.Hour
+
+// PriceRecorder writes every tick from a provider to price_history and
+// periodically deletes rows older than its retention
+type PriceRecorder struct {
+	provider  market.MarketDataProvider
+	retention time.Duration
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewPriceRecorder creates a recorder fed by provider that keeps
+// retention worth of history
+func NewPriceRecorder(provider market.MarketDataProvider, retention time.Duration) *PriceRecorder {
+	return &PriceRecorder{
+		provider:  provider,
+		retention: retention,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Start subscribes to every known symbol and starts the cleanup loop
+func (r *PriceRecorder) Start() {
+	for _, symbol := range market.Symbols() {
+		quotes, cancel := r.provider.Subscribe(symbol)
+		r.wg.Add(1)
+		go r.record(quotes, cancel)
+	}
+	r.wg.Add(1)
+	go r.cleanup()
+}
+
+// Stop ends recording and cleanup and waits for in-flight writes. Later
+// calls are no-ops.
+func (r *PriceRecorder) Stop() {
+	r.stopOnce.Do(func() { close(r.stopCh) })
+	r.wg.Wait()
+}
+
+// record writes one subscription's quotes until Stop
+func (r *PriceRecorder) record(quotes <-chan market.Quote, cancel func()) {
+	defer r.wg.Done()
+	defer cancel()
+
+	for {
+		select {
+		case q := <-quotes:
+			_, err := db.DB.Exec(
+				"INSERT INTO price_history (stock_symbol, price, recorded_at) VALUES ($1, $2, $3)",
+				q.Symbol, q.Price, q.Timestamp,
+			)
+			if err != nil {
+				slog.Error("Failed to record price", "symbol", q.Symbol, "error", err)
+			}
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// cleanup deletes history older than retention, once at start and then
+// every historyCleanupInterval
+func (r *PriceRecorder) cleanup() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(historyCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := db.DB.Exec("DELETE FROM price_history WHERE recorded_at < $1", time.Now().Add(-r.retention))
+		if err != nil {
+			slog.Error("Failed to prune price history", "error", err)
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			slog.Debug("Pruned price history", "rows", n)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// GetPriceHistory handles GET /api/stocks/:symbol/history?window=1h&interval=1m.
+// Without interval it returns the raw ticks in window; with it, OHLC
+// candles of that width.
+func GetPriceHistory(c *gin.Context) {
+	symbol, ok := market.NormalizeSymbol(c.Param("symbol"))
+	if !ok {
+		RespondError(c, http.StatusNotFound, "unknown symbol")
+		return
+	}
+
+	window := defaultHistoryWindow
+	if v := c.Query("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			RespondError(c, http.StatusBadRequest, "window must be a positive duration like 1h")
+			return
+		}
+		window = d
+	}
+
+	var interval time.Duration
+	if v := c.Query("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second {
+			RespondError(c, http.StatusBadRequest, "interval must be a duration of at least 1s")
+			return
+		}
+		interval = d
+	}
+
+	rows, err := db.DB.Query(`
+        SELECT price, recorded_at
+        FROM price_history
+        WHERE stock_symbol = $1 AND recorded_at >= $2
+        ORDER BY recorded_at DESC
+        LIMIT $3
+    `, symbol, time.Now().Add(-window), maxHistoryPoints)
+
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch price history")
+		return
+	}
+	defer rows.Close()
+
+	points := make([]market.Quote, 0)
+	for rows.Next() {
+		q := market.Quote{Symbol: symbol}
+		if err := rows.Scan(&q.Price, &q.Timestamp); err != nil {
+			continue
+		}
+		points = append(points, q)
+	}
+
+	// Newest were fetched first so the cap keeps the recent end; put them
+	// back in time order
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+
+	if interval > 0 {
+		candles := market.Aggregate(points, interval)
+		Respond(c, http.StatusOK, gin.H{
+			"symbol":   symbol,
+			"interval": interval.String(),
+			"candles":  candles,
+			"count":    len(candles),
+		})
+		return
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"symbol": symbol,
+		"points": points,
+		"count":  len(points),
+	})
+}
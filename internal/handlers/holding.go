@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetHolding handles GET /api/portfolio/:userId/holdings/:symbol, returning
+// one position valued at the latest price, for a stock detail page without
+// fetching the whole portfolio. A symbol the user doesn't hold gets 404.
+func GetHolding(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	symbol, ok := market.NormalizeSymbol(c.Param("symbol"))
+	if !ok {
+		RespondError(c, http.StatusBadRequest, ErrUnknownSymbol)
+		return
+	}
+
+	var p models.Portfolio
+	err := db.DB.QueryRow(`
+        SELECT id, user_id, stock_symbol, quantity, avg_purchase_price, margin_used, updated_at
+        FROM portfolios
+        WHERE user_id = $1 AND stock_symbol = $2 AND quantity <> 0
+    `, userID, symbol).Scan(&p.ID, &p.UserID, &p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice, &p.MarginUsed, &p.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		RespondError(c, http.StatusNotFound, ErrNoPosition)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	Respond(c, http.StatusOK, valueHolding(p, Prices))
+}
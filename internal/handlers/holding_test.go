@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetHolding(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 160.0, Timestamp: time.Now()})
+
+	userID := db.CreateTestUser(t, database, "holder", 10000.0)
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 10, 150.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/portfolio/:userId/holdings/:symbol", GetHolding)
+	get := func(symbol string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+			fmt.Sprintf("/api/portfolio/%d/holdings/%s", userID, symbol), nil))
+		return w
+	}
+
+	// Symbols are case-insensitive
+	w := get("aapl")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data models.Holding `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	h := body.Data
+	if h.StockSymbol != "AAPL" || h.Quantity != 10 || h.AvgPurchasePrice != 150.0 ||
+		h.CurrentPrice != 160.0 || h.CurrentValue != 1600.0 || h.UnrealizedPL != 100.0 {
+		t.Errorf("Expected 10 AAPL bought at 150 worth 1600, got %+v", h)
+	}
+
+	if w := get("MSFT"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a stock not held, got %d", w.Code)
+	}
+	if w := get("NOPE"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown symbol, got %d", w.Code)
+	}
+}
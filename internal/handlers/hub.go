@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/metrics"
+)
+
+// clientBuffer is how many updates a client may fall behind before the
+// hub drops it
+const clientBuffer = 32
+
+// hubClient is one connected WebSocket client
+type hubClient struct {
+	send chan PriceUpdate
+}
+
+// Hub is the single source of price truth: it consumes the provider once,
+// records each quote, matches resting orders against it and fans it out to
+// every connected client
+type Hub struct {
+	provider market.MarketDataProvider
+
+	// MaxConnections caps concurrent WebSocket connections across every
+	// endpoint served by the hub. Zero means no limit.
+	MaxConnections int
+	conns          atomic.Int64
+
+	// Keepalive: writes give up after writeWait, and clients are pinged
+	// every pingPeriod and dropped after pongWait without a pong
+	writeWait  time.Duration
+	pingPeriod time.Duration
+	pongWait   time.Duration
+
+	clock clock.Clock // Stamps snapshots sent outside a tick
+
+	clients    map[*hubClient]bool
+	register   chan *hubClient
+	unregister chan *hubClient
+	broadcast  chan market.Quote
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewHub creates a hub fed by provider
+func NewHub(provider market.MarketDataProvider) *Hub {
+	return &Hub{
+		provider:   provider,
+		clients:    make(map[*hubClient]bool),
+		register:   make(chan *hubClient),
+		unregister: make(chan *hubClient),
+		broadcast:  make(chan market.Quote),
+		stopCh:     make(chan struct{}),
+		writeWait:  wsWriteWait,
+		pingPeriod: wsPingPeriod,
+		pongWait:   wsPongWait,
+		clock:      clock.Real(),
+	}
+}
+
+// Start subscribes to every known symbol and starts the broadcast loop
+func (h *Hub) Start() {
+	for _, symbol := range market.Symbols() {
+		quotes, cancel := h.provider.Subscribe(symbol)
+		go h.forward(quotes, cancel)
+	}
+	go h.run()
+}
+
+// Stop ends the broadcast loop and disconnects every client. Later calls
+// are no-ops.
+func (h *Hub) Stop() {
+	h.stopOnce.Do(func() { close(h.stopCh) })
+}
+
+// forward feeds one provider subscription into the broadcast loop
+func (h *Hub) forward(quotes <-chan market.Quote, cancel func()) {
+	defer cancel()
+
+	for {
+		select {
+		case q := <-quotes:
+			select {
+			case h.broadcast <- q:
+			case <-h.stopCh:
+				return
+			}
+		case <-h.stopCh:
+			return
+		}
+	}
+}
+
+// run owns the client registry; registration and broadcast never overlap
+func (h *Hub) run() {
+	for {
+		select {
+		case client := <-h.register:
+			h.clients[client] = true
+
+		case client := <-h.unregister:
+			if h.clients[client] {
+				delete(h.clients, client)
+				close(client.send)
+			}
+
+		case q := <-h.broadcast:
+			// Publish for REST valuation
+			Prices.Set(q)
+
+			// Fill any orders this price crosses. In a halted symbol they
+			// rest until it resumes.
+			if !Halts.IsHalted(q.Symbol) {
+				if matched := Orders.MatchAgainstPrice(q.Symbol, q.Price); len(matched) > 0 {
+					go fillOrders(matched, q.Price)
+				}
+			}
+
+			for client := range h.clients {
+				select {
+				case client.send <- PriceUpdate(q):
+				default:
+					// Too slow to keep up; drop it rather than stall everyone
+					slog.Warn("Dropping slow WebSocket client")
+					delete(h.clients, client)
+					close(client.send)
+				}
+			}
+
+		case <-h.stopCh:
+			for client := range h.clients {
+				delete(h.clients, client)
+				close(client.send)
+			}
+			return
+		}
+	}
+}
+
+// acquireConn reserves a connection slot, reporting false when the hub is
+// at MaxConnections. Pair each successful call with releaseConn.
+func (h *Hub) acquireConn() bool {
+	if n := h.conns.Add(1); h.MaxConnections > 0 && n > int64(h.MaxConnections) {
+		h.conns.Add(-1)
+		return false
+	}
+	metrics.WebSocketConnections.Inc()
+	return true
+}
+
+// releaseConn frees a slot taken by acquireConn
+func (h *Hub) releaseConn() {
+	h.conns.Add(-1)
+	metrics.WebSocketConnections.Dec()
+}
+
+// Connections returns the number of open WebSocket connections
+func (h *Hub) Connections() int {
+	return int(h.conns.Load())
+}
+
+// addClient registers a new client, or returns nil once the hub has stopped
+func (h *Hub) addClient() *hubClient {
+	client := &hubClient{send: make(chan PriceUpdate, clientBuffer)}
+
+	select {
+	case h.register <- client:
+		return client
+	case <-h.stopCh:
+		return nil
+	}
+}
+
+// removeClient unregisters a client. Safe to call more than once.
+func (h *Hub) removeClient(client *hubClient) {
+	select {
+	case h.unregister <- client:
+	case <-h.stopCh:
+	}
+}
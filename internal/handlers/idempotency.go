@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader lets a client retry a trade without executing it twice
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ReplayedHeader is set to "true" on responses replayed from an earlier trade
+const ReplayedHeader = "Idempotent-Replayed"
+
+// IdempotencyKey returns the request's Idempotency-Key header, or "" if it
+// has none. Keys follow the X-Request-ID format; an invalid one gets a 400
+// and ok is false.
+func IdempotencyKey(c *gin.Context) (key string, ok bool) {
+	key = c.GetHeader(IdempotencyKeyHeader)
+	if key != "" && !validRequestID.MatchString(key) {
+		RespondError(c, http.StatusBadRequest, "Idempotency-Key must be 1-64 letters, digits, '.', '_' or '-'")
+		return "", false
+	}
+	return key, true
+}
+
+// errIdempotencyKeyTaken is returned when another trade saved the same
+// idempotency key first; idempotent replays that trade instead
+const errIdempotencyKeyTaken = "idempotency key already used"
+
+// idempotent runs a trade unless the user already made one with the same
+// idempotency key within Config.IdempotencyTTL, in which case that trade's
+// result is returned with Replayed set. Only successful trades are
+// remembered, so a rejected trade can be retried with the same key. Dry
+// runs ignore the key. The key is saved by commitTrade in the trade's own
+// transaction, so a trade is never committed without it.
+func (tp *TradeProcessor) idempotent(req models.BuyRequest, run func() TradeResult) TradeResult {
+	if req.IdempotencyKey == "" || req.DryRun {
+		return run()
+	}
+
+	if result, found, err := tp.lookupIdempotencyKey(req); found || err != nil {
+		return result
+	}
+
+	result := run()
+	if result.Error == errIdempotencyKeyTaken {
+		// A concurrent retry committed first, on another instance
+		if replay, found, _ := tp.lookupIdempotencyKey(req); found {
+			return replay
+		}
+	}
+	return result
+}
+
+// lookupIdempotencyKey returns the replayed result of the user's unexpired
+// trade under req's key, with found set. The error is set only for
+// database failures, and the result then says so, since executing could
+// duplicate the trade the key guards.
+func (tp *TradeProcessor) lookupIdempotencyKey(req models.BuyRequest) (TradeResult, bool, error) {
+	cutoff := tp.clock.Now().Add(-tp.Config.IdempotencyTTL)
+
+	var stored []byte
+	err := db.DB.QueryRow(`
+        SELECT result FROM idempotency_keys
+        WHERE user_id = $1 AND key = $2 AND created_at > $3
+    `, req.UserID, req.IdempotencyKey, cutoff).Scan(&stored)
+	if err == nil {
+		var result TradeResult
+		if err = json.Unmarshal(stored, &result); err == nil {
+			result.Replayed = true
+			return result, true, nil
+		}
+	}
+	if err != sql.ErrNoRows {
+		slog.Error("Failed to look up idempotency key", "user_id", req.UserID, "key", req.IdempotencyKey, "error", err)
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, false, err
+	}
+	return TradeResult{}, false, nil
+}
+
+// saveIdempotencyKey stores a trade's result under its key inside the
+// trade's transaction, first pruning the user's expired keys. A key still
+// held by an unexpired trade fails the insert on the (user_id, key)
+// constraint. Creation and expiry are both timed by tp.clock.
+func (tp *TradeProcessor) saveIdempotencyKey(tx *sql.Tx, req models.BuyRequest, result TradeResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	now := tp.clock.Now()
+	_, err = tx.Exec(
+		"DELETE FROM idempotency_keys WHERE user_id = $1 AND created_at <= $2",
+		req.UserID, now.Add(-tp.Config.IdempotencyTTL),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO idempotency_keys (user_id, key, result, created_at) VALUES ($1, $2, $3, $4)",
+		req.UserID, req.IdempotencyKey, body, now,
+	)
+	return err
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubmitTrade_IdempotencyKey(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "idempotent", 10000.0)
+
+	tp := NewTradeProcessor(2)
+	tp.Start()
+	defer tp.Stop()
+
+	req := models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 100.0, IdempotencyKey: "buy-1"}
+
+	first := tp.SubmitTrade(req)
+	if !first.Success || first.Replayed {
+		t.Fatalf("Expected first buy to execute, got %+v", first)
+	}
+
+	second := tp.SubmitTrade(req)
+	if !second.Success || !second.Replayed || second.TradeID != first.TradeID {
+		t.Errorf("Expected replay of trade %d, got %+v", first.TradeID, second)
+	}
+
+	var trades int
+	var balance float64
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&trades)
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if trades != 1 || balance != 9500.0 {
+		t.Errorf("Expected one trade and balance 9500, got %d trades and %.2f", trades, balance)
+	}
+
+	// A new key trades again
+	req.IdempotencyKey = "buy-2"
+	if result := tp.SubmitTrade(req); !result.Success || result.Replayed {
+		t.Errorf("Expected a new key to execute, got %+v", result)
+	}
+
+	// Expired keys no longer replay
+	tp.Config.IdempotencyTTL = time.Nanosecond
+	req.IdempotencyKey = "buy-1"
+	if result := tp.SubmitTrade(req); !result.Success || result.Replayed {
+		t.Errorf("Expected an expired key to execute, got %+v", result)
+	}
+
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&trades)
+	if trades != 3 {
+		t.Errorf("Expected 3 trades, got %d", trades)
+	}
+}
+
+func TestSubmitTrade_IdempotencyKeySavedWithTrade(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "idempotent_tx", 10000.0)
+
+	saved := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	tp := NewTradeProcessor(1)
+	tp.clock = clock.NewFake(saved)
+	tp.Start()
+	defer tp.Stop()
+
+	req := models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 100.0, IdempotencyKey: "buy-1"}
+	first := tp.SubmitTrade(req)
+	if !first.Success {
+		t.Fatalf("Expected the buy to execute, got %+v", first)
+	}
+
+	// The key is stamped by the same clock that expires it
+	var createdAt time.Time
+	database.QueryRow("SELECT created_at FROM idempotency_keys WHERE user_id = $1 AND key = 'buy-1'", userID).Scan(&createdAt)
+	if !createdAt.Equal(saved) {
+		t.Errorf("Expected the key saved at %v, got %v", saved, createdAt)
+	}
+
+	// Another instance commits a trade under the next key while this one
+	// is mid-trade. Its key insert fails, so its trade is rolled back and
+	// the other instance's is replayed.
+	positionReadHook = func(int, string) {
+		if _, err := database.Exec(
+			`INSERT INTO idempotency_keys (user_id, key, result, created_at) VALUES ($1, 'buy-2', '{"TradeID": 999, "Success": true}', $2)`,
+			userID, saved,
+		); err != nil {
+			t.Errorf("Concurrent key insert failed: %v", err)
+		}
+	}
+	defer func() { positionReadHook = func(int, string) {} }()
+
+	req.IdempotencyKey = "buy-2"
+	second := tp.SubmitTrade(req)
+	if !second.Success || !second.Replayed || second.TradeID != 999 {
+		t.Errorf("Expected the concurrent trade replayed, got %+v", second)
+	}
+
+	var trades int
+	var balance float64
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&trades)
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if trades != 1 || balance != 9500.0 {
+		t.Errorf("Expected only the first trade committed, got %d trades and balance %.2f", trades, balance)
+	}
+}
+
+func TestIdempotencyKey_Header(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"", "", true},
+		{"order-42_retry.1", "order-42_retry.1", true},
+		{"bad key!", "", false},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/trades/buy", nil)
+		if tt.header != "" {
+			c.Request.Header.Set(IdempotencyKeyHeader, tt.header)
+		}
+
+		key, ok := IdempotencyKey(c)
+		if key != tt.want || ok != tt.ok {
+			t.Errorf("%q: got %q, %v; want %q, %v", tt.header, key, ok, tt.want, tt.ok)
+		}
+		if !ok && w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", tt.header, w.Code)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewTradeImpact handles POST /api/trades/impact. Whether the trade is
+// feasible, and the cash and position it would leave, come from a dry run
+// through the trade processor, so the preview applies exactly the rules a
+// real trade would.
+func (tp *TradeProcessor) PreviewTradeImpact(c *gin.Context) {
+	var req models.ImpactRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if userID, ok := AuthenticatedUserID(c); ok {
+		req.UserID = userID
+	}
+
+	symbol, ok := market.NormalizeSymbol(req.StockSymbol)
+	if !ok {
+		RespondError(c, http.StatusBadRequest, ErrUnknownSymbol)
+		return
+	}
+	req.StockSymbol = symbol
+
+	var cashBalance float64
+	err := db.DB.QueryRow(
+		"SELECT cash_balance FROM users WHERE id = $1",
+		req.UserID,
+	).Scan(&cashBalance)
+
+	if err == sql.ErrNoRows {
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	// Shorts are held as negative quantities
+	rows, err := db.DB.Query(`
+        SELECT stock_symbol, quantity, avg_purchase_price
+        FROM portfolios
+        WHERE user_id = $1 AND quantity <> 0
+    `, req.UserID)
+
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	defer rows.Close()
+
+	holdings := make([]models.Portfolio, 0)
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice); err != nil {
+			continue
+		}
+		holdings = append(holdings, p)
+	}
+
+	result := tp.SubmitTradeWithContext(c.Request.Context(), models.BuyRequest{
+		UserID:      req.UserID,
+		StockSymbol: req.StockSymbol,
+		Quantity:    req.Quantity,
+		Price:       req.Price,
+		TradeType:   req.TradeType,
+		DryRun:      true,
+	})
+	// Rejections make the trade infeasible; anything else is an error
+	if status := result.HTTPStatus(); status != http.StatusOK && status != http.StatusBadRequest {
+		RespondError(c, status, result.Error)
+		return
+	}
+
+	Respond(c, http.StatusOK, computeImpact(tp.Config, Prices, cashBalance, holdings, req, result))
+}
+
+// computeImpact compares account metrics before req with after it, given
+// the trade's dry run. A rejected trade is infeasible and leaves the
+// account as it was. Holdings are valued at the latest prices.
+func computeImpact(cfg config.Config, prices *market.PriceStore, cash float64, holdings []models.Portfolio, req models.ImpactRequest, dryRun TradeResult) models.ImpactResponse {
+	response := models.ImpactResponse{
+		Before:   impactMetrics(cfg, cash, holdings, req.StockSymbol, prices),
+		Feasible: dryRun.Success,
+	}
+	if !dryRun.Success {
+		response.Reason = dryRun.Error
+		response.After = response.Before
+		return response
+	}
+	response.Fee = dryRun.Fee
+
+	after := make([]models.Portfolio, 0, len(holdings)+1)
+	for _, h := range holdings {
+		if h.StockSymbol != req.StockSymbol {
+			after = append(after, h)
+		}
+	}
+	if dryRun.PositionQuantity != 0 {
+		after = append(after, models.Portfolio{
+			StockSymbol:      req.StockSymbol,
+			Quantity:         dryRun.PositionQuantity,
+			AvgPurchasePrice: dryRun.PositionAvgPrice,
+		})
+	}
+
+	response.After = impactMetrics(cfg, dryRun.CashBalance, after, req.StockSymbol, prices)
+	return response
+}
+
+// impactMetrics summarizes an account's concentration and diversification,
+// with holdings valued at the latest prices. A short's weight is the size
+// of its value. Buying power is what a buy may spend, as buyOnce reckons
+// it: cash, or with margin, marginAccount.buyingPower.
+func impactMetrics(cfg config.Config, cash float64, holdings []models.Portfolio, symbol string, prices *market.PriceStore) models.ImpactMetrics {
+	metrics := models.ImpactMetrics{
+		BuyingPower: cash,
+		TotalValue:  cash,
+		NumHoldings: len(holdings),
+	}
+
+	account := marginAccount{Cash: cash}
+	valued := valueHoldings(holdings, prices)
+	values := make([]float64, len(holdings))
+	holdingsValue, grossValue := 0.0, 0.0
+	for i, h := range holdings {
+		value := valued[holdingKey{UserID: h.UserID, Symbol: h.StockSymbol}].CurrentValue
+		values[i] = value
+		holdingsValue += value
+		grossValue += math.Abs(value)
+		if value >= 0 {
+			account.LongValue += value
+		} else {
+			account.ShortValue += value
+		}
+		if h.StockSymbol == symbol {
+			metrics.PositionQuantity = h.Quantity
+			metrics.PositionValue = value
+		}
+	}
+	metrics.TotalValue += holdingsValue
+	if cfg.MarginMultiplier > 1 {
+		metrics.BuyingPower = account.buyingPower(cfg.MarginMultiplier)
+	}
+
+	if metrics.TotalValue <= 0 {
+		return metrics
+	}
+
+	metrics.PositionPercent = math.Abs(metrics.PositionValue) / metrics.TotalValue * 100
+
+	hhi := 0.0
+	for _, value := range values {
+		if pct := math.Abs(value) / metrics.TotalValue * 100; pct > metrics.MaxConcentration {
+			metrics.MaxConcentration = pct
+		}
+		if grossValue > 0 {
+			weight := math.Abs(value) / grossValue
+			hhi += weight * weight
+		}
+	}
+
+	if len(holdings) > 0 {
+		metrics.Diversification = 1 - hhi
+	}
+
+	return metrics
+}
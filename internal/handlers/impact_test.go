@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestComputeImpact_BuyNewSymbol(t *testing.T) {
+	holdings := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 100.0}, // $1000
+	}
+
+	req := models.ImpactRequest{
+		UserID:      1,
+		StockSymbol: "MSFT",
+		TradeType:   "BUY",
+		Quantity:    5,
+		Price:       200.0, // $1000
+	}
+	dryRun := TradeResult{Success: true, CashBalance: 8000.0, PositionQuantity: 5, PositionAvgPrice: 200.0}
+
+	impact := computeImpact(config.Config{}, market.NewPriceStore(), 9000.0, holdings, req, dryRun)
+
+	if !impact.Feasible {
+		t.Fatalf("Expected trade to be feasible, got reason: %s", impact.Reason)
+	}
+
+	// Before: $9000 cash + $1000 AAPL, no MSFT
+	if impact.Before.PositionPercent != 0 {
+		t.Errorf("Expected no MSFT exposure before, got %.2f%%", impact.Before.PositionPercent)
+	}
+	if impact.Before.Diversification != 0 {
+		t.Errorf("Expected zero diversification with one holding, got %.4f", impact.Before.Diversification)
+	}
+
+	// After: $8000 cash + $1000 AAPL + $1000 MSFT
+	if impact.After.BuyingPower != 8000.0 {
+		t.Errorf("Expected buying power 8000, got %.2f", impact.After.BuyingPower)
+	}
+	if impact.After.TotalValue != 10000.0 {
+		t.Errorf("Expected total value unchanged at 10000, got %.2f", impact.After.TotalValue)
+	}
+	if !approxEqual(impact.After.PositionPercent, 10.0) {
+		t.Errorf("Expected MSFT at 10%%, got %.2f%%", impact.After.PositionPercent)
+	}
+	if impact.After.NumHoldings != 2 {
+		t.Errorf("Expected 2 holdings after, got %d", impact.After.NumHoldings)
+	}
+	// Two equal-weight holdings: 1 - (0.5² + 0.5²) = 0.5
+	if !approxEqual(impact.After.Diversification, 0.5) {
+		t.Errorf("Expected diversification 0.5, got %.4f", impact.After.Diversification)
+	}
+}
+
+func TestComputeImpact_SellEntirePosition(t *testing.T) {
+	holdings := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 100.0},
+		{StockSymbol: "TSLA", Quantity: 4, AvgPurchasePrice: 250.0},
+	}
+
+	req := models.ImpactRequest{
+		UserID:      1,
+		StockSymbol: "TSLA",
+		TradeType:   "SELL",
+		Quantity:    4,
+		Price:       300.0,
+	}
+	dryRun := TradeResult{Success: true, CashBalance: 1200.0}
+
+	impact := computeImpact(config.Config{}, market.NewPriceStore(), 0, holdings, req, dryRun)
+
+	if !impact.Feasible {
+		t.Fatalf("Expected trade to be feasible, got reason: %s", impact.Reason)
+	}
+	if !approxEqual(impact.Before.PositionPercent, 50.0) {
+		t.Errorf("Expected TSLA at 50%% before, got %.2f%%", impact.Before.PositionPercent)
+	}
+	if impact.After.PositionQuantity != 0 || impact.After.NumHoldings != 1 {
+		t.Errorf("Expected TSLA closed out, got quantity %d across %d holdings",
+			impact.After.PositionQuantity, impact.After.NumHoldings)
+	}
+	if impact.After.BuyingPower != 1200.0 {
+		t.Errorf("Expected buying power 1200, got %.2f", impact.After.BuyingPower)
+	}
+}
+
+func TestComputeImpact_Infeasible(t *testing.T) {
+	holdings := []models.Portfolio{{StockSymbol: "AAPL", Quantity: 1, AvgPurchasePrice: 100.0}}
+	buy := models.ImpactRequest{UserID: 1, StockSymbol: "AAPL", TradeType: "BUY", Quantity: 10, Price: 150.0}
+	dryRun := TradeResult{Success: false, Kind: KindInsufficientFunds, Error: ErrInsufficientFunds}
+
+	impact := computeImpact(config.Config{}, market.NewPriceStore(), 100.0, holdings, buy, dryRun)
+	if impact.Feasible || impact.Reason != ErrInsufficientFunds {
+		t.Errorf("Expected insufficient funds, got feasible=%v reason=%q", impact.Feasible, impact.Reason)
+	}
+	if impact.After != impact.Before {
+		t.Errorf("Expected a rejected trade to leave the account as it was, got %+v after %+v", impact.After, impact.Before)
+	}
+}
+
+func TestComputeImpact_LivePricesAndFees(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 200.0, Timestamp: time.Now()})
+
+	holdings := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 100.0}, // worth $2000 at the quote
+	}
+
+	buy := models.ImpactRequest{UserID: 1, StockSymbol: "MSFT", TradeType: "BUY", Quantity: 5, Price: 100.0}
+	dryRun := TradeResult{Success: true, Fee: 5.0, CashBalance: 495.0, PositionQuantity: 5, PositionAvgPrice: 100.0}
+	impact := computeImpact(config.Config{}, prices, 1000.0, holdings, buy, dryRun)
+
+	if impact.Fee != 5.0 {
+		t.Errorf("Expected fee 5, got %.2f", impact.Fee)
+	}
+	if !approxEqual(impact.Before.TotalValue, 3000.0) {
+		t.Errorf("Expected AAPL valued at the quote for a 3000 total, got %.2f", impact.Before.TotalValue)
+	}
+	if !approxEqual(impact.After.TotalValue, 2995.0) {
+		t.Errorf("Expected total value 2995 after the fee, got %.2f", impact.After.TotalValue)
+	}
+}
+
+func TestComputeImpact_ShortsAndMargin(t *testing.T) {
+	holdings := []models.Portfolio{
+		{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 100.0}, // $1000 long
+		{StockSymbol: "TSLA", Quantity: -4, AvgPurchasePrice: 250.0}, // $1000 owed
+	}
+	req := models.ImpactRequest{UserID: 1, StockSymbol: "TSLA", TradeType: "BUY", Quantity: 1, Price: 250.0}
+
+	metrics := computeImpact(config.Config{}, market.NewPriceStore(), 2000.0, holdings, req, TradeResult{}).Before
+
+	// $2000 cash + $1000 AAPL - $1000 TSLA
+	if !approxEqual(metrics.TotalValue, 2000.0) || metrics.NumHoldings != 2 {
+		t.Errorf("Expected the short counted against 2000 total across 2 holdings, got %+v", metrics)
+	}
+	if metrics.PositionQuantity != -4 || !approxEqual(metrics.PositionValue, -1000.0) {
+		t.Errorf("Expected TSLA short 4 worth -1000, got %d worth %.2f", metrics.PositionQuantity, metrics.PositionValue)
+	}
+	if !approxEqual(metrics.PositionPercent, 50.0) || !approxEqual(metrics.Diversification, 0.5) {
+		t.Errorf("Expected the short weighted by its size, got %+v", metrics)
+	}
+	if metrics.BuyingPower != 2000.0 {
+		t.Errorf("Expected buying power to be cash without margin, got %.2f", metrics.BuyingPower)
+	}
+
+	// Equity 2000 at 2x, less the 1000 of longs it already backs
+	margin := config.Config{MarginMultiplier: 2}
+	if metrics := computeImpact(margin, market.NewPriceStore(), 2000.0, holdings, req, TradeResult{}).Before; !approxEqual(metrics.BuyingPower, 3000.0) {
+		t.Errorf("Expected margin buying power 3000, got %.2f", metrics.BuyingPower)
+	}
+}
+
+func TestPreviewTradeImpact(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "previewer", 1000.0)
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 10, 100.0), ($1, 'TSLA', -2, 250.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/trades/impact", tp.PreviewTradeImpact)
+
+	preview := func(symbol, tradeType string, quantity int, price float64) (int, models.ImpactResponse) {
+		body := fmt.Sprintf(`{"user_id":%d,"stock_symbol":%q,"trade_type":%q,"quantity":%d,"price":%v}`,
+			userID, symbol, tradeType, quantity, price)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/trades/impact", bytes.NewBufferString(body)))
+
+		var resp struct {
+			Data models.ImpactResponse `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data
+	}
+
+	// A lower-case symbol is the same holding
+	code, impact := preview("aapl", "SELL", 4, 100.0)
+	if code != http.StatusOK || !impact.Feasible {
+		t.Fatalf("Expected a feasible sell of aapl, got %d %+v", code, impact)
+	}
+	if impact.Before.PositionQuantity != 10 || impact.After.PositionQuantity != 6 {
+		t.Errorf("Expected AAPL to go from 10 to 6 shares, got %d to %d",
+			impact.Before.PositionQuantity, impact.After.PositionQuantity)
+	}
+
+	if code, _ := preview("ZZZZ", "BUY", 1, 10.0); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown symbol, got %d", code)
+	}
+
+	// Shorts are part of the account before and after
+	code, impact = preview("TSLA", "BUY", 1, 250.0)
+	if code != http.StatusOK || impact.Before.PositionQuantity != -2 || impact.After.PositionQuantity != -1 {
+		t.Errorf("Expected covering one share to take TSLA from -2 to -1, got %d %+v", code, impact)
+	}
+	if impact.Before.NumHoldings != 2 {
+		t.Errorf("Expected the short counted as a holding, got %d", impact.Before.NumHoldings)
+	}
+
+	// Selling what isn't held depends on whether shorting is allowed
+	if _, impact := preview("MSFT", "SELL", 1, 100.0); impact.Feasible || impact.Reason != ErrNoPosition {
+		t.Errorf("Expected %q with shorting off, got %+v", ErrNoPosition, impact)
+	}
+	tp.Config.ShortSelling = true
+	if _, impact := preview("MSFT", "SELL", 1, 100.0); !impact.Feasible || impact.After.PositionQuantity != -1 {
+		t.Errorf("Expected a feasible short with shorting on, got %+v", impact)
+	}
+
+	// Commission counts against cash: $1000 buys $1000 of shares only
+	// without a fee
+	if _, impact := preview("MSFT", "BUY", 10, 100.0); !impact.Feasible {
+		t.Errorf("Expected a $1000 buy to fit $1000 of cash, got %+v", impact)
+	}
+	tp.Config.Commission = config.Commission{Flat: 1.0}
+	if _, impact := preview("MSFT", "BUY", 10, 100.0); impact.Feasible || impact.Reason != ErrInsufficientFunds {
+		t.Errorf("Expected %q once the fee is counted, got %+v", ErrInsufficientFunds, impact)
+	}
+
+	// Margin raises buying power and lets the same buy through
+	tp.Config.MarginMultiplier = 2
+	code, impact = preview("MSFT", "BUY", 10, 100.0)
+	if code != http.StatusOK || !impact.Feasible || impact.Before.BuyingPower <= 1000.0 {
+		t.Errorf("Expected a feasible buy on margin with buying power over cash, got %d %+v", code, impact)
+	}
+
+	tp.Config.PositionLimit = config.PositionLimit{MaxShares: 5}
+	if _, impact := preview("MSFT", "BUY", 10, 100.0); impact.Feasible || impact.Reason != ErrPositionLimit {
+		t.Errorf("Expected %q, got %+v", ErrPositionLimit, impact)
+	}
+
+	// Previews change nothing
+	var trades int
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&trades)
+	if trades != 0 {
+		t.Errorf("Expected no trades recorded by previews, got %d", trades)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Leaderboard sizing for the limit query parameter
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardEntry is one user's standing
+type LeaderboardEntry struct {
+	Rank          int     `json:"rank"`
+	UserID        int     `json:"user_id"`
+	Username      string  `json:"username"`
+	CashBalance   float64 `json:"cash_balance"`
+	HoldingsValue float64 `json:"holdings_value"`
+	TotalValue    float64 `json:"total_value"`
+	ReturnPercent float64 `json:"return_percent"` // Against the configured starting balance plus net deposits
+}
+
+// leaderboardAccount is one user's cash, net deposits and positions
+type leaderboardAccount struct {
+	UserID      int
+	Username    string
+	Cash        float64
+	NetDeposits float64
+	Positions   []models.Portfolio
+}
+
+// Leaderboard ranks users by total account value. Rankings are cached for
+// a few seconds since every refresh reads all accounts.
+type Leaderboard struct {
+	ttl             time.Duration
+	startingBalance float64
+	load            func() ([]leaderboardAccount, error)
+	clock           clock.Clock
+
+	mu        sync.Mutex
+	ranking   []LeaderboardEntry
+	refreshed time.Time
+}
+
+// NewLeaderboard creates a leaderboard that recomputes rankings at most
+// once per ttl, measuring returns against startingBalance
+func NewLeaderboard(ttl time.Duration, startingBalance float64) *Leaderboard {
+	return &Leaderboard{
+		ttl:             ttl,
+		startingBalance: startingBalance,
+		load:            loadLeaderboardAccounts,
+		clock:           clock.Real(),
+	}
+}
+
+// GetLeaderboard handles GET /api/leaderboard?limit=N, returning the top N
+// users (default 10, at most 100) by cash plus holdings at market prices
+func (lb *Leaderboard) GetLeaderboard(c *gin.Context) {
+	limit := defaultLeaderboardLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxLeaderboardLimit {
+			RespondError(c, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	ranking, err := lb.rankings()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to build leaderboard")
+		return
+	}
+
+	page := Pagination{Limit: limit, HasMore: len(ranking) > limit}
+	if page.HasMore {
+		ranking = ranking[:limit]
+	}
+	page.Count = len(ranking)
+	RespondWithMeta(c, http.StatusOK, gin.H{
+		"leaders": ranking,
+		"count":   len(ranking),
+	}, Meta{Pagination: &page})
+}
+
+// rankings returns the cached ranking, recomputing it once stale
+func (lb *Leaderboard) rankings() ([]LeaderboardEntry, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	now := lb.clock.Now()
+	if lb.ranking != nil && now.Sub(lb.refreshed) < lb.ttl {
+		return lb.ranking, nil
+	}
+
+	accounts, err := lb.load()
+	if err != nil {
+		return nil, err
+	}
+	lb.ranking = rankAccounts(accounts, Prices, lb.startingBalance)
+	lb.refreshed = now
+	return lb.ranking, nil
+}
+
+// rankAccounts values each account at the latest prices, every account
+// against the same snapshot, and sorts them by total value, highest first
+func rankAccounts(accounts []leaderboardAccount, prices *market.PriceStore, startingBalance float64) []LeaderboardEntry {
+	var positions []models.Portfolio
+	for _, a := range accounts {
+		for _, p := range a.Positions {
+			p.UserID = a.UserID
+			positions = append(positions, p)
+		}
+	}
+	values := valueHoldings(positions, prices)
+
+	ranking := make([]LeaderboardEntry, 0, len(accounts))
+	for _, a := range accounts {
+		entry := LeaderboardEntry{
+			UserID:      a.UserID,
+			Username:    a.Username,
+			CashBalance: a.Cash,
+		}
+		for _, p := range a.Positions {
+			entry.HoldingsValue += values[holdingKey{UserID: a.UserID, Symbol: p.StockSymbol}].CurrentValue
+		}
+		entry.TotalValue = entry.CashBalance + entry.HoldingsValue
+
+		if basis := startingBalance + a.NetDeposits; basis > 0 {
+			entry.ReturnPercent = math.Round((entry.TotalValue-basis)/basis*10000) / 100
+		}
+		ranking = append(ranking, entry)
+	}
+
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return ranking[i].TotalValue > ranking[j].TotalValue
+	})
+	for i := range ranking {
+		ranking[i].Rank = i + 1
+	}
+	return ranking
+}
+
+// loadLeaderboardAccounts reads every user's cash, net deposits and open
+// positions in one query. Only deposits and withdrawals since the user's
+// latest reset count.
+func loadLeaderboardAccounts() ([]leaderboardAccount, error) {
+	rows, err := db.DB.Query(`
+        SELECT u.id, u.username, u.cash_balance, COALESCE(ct.net_deposits, 0),
+               p.stock_symbol, p.quantity, p.avg_purchase_price
+        FROM users u
+        LEFT JOIN (
+            SELECT user_id,
+                   SUM(CASE WHEN type = 'DEPOSIT' THEN amount ELSE -amount END) AS net_deposits
+            FROM cash_transactions t
+            WHERE type <> 'RESET' AND id > COALESCE((
+                SELECT MAX(id) FROM cash_transactions r
+                WHERE r.user_id = t.user_id AND r.type = 'RESET'
+            ), 0)
+            GROUP BY user_id
+        ) ct ON ct.user_id = u.id
+        LEFT JOIN portfolios p ON p.user_id = u.id AND p.quantity <> 0
+        ORDER BY u.id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]leaderboardAccount, 0)
+	for rows.Next() {
+		var a leaderboardAccount
+		var symbol *string
+		var quantity *int
+		var avgPrice *float64
+		if err := rows.Scan(&a.UserID, &a.Username, &a.Cash, &a.NetDeposits, &symbol, &quantity, &avgPrice); err != nil {
+			return nil, err
+		}
+
+		// Rows for the same user are adjacent
+		if n := len(accounts); n == 0 || accounts[n-1].UserID != a.UserID {
+			accounts = append(accounts, a)
+		}
+		if symbol != nil {
+			last := &accounts[len(accounts)-1]
+			last.Positions = append(last.Positions, models.Portfolio{
+				UserID:           a.UserID,
+				StockSymbol:      *symbol,
+				Quantity:         *quantity,
+				AvgPurchasePrice: *avgPrice,
+			})
+		}
+	}
+	return accounts, rows.Err()
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRankAccounts(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 200.0})
+
+	accounts := []leaderboardAccount{
+		{UserID: 1, Username: "cash_only", Cash: 10000.0},
+		{UserID: 2, Username: "winner", Cash: 8000.0, Positions: []models.Portfolio{
+			{StockSymbol: "AAPL", Quantity: 20, AvgPurchasePrice: 100.0}, // Worth 4000 at market
+		}},
+		{UserID: 3, Username: "depositor", Cash: 15000.0, NetDeposits: 5000.0},
+		{UserID: 4, Username: "loser", Cash: 5000.0, Positions: []models.Portfolio{
+			{StockSymbol: "MSFT", Quantity: 10, AvgPurchasePrice: 100.0}, // Unquoted: valued at cost
+		}},
+	}
+
+	ranking := rankAccounts(accounts, prices, 10000.0)
+
+	want := []struct {
+		user          string
+		total         float64
+		returnPercent float64
+	}{
+		{"depositor", 15000.0, 0},
+		{"winner", 12000.0, 20},
+		{"cash_only", 10000.0, 0},
+		{"loser", 6000.0, -40},
+	}
+	for i, w := range want {
+		e := ranking[i]
+		if e.Rank != i+1 || e.Username != w.user || e.TotalValue != w.total || e.ReturnPercent != w.returnPercent {
+			t.Errorf("Rank %d: expected %s at %.2f (%.2f%%), got %+v", i+1, w.user, w.total, w.returnPercent, e)
+		}
+	}
+}
+
+func TestLeaderboard_CachesAndLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	loads := 0
+	fake := clock.NewFake(time.Now())
+	lb := NewLeaderboard(5*time.Second, 10000.0)
+	lb.clock = fake
+	lb.load = func() ([]leaderboardAccount, error) {
+		loads++
+		return []leaderboardAccount{
+			{UserID: 1, Username: "a", Cash: 100.0},
+			{UserID: 2, Username: "b", Cash: 300.0},
+			{UserID: 3, Username: "c", Cash: 200.0},
+		}, nil
+	}
+
+	router := gin.New()
+	router.GET("/api/leaderboard", lb.GetLeaderboard)
+
+	var page *Pagination
+	get := func(query string) (int, []LeaderboardEntry) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/leaderboard"+query, nil))
+		var body struct {
+			Data struct {
+				Leaders []LeaderboardEntry `json:"leaders"`
+			} `json:"data"`
+			Meta Meta `json:"meta"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		page = body.Meta.Pagination
+		return w.Code, body.Data.Leaders
+	}
+
+	if code, leaders := get("?limit=2"); code != http.StatusOK || len(leaders) != 2 || leaders[0].Username != "b" {
+		t.Errorf("Expected top 2 led by b, got %d %+v", code, leaders)
+	}
+	if page == nil || page.Limit != 2 || page.Count != 2 || !page.HasMore {
+		t.Errorf("Expected a page of 2 with more to come, got %+v", page)
+	}
+	if _, leaders := get(""); len(leaders) != 3 {
+		t.Errorf("Expected all 3 users, got %+v", leaders)
+	}
+	if page == nil || page.Limit != 10 || page.Count != 3 || page.HasMore {
+		t.Errorf("Expected the whole board in one page, got %+v", page)
+	}
+	if loads != 1 {
+		t.Errorf("Expected one load within the TTL, got %d", loads)
+	}
+
+	fake.Advance(6 * time.Second)
+	get("")
+	if loads != 2 {
+		t.Errorf("Expected a reload after the TTL, got %d loads", loads)
+	}
+
+	if code, _ := get("?limit=0"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for limit=0, got %d", code)
+	}
+}
+
+func TestLeaderboard_Empty(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lb := NewLeaderboard(time.Second, 10000.0)
+	lb.load = func() ([]leaderboardAccount, error) { return []leaderboardAccount{}, nil }
+
+	router := gin.New()
+	router.GET("/api/leaderboard", lb.GetLeaderboard)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/leaderboard", nil))
+	if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var body struct {
+		Data struct {
+			Leaders []LeaderboardEntry `json:"leaders"`
+			Count   int                `json:"count"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Data.Leaders == nil || body.Data.Count != 0 {
+		t.Errorf("Expected an empty list, got %s", w.Body.String())
+	}
+}
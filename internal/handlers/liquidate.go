@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// LiquidatedPosition is one sell made by a liquidation
+type LiquidatedPosition struct {
+	StockSymbol string  `json:"stock_symbol"`
+	TradeID     int     `json:"trade_id"`
+	Quantity    int     `json:"quantity"`
+	Price       float64 `json:"price"`
+	Proceeds    float64 `json:"proceeds"`
+	Fee         float64 `json:"fee"`
+}
+
+// SkippedPosition is a symbol a liquidation didn't sell, and why
+type SkippedPosition struct {
+	StockSymbol string `json:"stock_symbol"`
+	Note        string `json:"note"`
+}
+
+// Liquidate handles POST /api/trades/liquidate, selling the whole of one
+// position, or of every position when stock_symbol is omitted, at the
+// latest market price. Each symbol is a separate SellAll trade, so the
+// share count is read under the user's lock in the same transaction that
+// sells it. Symbols that aren't held or have no price yet are skipped with
+// a note.
+func (tp *TradeProcessor) Liquidate(c *gin.Context) {
+	var req models.LiquidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if userID, ok := AuthenticatedUserID(c); ok {
+		req.UserID = userID
+	}
+
+	symbols := []string{req.StockSymbol}
+	if req.StockSymbol != "" {
+		symbol, ok := market.NormalizeSymbol(req.StockSymbol)
+		if !ok {
+			RespondError(c, http.StatusBadRequest, ErrUnknownSymbol)
+			return
+		}
+		symbols[0] = symbol
+	} else {
+		held, err := heldSymbols(req.UserID)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, ErrUserNotFound)
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+			return
+		}
+		symbols = held
+	}
+
+	sold := make([]LiquidatedPosition, 0, len(symbols))
+	skipped := make([]SkippedPosition, 0)
+	totalProceeds, totalFees := 0.0, 0.0
+
+	for _, symbol := range symbols {
+		result := tp.SubmitTradeWithContext(c.Request.Context(), models.BuyRequest{
+			UserID:      req.UserID,
+			StockSymbol: symbol,
+			TradeType:   models.TradeTypeSell,
+			OrderType:   models.OrderTypeMarket,
+			SellAll:     true,
+			Note:        "Liquidation",
+		})
+		if !result.Success {
+			note := result.Error
+			switch result.Kind {
+			case KindNoPosition:
+				note = "not held"
+			case KindNoMarketPrice:
+				note = "no market price yet"
+			}
+			skipped = append(skipped, SkippedPosition{StockSymbol: symbol, Note: note})
+			continue
+		}
+
+		sold = append(sold, LiquidatedPosition{
+			StockSymbol: symbol,
+			TradeID:     result.TradeID,
+			Quantity:    result.Quantity,
+			Price:       result.Price,
+			Proceeds:    result.TotalAmount,
+			Fee:         result.Fee,
+		})
+		totalProceeds += result.TotalAmount
+		totalFees += result.Fee
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"trades":         sold,
+		"skipped":        skipped,
+		"total_proceeds": totalProceeds,
+		"total_fees":     totalFees,
+	})
+}
+
+// heldSymbols returns the symbols userID holds, or sql.ErrNoRows if the
+// user doesn't exist
+func heldSymbols(userID int) ([]string, error) {
+	var exists bool
+	err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	rows, err := db.DB.Query(
+		"SELECT stock_symbol FROM portfolios WHERE user_id = $1 AND quantity > 0 ORDER BY stock_symbol",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	symbols := make([]string, 0)
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, rows.Err()
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/gin-gonic/gin"
+)
+
+type liquidateResponse struct {
+	Data struct {
+		Trades        []LiquidatedPosition `json:"trades"`
+		Skipped       []SkippedPosition    `json:"skipped"`
+		TotalProceeds float64              `json:"total_proceeds"`
+	} `json:"data"`
+}
+
+func TestLiquidate(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "liquidator", 1000.0)
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 10, 140.0), ($1, 'MSFT', 5, 300.0), ($1, 'GOOGL', 2, 130.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	// GOOGL has no quote yet
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 150.0, Timestamp: time.Now()})
+	Prices.Set(market.Quote{Symbol: "MSFT", Price: 400.0, Timestamp: time.Now()})
+
+	tp := NewTradeProcessor(2)
+	tp.Start()
+	defer tp.Stop()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/trades/liquidate", tp.Liquidate)
+
+	liquidate := func(body string) liquidateResponse {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/trades/liquidate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp liquidateResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	// A symbol that isn't held is skipped, not an error
+	resp := liquidate(fmt.Sprintf(`{"user_id": %d, "stock_symbol": "TSLA"}`, userID))
+	if len(resp.Data.Trades) != 0 || len(resp.Data.Skipped) != 1 || resp.Data.Skipped[0].Note != "no market price yet" {
+		t.Errorf("Expected TSLA skipped, got %+v", resp.Data)
+	}
+
+	resp = liquidate(fmt.Sprintf(`{"user_id": %d}`, userID))
+	if len(resp.Data.Trades) != 2 {
+		t.Fatalf("Expected AAPL and MSFT sold, got %+v", resp.Data.Trades)
+	}
+	if resp.Data.Trades[0].Quantity != 10 || resp.Data.Trades[1].Quantity != 5 {
+		t.Errorf("Expected whole positions sold, got %+v", resp.Data.Trades)
+	}
+	if resp.Data.TotalProceeds != 3500.0 {
+		t.Errorf("Expected total proceeds 3500, got %.2f", resp.Data.TotalProceeds)
+	}
+	if len(resp.Data.Skipped) != 1 || resp.Data.Skipped[0].StockSymbol != "GOOGL" {
+		t.Errorf("Expected GOOGL skipped for lack of a price, got %+v", resp.Data.Skipped)
+	}
+
+	var remaining int
+	database.QueryRow("SELECT COUNT(*) FROM portfolios WHERE user_id = $1", userID).Scan(&remaining)
+	if remaining != 1 {
+		t.Errorf("Expected only GOOGL left, have %d positions", remaining)
+	}
+
+	var balance float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if balance != 4500.0 {
+		t.Errorf("Expected balance 4500, got %.2f", balance)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// recordLot opens a lot for a buy
+func recordLot(tx *sql.Tx, userID int, symbol string, quantity int, price float64, tradeID int) error {
+	_, err := tx.Exec(`
+        INSERT INTO portfolio_lots (user_id, stock_symbol, quantity, price, trade_id)
+        VALUES ($1, $2, $3, $4, $5)
+    `, userID, symbol, quantity, price, tradeID)
+	return err
+}
+
+// consumeLots removes quantity shares from the position's lots, oldest
+// first, and returns the cost of the shares taken and how many the lots
+// covered. Positions opened before lots were tracked may have fewer lot
+// shares than quantity.
+func consumeLots(tx *sql.Tx, userID int, symbol string, quantity int) (float64, int, error) {
+	rows, err := tx.Query(`
+        SELECT id, quantity, price
+        FROM portfolio_lots
+        WHERE user_id = $1 AND stock_symbol = $2
+        ORDER BY bought_at, id
+        FOR UPDATE
+    `, userID, symbol)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var lots []models.Lot
+	for rows.Next() {
+		var lot models.Lot
+		if err := rows.Scan(&lot.ID, &lot.Quantity, &lot.Price); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		lots = append(lots, lot)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	cost, covered, changed := takeFIFO(lots, quantity)
+	for _, lot := range changed {
+		if lot.Quantity == 0 {
+			_, err = tx.Exec("DELETE FROM portfolio_lots WHERE id = $1", lot.ID)
+		} else {
+			_, err = tx.Exec("UPDATE portfolio_lots SET quantity = $1 WHERE id = $2", lot.Quantity, lot.ID)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return cost, covered, nil
+}
+
+// takeFIFO takes quantity shares from lots in order. It returns their
+// total cost, how many shares the lots covered, and the lots it touched
+// with their remaining quantity (zero for a lot used up).
+func takeFIFO(lots []models.Lot, quantity int) (float64, int, []models.Lot) {
+	var cost float64
+	covered := 0
+	var changed []models.Lot
+
+	for _, lot := range lots {
+		if covered == quantity {
+			break
+		}
+		take := min(lot.Quantity, quantity-covered)
+		cost += float64(take) * lot.Price
+		covered += take
+
+		lot.Quantity -= take
+		changed = append(changed, lot)
+	}
+	return cost, covered, changed
+}
+
+// sellRealizedPL is a sell's profit after fees. Under FIFO the shares the lots
+// covered are costed at their lot prices and any remainder at the average
+// price; under average cost every share is costed at the average price.
+func sellRealizedPL(method config.CostBasisMethod, proceeds, fee, avgPrice float64, quantity int, lotCost float64, lotShares int) float64 {
+	if method == config.CostBasisFIFO {
+		return proceeds - fee - lotCost - avgPrice*float64(quantity-lotShares)
+	}
+	return proceeds - fee - avgPrice*float64(quantity)
+}
+
+// GetLots handles GET /api/portfolio/:userId/lots?symbol=AAPL, listing
+// open lots oldest first
+func GetLots(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	query := `
+        SELECT id, user_id, stock_symbol, quantity, price, bought_at
+        FROM portfolio_lots
+        WHERE user_id = $1`
+	args := []interface{}{userID}
+	if raw := c.Query("symbol"); raw != "" {
+		symbol, ok := parseSymbol(c, raw)
+		if !ok {
+			return
+		}
+		query += " AND stock_symbol = $2"
+		args = append(args, symbol)
+	}
+	query += " ORDER BY stock_symbol, bought_at, id"
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch lots")
+		return
+	}
+	defer rows.Close()
+
+	lots := make([]models.Lot, 0)
+	for rows.Next() {
+		var lot models.Lot
+		if err := rows.Scan(&lot.ID, &lot.UserID, &lot.StockSymbol, &lot.Quantity, &lot.Price, &lot.BoughtAt); err != nil {
+			continue
+		}
+		lots = append(lots, lot)
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"lots":  lots,
+		"count": len(lots),
+	})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestTakeFIFO_SpansLots(t *testing.T) {
+	lots := []models.Lot{
+		{ID: 1, Quantity: 10, Price: 100.0},
+		{ID: 2, Quantity: 10, Price: 120.0},
+		{ID: 3, Quantity: 5, Price: 90.0},
+	}
+
+	cost, covered, changed := takeFIFO(lots, 15)
+	if cost != 10*100.0+5*120.0 || covered != 15 {
+		t.Errorf("Expected cost 1600 for 15 shares, got %.2f for %d", cost, covered)
+	}
+	if len(changed) != 2 || changed[0].Quantity != 0 || changed[1].Quantity != 5 {
+		t.Errorf("Expected lot 1 used up and lot 2 left with 5, got %+v", changed)
+	}
+
+	// More shares than the lots hold: the lots cover what they can
+	cost, covered, _ = takeFIFO(lots[:1], 12)
+	if cost != 1000.0 || covered != 10 {
+		t.Errorf("Expected the single lot to cover 10 shares for 1000, got %d for %.2f", covered, cost)
+	}
+}
+
+func TestSellRealizedPL_AverageVsFIFO(t *testing.T) {
+	// Buy 10 @ 100, buy 10 @ 120, sell 15 @ 130
+	lots := []models.Lot{{Quantity: 10, Price: 100.0}, {Quantity: 10, Price: 120.0}}
+	avgPrice := 110.0
+	proceeds := 15 * 130.0
+
+	lotCost, lotShares, _ := takeFIFO(lots, 15)
+
+	if pl := sellRealizedPL(config.CostBasisAverage, proceeds, 0, avgPrice, 15, lotCost, lotShares); pl != 300.0 {
+		t.Errorf("Average cost: expected 300, got %.2f", pl)
+	}
+	if pl := sellRealizedPL(config.CostBasisFIFO, proceeds, 0, avgPrice, 15, lotCost, lotShares); pl != 350.0 {
+		t.Errorf("FIFO: expected 350, got %.2f", pl)
+	}
+
+	// Shares the lots don't cover fall back to the average price
+	lotCost, lotShares, _ = takeFIFO(lots[:1], 15)
+	if pl := sellRealizedPL(config.CostBasisFIFO, proceeds, 5, avgPrice, 15, lotCost, lotShares); pl != 1950.0-5-1000.0-550.0 {
+		t.Errorf("FIFO with uncovered shares: expected 395, got %.2f", pl)
+	}
+}
+
+func TestSell_RealizedPLByCostBasis(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	for method, want := range map[config.CostBasisMethod]float64{
+		config.CostBasisAverage: 300.0,
+		config.CostBasisFIFO:    350.0,
+	} {
+		userID := db.CreateTestUser(t, database, "lots_"+string(method), 10000.0)
+
+		tp := NewTradeProcessor(1)
+		tp.Config = config.Default()
+		tp.Config.CostBasis = method
+		tp.Start()
+
+		tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 100.0})
+		tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 120.0})
+		result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 15, Price: 130.0})
+		tp.Stop()
+
+		if !result.Success {
+			t.Fatalf("%s: sell failed: %s", method, result.Error)
+		}
+
+		var pl float64
+		database.QueryRow("SELECT realized_pl FROM trades WHERE id = $1", result.TradeID).Scan(&pl)
+		if pl != want {
+			t.Errorf("%s: expected realized P&L %.2f, got %.2f", method, want, pl)
+		}
+
+		// Lots are consumed oldest first in either mode
+		var lotCount, lotQty int
+		var lotPrice float64
+		database.QueryRow(`
+            SELECT COUNT(*), COALESCE(SUM(quantity), 0), COALESCE(MAX(price), 0)
+            FROM portfolio_lots WHERE user_id = $1
+        `, userID).Scan(&lotCount, &lotQty, &lotPrice)
+		if lotCount != 1 || lotQty != 5 || lotPrice != 120.0 {
+			t.Errorf("%s: expected one lot of 5 @ 120 left, got %d lots, %d shares, max price %.2f",
+				method, lotCount, lotQty, lotPrice)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+// marginAccount is what the margin checks need to know about an account.
+// Positions are valued at the latest prices.
+type marginAccount struct {
+	Cash       float64 // Negative while borrowing
+	LongValue  float64
+	ShortValue float64 // Zero or negative: what buying the shorts back would cost
+}
+
+// equity is what the account would be worth with every position closed
+func (a marginAccount) equity() float64 {
+	return a.Cash + a.LongValue + a.ShortValue
+}
+
+// buyingPower is how much more stock the account may buy: equity scaled by
+// the margin multiplier, less the long positions it already backs
+func (a marginAccount) buyingPower(multiplier float64) float64 {
+	return a.equity()*multiplier - a.LongValue
+}
+
+// meetsMaintenance reports whether equity is at least maintenancePercent
+// of the long positions
+func (a marginAccount) meetsMaintenance(maintenancePercent float64) bool {
+	return a.equity() >= a.LongValue*maintenancePercent/100
+}
+
+// afterBuy is the account once quantity shares of symbol are bought at
+// price for cost plus fee, valued at the latest price
+func (a marginAccount) afterBuy(symbol string, quantity int, price, cost, fee float64) marginAccount {
+	h := valueHolding(models.Portfolio{StockSymbol: symbol, Quantity: quantity, AvgPurchasePrice: price}, Prices)
+	a.Cash -= cost + fee
+	a.LongValue += h.CurrentValue
+	return a
+}
+
+// afterShortSell is the account once quantity shares of symbol are sold
+// short at price for proceeds less fee, valued at the latest price
+func (a marginAccount) afterShortSell(symbol string, quantity int, price, proceeds, fee float64) marginAccount {
+	h := valueHolding(models.Portfolio{StockSymbol: symbol, Quantity: -quantity, AvgPurchasePrice: price}, Prices)
+	a.Cash += proceeds - fee
+	a.ShortValue += h.CurrentValue
+	return a
+}
+
+// loadMarginAccount values the user's positions inside tx, alongside the
+// cash balance the caller has already locked
+func loadMarginAccount(tx *sql.Tx, userID int, cash float64) (marginAccount, error) {
+	account := marginAccount{Cash: cash}
+
+	rows, err := tx.Query(`
+        SELECT stock_symbol, quantity, avg_purchase_price
+        FROM portfolios
+        WHERE user_id = $1 AND quantity <> 0
+    `, userID)
+	if err != nil {
+		return account, err
+	}
+	defer rows.Close()
+
+	var positions []models.Portfolio
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice); err != nil {
+			return account, err
+		}
+		positions = append(positions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return account, err
+	}
+
+	valued := valueHoldings(positions, Prices)
+	for _, p := range positions {
+		if h := valued[holdingKey{UserID: p.UserID, Symbol: p.StockSymbol}]; p.Quantity > 0 {
+			account.LongValue += h.CurrentValue
+		} else {
+			account.ShortValue += h.CurrentValue
+		}
+	}
+	return account, nil
+}
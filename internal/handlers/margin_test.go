@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestMarginAccount_BuyingPower(t *testing.T) {
+	account := marginAccount{Cash: 10000.0}
+
+	if bp := account.buyingPower(1); bp != 10000.0 {
+		t.Errorf("Expected cash as buying power without margin, got %.2f", bp)
+	}
+	if bp := account.buyingPower(2); bp != 20000.0 {
+		t.Errorf("Expected twice equity at 2x, got %.2f", bp)
+	}
+
+	// Fully drawn: $20000 of stock on $10000 of equity
+	drawn := account.afterBuy("AAPL", 200, 100.0, 20000.0, 0)
+	if bp := drawn.buyingPower(2); bp != 0 {
+		t.Errorf("Expected no buying power left at the boundary, got %.2f", bp)
+	}
+}
+
+func TestMarginAccount_Maintenance(t *testing.T) {
+	// $2500 equity against $10000 of stock is exactly 25%
+	at := marginAccount{Cash: -7500.0, LongValue: 10000.0}
+	if !at.meetsMaintenance(25) {
+		t.Error("Expected equity at exactly 25% to meet maintenance")
+	}
+
+	beyond := marginAccount{Cash: -7501.0, LongValue: 10000.0}
+	if beyond.meetsMaintenance(25) {
+		t.Error("Expected equity below 25% to breach maintenance")
+	}
+
+	// Shorts count against equity
+	short := marginAccount{Cash: -5000.0, LongValue: 10000.0, ShortValue: -3000.0}
+	if short.meetsMaintenance(25) {
+		t.Error("Expected $2000 of equity on $10000 long to breach 25%")
+	}
+}
+
+func TestBuyStock_OnMargin(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 100.0, Timestamp: time.Now()})
+
+	userID := db.CreateTestUser(t, database, "margin_buyer", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.MarginMultiplier = 2
+	tp.Start()
+	defer tp.Stop()
+
+	// $20000 is exactly the buying power of $10000 at 2x
+	result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 200, Price: 100.0})
+	if !result.Success {
+		t.Fatalf("Expected a buy at the margin boundary to succeed, got error: %s", result.Error)
+	}
+
+	var cash, marginUsed float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&cash)
+	database.QueryRow("SELECT margin_used FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID).Scan(&marginUsed)
+	if cash != -10000.0 || marginUsed != 10000.0 {
+		t.Errorf("Expected $10000 borrowed, got cash %.2f and margin used %.2f", cash, marginUsed)
+	}
+
+	// One more share is beyond it
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+	if result.Success || result.Kind != KindInsufficientFunds {
+		t.Errorf("Expected KindInsufficientFunds beyond the boundary, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	// Selling half takes half the loan with it
+	if result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 100, Price: 100.0}); !result.Success {
+		t.Fatalf("Expected sell to succeed, got error: %s", result.Error)
+	}
+	database.QueryRow("SELECT margin_used FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID).Scan(&marginUsed)
+	if marginUsed != 5000.0 {
+		t.Errorf("Expected $5000 of margin left on 100 shares, got %.2f", marginUsed)
+	}
+}
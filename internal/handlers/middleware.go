@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/semaphore"
+)
+
+// LimitConcurrency caps the number of requests in flight through the
+// wrapped routes. Excess requests get 503 immediately instead of parking a
+// goroutine behind the trade queue.
+func LimitConcurrency(max int64) gin.HandlerFunc {
+	sem := semaphore.NewWeighted(max)
+
+	return func(c *gin.Context) {
+		if !sem.TryAcquire(1) {
+			RespondError(c, http.StatusServiceUnavailable, "Too many requests in flight, try again")
+			c.Abort()
+			return
+		}
+		defer sem.Release(1)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimitConcurrency_RejectsWhenSaturated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	router := gin.New()
+	router.POST("/trade", LimitConcurrency(1), func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		Respond(c, http.StatusOK, gin.H{"ok": true})
+	})
+
+	// First request takes the only slot and blocks
+	firstDone := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/trade", nil))
+		firstDone <- w.Code
+	}()
+	<-entered
+
+	// Second request must be rejected without waiting
+	start := time.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/trade", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while saturated, got %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected immediate rejection, took %v", elapsed)
+	}
+
+	close(release)
+	if code := <-firstDone; code != http.StatusOK {
+		t.Errorf("Expected first request to succeed, got %d", code)
+	}
+
+	// Slot is free again
+	go func() { <-entered }()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/trade", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected request to succeed after release, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_RejectsRapidRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(1, 3)
+	router := gin.New()
+	router.POST("/trade", limiter.Middleware(), func(c *gin.Context) {
+		Respond(c, http.StatusOK, gin.H{"ok": true})
+	})
+
+	fire := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/trade", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	allowed, limited := 0, 0
+	for i := 0; i < 10; i++ {
+		w := fire("10.0.0.1")
+		switch w.Code {
+		case http.StatusOK:
+			allowed++
+		case http.StatusTooManyRequests:
+			limited++
+			if w.Header().Get("Retry-After") == "" {
+				t.Error("Expected Retry-After on 429")
+			}
+		default:
+			t.Fatalf("Unexpected status %d", w.Code)
+		}
+	}
+
+	if allowed != 3 || limited != 7 {
+		t.Errorf("Expected 3 allowed and 7 limited, got %d and %d", allowed, limited)
+	}
+
+	// Another client has its own bucket
+	if w := fire("10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("Expected a different client to be allowed, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_SweepsIdleBuckets(t *testing.T) {
+	limiter := NewRateLimiter(1, 2)
+	fake := clock.NewFake(time.Now())
+	limiter.clock = fake
+
+	limiter.allow("a")
+	limiter.allow("b")
+
+	fake.Advance(time.Minute)
+	limiter.allow("c")
+
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Expected idle buckets to be swept, have %d", len(limiter.buckets))
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Notifier is told about each trade that commits, e.g. to email the user
+// or call a webhook. Notify runs off the worker, so it may block; its error
+// is logged and never affects the trade.
+type Notifier interface {
+	Notify(event TradeConfirmation) error
+}
+
+// noopNotifier is the default Notifier, which tells no one
+type noopNotifier struct{}
+
+func (noopNotifier) Notify(TradeConfirmation) error { return nil }
+
+// notify hands a confirmation to the notifier, logging a failure
+func (tp *TradeProcessor) notify(event TradeConfirmation) {
+	if err := tp.Notifier.Notify(event); err != nil {
+		slog.Error("Trade notification failed", "trade_id", event.TradeID, "user_id", event.UserID, "error", err)
+	}
+}
+
+// Webhook delivery defaults
+const (
+	webhookTimeout    = 5 * time.Second
+	webhookRetryDelay = time.Second
+)
+
+// WebhookNotifier POSTs each trade confirmation as JSON to URL, retrying a
+// failed delivery up to Retries more times with exponential backoff
+type WebhookNotifier struct {
+	URL        string
+	Retries    int
+	RetryDelay time.Duration // Before the first retry; doubles after each
+	Client     *http.Client
+}
+
+// NewWebhookNotifier returns a notifier posting to url, retrying twice
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:        url,
+		Retries:    2,
+		RetryDelay: webhookRetryDelay,
+		Client:     &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify delivers event, returning the last attempt's error if every
+// attempt fails. Any 2xx response counts as delivered.
+func (w *WebhookNotifier) Notify(event TradeConfirmation) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	delay := w.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err = w.post(body)
+		if err == nil || attempt >= w.Retries {
+			return err
+		}
+
+		slog.Warn("Retrying trade webhook", "trade_id", event.TradeID, "attempt", attempt+1, "delay", delay, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post makes one delivery attempt
+func (w *WebhookNotifier) post(body []byte) error {
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
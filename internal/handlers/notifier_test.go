@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+// webhookServer records each confirmation POSTed to it
+func webhookServer(t *testing.T) (*httptest.Server, <-chan TradeConfirmation) {
+	received := make(chan TradeConfirmation, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		var c TradeConfirmation
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			t.Errorf("Failed to decode webhook payload: %v", err)
+		}
+		received <- c
+	}))
+	return server, received
+}
+
+func TestWebhookNotifier_PostsConfirmation(t *testing.T) {
+	server, received := webhookServer(t)
+	defer server.Close()
+
+	sent := TradeConfirmation{TradeID: 42, UserID: 7, StockSymbol: "AAPL", TradeType: models.TradeTypeBuy, Quantity: 3, Price: 150.0, TotalAmount: 450.0}
+	if err := NewWebhookNotifier(server.URL).Notify(sent); err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+
+	select {
+	case c := <-received:
+		if c != sent {
+			t.Errorf("Expected %+v, got %+v", sent, c)
+		}
+	default:
+		t.Fatal("Expected the webhook to have been called")
+	}
+}
+
+func TestWebhookNotifier_Retries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookNotifier(server.URL)
+	webhook.RetryDelay = time.Millisecond
+
+	webhook.Retries = 1
+	if err := webhook.Notify(TradeConfirmation{TradeID: 1}); err == nil {
+		t.Error("Expected two failed attempts to return an error")
+	}
+
+	calls.Store(0)
+	webhook.Retries = 2
+	if err := webhook.Notify(TradeConfirmation{TradeID: 1}); err != nil {
+		t.Errorf("Expected the third attempt to deliver, got %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+}
+
+func TestSubmitTrade_NotifiesWebhook(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	server, received := webhookServer(t)
+	defer server.Close()
+
+	userID := db.CreateTestUser(t, database, "notified", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Notifier = NewWebhookNotifier(server.URL)
+	tp.Start()
+	defer tp.Stop()
+
+	result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 2, Price: 150.0})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+
+	select {
+	case c := <-received:
+		if c.TradeID != result.TradeID || c.UserID != userID || c.StockSymbol != "AAPL" || c.Quantity != 2 || c.TotalAmount != 300.0 {
+			t.Errorf("Expected a notification of trade %d, got %+v", result.TradeID, c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the webhook")
+	}
+}
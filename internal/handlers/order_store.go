@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+// OrderStore persists resting orders so the order book survives a restart.
+// The book stays the authority on what is resting; the store is written
+// through as orders are placed, cancelled and filled.
+type OrderStore interface {
+	// Insert saves a new open order, returning it with its ID and
+	// creation time assigned
+	Insert(order models.Order) (models.Order, error)
+
+	// Close moves an open order to status, FILLED with the trade that
+	// filled it or CANCELLED with tradeID 0
+	Close(orderID int, status string, tradeID int) error
+
+	// Open returns every open order, oldest first
+	Open() ([]models.Order, error)
+}
+
+// orderStore persists the order book; nil keeps orders in memory only. Set
+// once at startup.
+var orderStore OrderStore
+
+// SetOrderStore sets where the order book is persisted
+func SetOrderStore(s OrderStore) {
+	orderStore = s
+}
+
+// dbOrderStore keeps orders in the orders table
+type dbOrderStore struct {
+	db *sql.DB
+}
+
+// NewDBOrderStore returns an OrderStore backed by database's orders table
+func NewDBOrderStore(database *sql.DB) OrderStore {
+	return dbOrderStore{db: database}
+}
+
+func (s dbOrderStore) Insert(order models.Order) (models.Order, error) {
+	err := s.db.QueryRow(`
+        INSERT INTO orders (user_id, stock_symbol, quantity, trade_type, order_type, limit_price, trigger_price)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at
+    `, order.UserID, order.StockSymbol, order.Quantity, order.TradeType, order.OrderType,
+		order.LimitPrice, order.TriggerPrice).Scan(&order.ID, &order.CreatedAt)
+	return order, err
+}
+
+func (s dbOrderStore) Close(orderID int, status string, tradeID int) error {
+	_, err := s.db.Exec(`
+        UPDATE orders
+        SET status = $2, trade_id = NULLIF($3, 0), closed_at = NOW()
+        WHERE id = $1 AND status = 'OPEN'
+    `, orderID, status, tradeID)
+	return err
+}
+
+func (s dbOrderStore) Open() ([]models.Order, error) {
+	rows, err := s.db.Query(`
+        SELECT id, user_id, stock_symbol, quantity, trade_type, order_type, limit_price, trigger_price, created_at
+        FROM orders
+        WHERE status = 'OPEN'
+        ORDER BY id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var o models.Order
+		if err := rows.Scan(&o.ID, &o.UserID, &o.StockSymbol, &o.Quantity, &o.TradeType, &o.OrderType,
+			&o.LimitPrice, &o.TriggerPrice, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// RestoreOrders loads the store's open orders into Orders, then fills any
+// that provider's current price already crosses, since the market may have
+// moved through them while the server was down. Call it once at startup,
+// after SetOrderStore and SetOrderExecutor and before the hub starts, so no
+// tick is matched against a half-loaded book. It returns how many orders
+// were loaded.
+func RestoreOrders(provider market.MarketDataProvider) (int, error) {
+	if orderStore == nil {
+		return 0, nil
+	}
+
+	orders, err := orderStore.Open()
+	if err != nil {
+		return 0, err
+	}
+
+	symbols := make(map[string]bool)
+	for _, o := range orders {
+		Orders.AddOrder(o)
+		symbols[o.StockSymbol] = true
+	}
+
+	for symbol := range symbols {
+		price, err := provider.GetPrice(symbol)
+		if err != nil {
+			slog.Warn("No price to re-evaluate restored orders", "symbol", symbol, "error", err)
+			continue
+		}
+		if matched := Orders.MatchAgainstPrice(symbol, price); len(matched) > 0 {
+			fillOrders(matched, price)
+		}
+	}
+	return len(orders), nil
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// placeOrder places req through PlaceOrder and returns the resting order
+func placeOrder(t *testing.T, req models.BuyRequest) models.Order {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	PlaceOrder(c, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected order to be placed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	open := Orders.OpenOrders(req.UserID)
+	return open[len(open)-1]
+}
+
+func TestRestoreOrders_AfterRestart(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	gin.SetMode(gin.TestMode)
+	userID := db.CreateTestUser(t, database, "restored", 10000.0)
+
+	SetOrderStore(NewDBOrderStore(database))
+	defer SetOrderStore(nil)
+	exec := &fakeExecutor{}
+	SetOrderExecutor(exec)
+	defer SetOrderExecutor(nil)
+
+	saved := Orders
+	Orders = NewOrderBook()
+	defer func() { Orders = saved }()
+
+	crossed := placeOrder(t, models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 140.0, TradeType: models.TradeTypeBuy, OrderType: models.OrderTypeLimit})
+	resting := placeOrder(t, models.BuyRequest{UserID: userID, StockSymbol: "MSFT", Quantity: 2, Price: 300.0, TradeType: models.TradeTypeBuy, OrderType: models.OrderTypeLimit})
+	cancelled := placeOrder(t, models.BuyRequest{UserID: userID, StockSymbol: "TSLA", Quantity: 1, Price: 200.0, TradeType: models.TradeTypeSell, OrderType: models.OrderTypeStopLoss})
+
+	router := gin.New()
+	router.DELETE("/api/orders/:orderId", CancelOrder)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/orders/%d", cancelled.ID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected cancel to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Restart: the book is empty, and AAPL fell through the limit while down
+	Orders = NewOrderBook()
+	provider := newFakeProvider()
+	provider.prices = map[string]float64{"AAPL": 139.0, "MSFT": 310.0}
+
+	restored, err := RestoreOrders(provider)
+	if err != nil {
+		t.Fatalf("Failed to restore orders: %v", err)
+	}
+	if restored != 2 {
+		t.Errorf("Expected the 2 open orders restored, got %d", restored)
+	}
+
+	if len(exec.reqs) != 1 || exec.reqs[0].StockSymbol != "AAPL" || exec.reqs[0].Price != 139.0 {
+		t.Fatalf("Expected the crossed AAPL order filled at 139, got %+v", exec.reqs)
+	}
+
+	open := Orders.OpenOrders(userID)
+	if len(open) != 1 {
+		t.Fatalf("Expected 1 order resting after restart, got %+v", open)
+	}
+	if got := open[0]; got.ID != resting.ID || got.StockSymbol != "MSFT" || got.Quantity != 2 ||
+		got.LimitPrice != 300.0 || got.OrderType != models.OrderTypeLimit || !got.CreatedAt.Equal(resting.CreatedAt) {
+		t.Errorf("Expected %+v restored unchanged, got %+v", resting, got)
+	}
+
+	statuses := map[int]string{}
+	rows, err := database.Query("SELECT id, status FROM orders WHERE user_id = $1", userID)
+	if err != nil {
+		t.Fatalf("Failed to query orders: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var status string
+		rows.Scan(&id, &status)
+		statuses[id] = status
+	}
+	want := map[int]string{
+		crossed.ID:   models.OrderStatusFilled,
+		resting.ID:   models.OrderStatusOpen,
+		cancelled.ID: models.OrderStatusCancelled,
+	}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("Expected order %d %s, got %q", id, status, statuses[id])
+		}
+	}
+}
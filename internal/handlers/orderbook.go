@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+// OrderBook holds resting limit and stop orders per symbol until the
+// market price crosses them. Safe for concurrent use.
+type OrderBook struct {
+	mu       sync.Mutex
+	nextID   int
+	bySymbol map[string][]models.Order
+	symbolOf map[int]string // Order ID -> symbol, for cancellation
+	clock    clock.Clock    // Stamps orders placed without a time
+}
+
+// NewOrderBook creates an empty order book
+func NewOrderBook() *OrderBook {
+	return &OrderBook{
+		bySymbol: make(map[string][]models.Order),
+		symbolOf: make(map[int]string),
+		clock:    clock.Real(),
+	}
+}
+
+// AddOrder rests an order in the book and returns it with its ID assigned.
+// An order that already has an ID, e.g. from the order store, keeps it.
+func (ob *OrderBook) AddOrder(order models.Order) models.Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if order.ID == 0 {
+		ob.nextID++
+		order.ID = ob.nextID
+	} else if order.ID > ob.nextID {
+		ob.nextID = order.ID
+	}
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = ob.clock.Now()
+	}
+
+	ob.bySymbol[order.StockSymbol] = append(ob.bySymbol[order.StockSymbol], order)
+	ob.symbolOf[order.ID] = order.StockSymbol
+	return order
+}
+
+// MatchAgainstPrice removes and returns every order on symbol that the
+// given price crosses, oldest first
+func (ob *OrderBook) MatchAgainstPrice(symbol string, price float64) []models.Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	var matched []models.Order
+	resting := ob.bySymbol[symbol][:0]
+	for _, o := range ob.bySymbol[symbol] {
+		if crosses(o, price) {
+			matched = append(matched, o)
+			delete(ob.symbolOf, o.ID)
+		} else {
+			resting = append(resting, o)
+		}
+	}
+	ob.bySymbol[symbol] = resting
+
+	return matched
+}
+
+// CancelOrder removes a resting order. It returns false if the order
+// doesn't exist or has already filled.
+func (ob *OrderBook) CancelOrder(orderID int) (models.Order, bool) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	symbol, ok := ob.symbolOf[orderID]
+	if !ok {
+		return models.Order{}, false
+	}
+	delete(ob.symbolOf, orderID)
+
+	orders := ob.bySymbol[symbol]
+	for i, o := range orders {
+		if o.ID == orderID {
+			ob.bySymbol[symbol] = append(orders[:i], orders[i+1:]...)
+			return o, true
+		}
+	}
+	return models.Order{}, false
+}
+
+// Order cancellation failures
+var (
+	errOrderNotFound = errors.New("order not found")
+	errOrderNotOwned = errors.New("order belongs to another user")
+)
+
+// CancelUserOrder cancels a resting order on behalf of userID. It returns
+// errOrderNotFound if the order doesn't exist or has already been matched,
+// and errOrderNotOwned, leaving the order resting, if someone else placed it.
+func (ob *OrderBook) CancelUserOrder(userID, orderID int) (models.Order, error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	symbol, ok := ob.symbolOf[orderID]
+	if !ok {
+		return models.Order{}, errOrderNotFound
+	}
+
+	orders := ob.bySymbol[symbol]
+	for i, o := range orders {
+		if o.ID != orderID {
+			continue
+		}
+		if o.UserID != userID {
+			return models.Order{}, errOrderNotOwned
+		}
+		delete(ob.symbolOf, orderID)
+		ob.bySymbol[symbol] = append(orders[:i], orders[i+1:]...)
+		return o, nil
+	}
+	return models.Order{}, errOrderNotFound
+}
+
+// CancelUserOrders removes every order userID has resting and returns
+// them, oldest first
+func (ob *OrderBook) CancelUserOrders(userID int) []models.Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	cancelled := make([]models.Order, 0)
+	for symbol, symbolOrders := range ob.bySymbol {
+		resting := symbolOrders[:0]
+		for _, o := range symbolOrders {
+			if o.UserID == userID {
+				cancelled = append(cancelled, o)
+				delete(ob.symbolOf, o.ID)
+			} else {
+				resting = append(resting, o)
+			}
+		}
+		ob.bySymbol[symbol] = resting
+	}
+
+	sort.Slice(cancelled, func(i, j int) bool { return cancelled[i].ID < cancelled[j].ID })
+	return cancelled
+}
+
+// OpenOrders returns a user's resting orders, oldest first
+func (ob *OrderBook) OpenOrders(userID int) []models.Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	orders := make([]models.Order, 0)
+	for _, symbolOrders := range ob.bySymbol {
+		for _, o := range symbolOrders {
+			if o.UserID == userID {
+				orders = append(orders, o)
+			}
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	return orders
+}
+
+// crosses reports whether price is at or better than the order's limit,
+// or has reached a stop's trigger
+func crosses(o models.Order, price float64) bool {
+	switch o.OrderType {
+	case models.OrderTypeStopLoss:
+		return price <= o.TriggerPrice
+	case models.OrderTypeTakeProfit:
+		return price >= o.TriggerPrice
+	}
+
+	if o.TradeType == models.TradeTypeSell {
+		return price >= o.LimitPrice
+	}
+	return price <= o.LimitPrice
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/auth"
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOrderBook_StampsOrdersFromClock(t *testing.T) {
+	ob := NewOrderBook()
+	placed := time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC)
+	ob.clock = clock.NewFake(placed)
+
+	if order := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "AAPL", Quantity: 1, LimitPrice: 100.0}); !order.CreatedAt.Equal(placed) {
+		t.Errorf("Expected the order stamped %v, got %v", placed, order.CreatedAt)
+	}
+
+	// An order that already has a time keeps it
+	earlier := placed.Add(-time.Hour)
+	if order := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "AAPL", Quantity: 1, LimitPrice: 100.0, CreatedAt: earlier}); !order.CreatedAt.Equal(earlier) {
+		t.Errorf("Expected the order to keep %v, got %v", earlier, order.CreatedAt)
+	}
+}
+
+func TestOrderBook_KeepsAssignedIDs(t *testing.T) {
+	ob := NewOrderBook()
+
+	if order := ob.AddOrder(models.Order{ID: 41, UserID: 1, StockSymbol: "AAPL", Quantity: 1, LimitPrice: 100.0}); order.ID != 41 {
+		t.Errorf("Expected the order to keep ID 41, got %d", order.ID)
+	}
+	if order := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "AAPL", Quantity: 1, LimitPrice: 100.0}); order.ID != 42 {
+		t.Errorf("Expected the next new order to follow at 42, got %d", order.ID)
+	}
+}
+
+func TestOrderBook_MatchAgainstPrice(t *testing.T) {
+	ob := NewOrderBook()
+
+	buy := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "AAPL", Quantity: 5, TradeType: models.TradeTypeBuy, LimitPrice: 145.0})
+	sell := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "AAPL", Quantity: 2, TradeType: models.TradeTypeSell, LimitPrice: 155.0})
+	ob.AddOrder(models.Order{UserID: 2, StockSymbol: "MSFT", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 400.0})
+
+	if buy.ID == sell.ID {
+		t.Fatalf("Expected distinct order IDs, got %d twice", buy.ID)
+	}
+
+	if matched := ob.MatchAgainstPrice("AAPL", 150.0); len(matched) != 0 {
+		t.Errorf("Expected no fills between the limits, got %d", len(matched))
+	}
+
+	matched := ob.MatchAgainstPrice("AAPL", 145.0)
+	if len(matched) != 1 || matched[0].ID != buy.ID {
+		t.Fatalf("Expected buy limit to fill at its limit, got %+v", matched)
+	}
+
+	matched = ob.MatchAgainstPrice("AAPL", 156.0)
+	if len(matched) != 1 || matched[0].ID != sell.ID {
+		t.Fatalf("Expected sell limit to fill above its limit, got %+v", matched)
+	}
+
+	if matched := ob.MatchAgainstPrice("AAPL", 100.0); len(matched) != 0 {
+		t.Errorf("Expected filled orders to leave the book, got %d", len(matched))
+	}
+	if open := ob.OpenOrders(2); len(open) != 1 {
+		t.Errorf("Expected MSFT order to stay open, got %d", len(open))
+	}
+}
+
+func TestOrderBook_StopTriggers(t *testing.T) {
+	ob := NewOrderBook()
+
+	stop := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 2, TradeType: models.TradeTypeSell, OrderType: models.OrderTypeStopLoss, TriggerPrice: 240.0})
+	take := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 2, TradeType: models.TradeTypeSell, OrderType: models.OrderTypeTakeProfit, TriggerPrice: 260.0})
+
+	if matched := ob.MatchAgainstPrice("TSLA", 250.0); len(matched) != 0 {
+		t.Errorf("Expected no triggers between 240 and 260, got %d", len(matched))
+	}
+
+	matched := ob.MatchAgainstPrice("TSLA", 239.95)
+	if len(matched) != 1 || matched[0].ID != stop.ID {
+		t.Fatalf("Expected stop-loss to trigger below 240, got %+v", matched)
+	}
+
+	matched = ob.MatchAgainstPrice("TSLA", 260.0)
+	if len(matched) != 1 || matched[0].ID != take.ID {
+		t.Fatalf("Expected take-profit to trigger at 260, got %+v", matched)
+	}
+}
+
+func TestOrderBook_CancelOrder(t *testing.T) {
+	ob := NewOrderBook()
+
+	order := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 240.0})
+
+	if _, ok := ob.CancelOrder(order.ID); !ok {
+		t.Fatal("Expected resting order to cancel")
+	}
+	if _, ok := ob.CancelOrder(order.ID); ok {
+		t.Error("Expected second cancel to fail")
+	}
+	if matched := ob.MatchAgainstPrice("TSLA", 200.0); len(matched) != 0 {
+		t.Errorf("Expected cancelled order not to fill, got %d", len(matched))
+	}
+	if open := ob.OpenOrders(1); len(open) != 0 {
+		t.Errorf("Expected no open orders, got %d", len(open))
+	}
+}
+
+func TestOrderBook_CancelUserOrders(t *testing.T) {
+	ob := NewOrderBook()
+
+	first := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 240.0})
+	ob.AddOrder(models.Order{UserID: 2, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 240.0})
+	second := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "AAPL", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 140.0})
+
+	cancelled := ob.CancelUserOrders(1)
+	if len(cancelled) != 2 || cancelled[0].ID != first.ID || cancelled[1].ID != second.ID {
+		t.Fatalf("Expected user 1's two orders oldest first, got %+v", cancelled)
+	}
+	if _, ok := ob.CancelOrder(first.ID); ok {
+		t.Error("Expected a cancelled order to be gone")
+	}
+	if open := ob.OpenOrders(2); len(open) != 1 {
+		t.Errorf("Expected user 2's order to keep resting, got %d", len(open))
+	}
+}
+
+func TestCancelOrder_Endpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	saved := Orders
+	Orders = NewOrderBook()
+	defer func() { Orders = saved }()
+
+	order := Orders.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 240.0})
+
+	// The owner comes from the bearer token, not the path
+	issuer := auth.NewIssuer("secret", time.Hour)
+	router := gin.New()
+	router.Use(RequireAuth(issuer))
+	router.DELETE("/api/orders/:orderId", CancelOrder)
+	cancel := func(userID, orderID int) *httptest.ResponseRecorder {
+		token, _, _ := issuer.Issue(userID)
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/orders/%d", orderID), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := cancel(2, order.ID); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 cancelling another user's order, got %d", w.Code)
+	}
+	if open := Orders.OpenOrders(1); len(open) != 1 {
+		t.Fatalf("Expected the order to keep resting after a forbidden cancel, got %d open", len(open))
+	}
+
+	if w := cancel(1, order.ID); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 cancelling a resting order, got %d: %s", w.Code, w.Body.String())
+	}
+	if open := Orders.OpenOrders(1); len(open) != 0 {
+		t.Errorf("Expected no open orders after cancelling, got %d", len(open))
+	}
+
+	if w := cancel(1, order.ID); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 cancelling twice, got %d", w.Code)
+	}
+	if w := cancel(1, 999); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown order, got %d", w.Code)
+	}
+
+	token, _, _ := issuer.Issue(1)
+	req := httptest.NewRequest(http.MethodDelete, "/api/orders/abc", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-numeric order id, got %d", w.Code)
+	}
+}
+
+func TestCancelOrder_AuthDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	saved := Orders
+	Orders = NewOrderBook()
+	defer func() { Orders = saved }()
+
+	order := Orders.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 240.0})
+
+	router := gin.New()
+	router.DELETE("/api/orders/:orderId", CancelOrder)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/orders/%d", order.ID), nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 cancelling without auth, got %d: %s", w.Code, w.Body.String())
+	}
+	if open := Orders.OpenOrders(1); len(open) != 0 {
+		t.Errorf("Expected no open orders after cancelling, got %d", len(open))
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/orders/%d", order.ID), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 cancelling twice, got %d", w.Code)
+	}
+}
+
+func TestCancelOrder_FilledOrderIsNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	saved := Orders
+	Orders = NewOrderBook()
+	defer func() { Orders = saved }()
+
+	order := Orders.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 240.0})
+	Orders.MatchAgainstPrice("TSLA", 239.0)
+
+	router := gin.New()
+	router.DELETE("/api/orders/:orderId", CancelOrder)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/orders/%d", order.ID), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 cancelling a filled order, got %d", w.Code)
+	}
+}
+
+func TestCancelOrder_RacesFill(t *testing.T) {
+	exec := &fakeExecutor{}
+	SetOrderExecutor(exec)
+	defer SetOrderExecutor(nil)
+
+	ob := NewOrderBook()
+	const rounds = 500
+	var cancelled int
+	for i := 0; i < rounds; i++ {
+		order := ob.AddOrder(models.Order{UserID: 1, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeBuy, LimitPrice: 240.0})
+
+		var wg sync.WaitGroup
+		var cancelErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, cancelErr = ob.CancelUserOrder(1, order.ID)
+		}()
+		go func() {
+			// What the hub does with a tick that crosses the limit
+			defer wg.Done()
+			fillOrders(ob.MatchAgainstPrice("TSLA", 239.0), 239.0)
+		}()
+		wg.Wait()
+
+		if cancelErr == nil {
+			cancelled++
+		} else if cancelErr != errOrderNotFound {
+			t.Fatalf("Round %d: expected a losing cancel to find nothing, got %v", i, cancelErr)
+		}
+	}
+
+	// Every order was either cancelled or filled, never both
+	if filled := len(exec.reqs); cancelled+filled != rounds {
+		t.Errorf("Expected %d orders cancelled or filled, got %d cancelled and %d filled", rounds, cancelled, filled)
+	}
+	if open := ob.OpenOrders(1); len(open) != 0 {
+		t.Errorf("Expected nothing left resting, got %d", len(open))
+	}
+}
+
+type fakeExecutor struct {
+	mu   sync.Mutex
+	reqs []models.BuyRequest
+}
+
+func (f *fakeExecutor) SubmitTrade(req models.BuyRequest) TradeResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reqs = append(f.reqs, req)
+	return TradeResult{Success: true, TradeID: len(f.reqs)}
+}
+
+func TestFillOrders_ExecutesAtCrossingPrice(t *testing.T) {
+	exec := &fakeExecutor{}
+	SetOrderExecutor(exec)
+	defer SetOrderExecutor(nil)
+
+	fillOrders([]models.Order{
+		{ID: 1, UserID: 7, StockSymbol: "AMZN", Quantity: 3, TradeType: models.TradeTypeSell, LimitPrice: 180.0},
+	}, 181.5)
+
+	if len(exec.reqs) != 1 {
+		t.Fatalf("Expected 1 submitted trade, got %d", len(exec.reqs))
+	}
+	req := exec.reqs[0]
+	if req.UserID != 7 || req.TradeType != models.TradeTypeSell || req.Quantity != 3 || req.Price != 181.5 {
+		t.Errorf("Unexpected fill request: %+v", req)
+	}
+}
+
+func TestFillOrders_NotesTriggeredStops(t *testing.T) {
+	exec := &fakeExecutor{}
+	SetOrderExecutor(exec)
+	defer SetOrderExecutor(nil)
+
+	fillOrders([]models.Order{
+		{ID: 4, UserID: 7, StockSymbol: "TSLA", Quantity: 1, TradeType: models.TradeTypeSell, OrderType: models.OrderTypeStopLoss, TriggerPrice: 240.0},
+	}, 239.5)
+
+	if len(exec.reqs) != 1 || !strings.Contains(exec.reqs[0].Note, "Triggered automatically by STOP_LOSS order 4") {
+		t.Errorf("Expected triggered note on the sell, got %+v", exec.reqs)
+	}
+}
+
+func TestFillOrders_StopWithoutSharesIsCancelled(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "stopped_out", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 1, 150.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	SetOrderExecutor(tp)
+	defer SetOrderExecutor(nil)
+
+	// Stop was sized for 5 shares but only 1 is left when it fires
+	fillOrders([]models.Order{
+		{ID: 1, UserID: userID, StockSymbol: "AAPL", Quantity: 5, TradeType: models.TradeTypeSell, OrderType: models.OrderTypeStopLoss, TriggerPrice: 140.0},
+	}, 139.0)
+
+	var tradeCount, quantity int
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&tradeCount)
+	database.QueryRow("SELECT quantity FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID).Scan(&quantity)
+
+	if tradeCount != 0 {
+		t.Errorf("Expected no trade recorded for a cancelled stop, got %d", tradeCount)
+	}
+	if quantity != 1 {
+		t.Errorf("Expected holding untouched at 1 share, got %d", quantity)
+	}
+}
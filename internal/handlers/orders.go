@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Orders is the shared book of resting orders, matched on each price tick
+var Orders = NewOrderBook()
+
+// OrderExecutor executes orders once they fill
+type OrderExecutor interface {
+	SubmitTrade(req models.BuyRequest) TradeResult
+}
+
+// orderExecutor runs filled orders; set once at startup
+var orderExecutor OrderExecutor
+
+// SetOrderExecutor sets where filled orders are sent for execution
+func SetOrderExecutor(e OrderExecutor) {
+	orderExecutor = e
+}
+
+// PlaceOrder rests a LIMIT, STOP_LOSS or TAKE_PROFIT order in the order
+// book. Price is the limit for LIMIT orders and the trigger for stops.
+func PlaceOrder(c *gin.Context, req models.BuyRequest) {
+	symbol, ok := market.NormalizeSymbol(req.StockSymbol)
+	if !ok {
+		RespondError(c, http.StatusBadRequest, ErrUnknownSymbol)
+		return
+	}
+	req.StockSymbol = symbol
+
+	if err := market.ValidateLimitPrice(req.StockSymbol, req.Price); err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order := models.Order{
+		UserID:      req.UserID,
+		StockSymbol: req.StockSymbol,
+		Quantity:    req.Quantity,
+		TradeType:   req.TradeType,
+		OrderType:   req.OrderType,
+	}
+	if order.TradeType == "" {
+		order.TradeType = models.TradeTypeBuy
+	}
+
+	switch req.OrderType {
+	case models.OrderTypeStopLoss, models.OrderTypeTakeProfit:
+		if order.TradeType != models.TradeTypeSell {
+			RespondError(c, http.StatusBadRequest, req.OrderType+" orders must be sells")
+			return
+		}
+		order.TriggerPrice = req.Price
+	default:
+		order.OrderType = models.OrderTypeLimit
+		order.LimitPrice = req.Price
+	}
+
+	if orderStore != nil {
+		var err error
+		if order, err = orderStore.Insert(order); err != nil {
+			slog.Error("Failed to persist order", "user_id", order.UserID, "symbol", order.StockSymbol, "error", err)
+			RespondError(c, http.StatusInternalServerError, "Failed to place order")
+			return
+		}
+	}
+	order = Orders.AddOrder(order)
+
+	Respond(c, http.StatusAccepted, gin.H{
+		"message": "Order placed",
+		"order":   order,
+	})
+}
+
+// GetOpenOrders handles GET /api/orders/:userId
+func GetOpenOrders(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	orders := Orders.OpenOrders(userID)
+	Respond(c, http.StatusOK, gin.H{
+		"orders": orders,
+		"count":  len(orders),
+	})
+}
+
+// CancelOrder handles DELETE /api/orders/:orderId, removing a resting
+// order before it fills. The order must belong to the authenticated user;
+// another user's order gets 403. An order already matched by a price tick
+// is past cancelling and gets 404, as does one that doesn't exist. With
+// auth disabled there is no user to check, and any order can be cancelled.
+func CancelOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("orderId"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid order id")
+		return
+	}
+
+	var order models.Order
+	if userID, ok := AuthenticatedUserID(c); ok {
+		order, err = Orders.CancelUserOrder(userID, orderID)
+	} else if order, ok = Orders.CancelOrder(orderID); !ok {
+		err = errOrderNotFound
+	}
+	switch {
+	case errors.Is(err, errOrderNotOwned):
+		RespondError(c, http.StatusForbidden, "forbidden")
+		return
+	case err != nil:
+		RespondError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if orderStore != nil {
+		if err := orderStore.Close(order.ID, models.OrderStatusCancelled, 0); err != nil {
+			// Put it back rather than leave it cancelled here but open in
+			// the store, to come back on the next restart
+			Orders.AddOrder(order)
+			slog.Error("Failed to persist order cancellation", "order_id", order.ID, "error", err)
+			RespondError(c, http.StatusInternalServerError, "Failed to cancel order")
+			return
+		}
+	}
+
+	slog.Info("Order cancelled by user", "order_id", order.ID, "order_type", order.OrderType,
+		"user_id", order.UserID, "symbol", order.StockSymbol)
+	Respond(c, http.StatusOK, gin.H{
+		"message": "Order cancelled",
+		"order":   order,
+	})
+}
+
+// fillOrders executes matched orders at the crossing price; triggered
+// stops become market sells. Orders that can no longer execute (e.g. the
+// cash or shares are gone) are cancelled and logged, not recorded.
+func fillOrders(matched []models.Order, price float64) {
+	if orderExecutor == nil {
+		return
+	}
+
+	for _, o := range matched {
+		req := models.BuyRequest{
+			UserID:      o.UserID,
+			StockSymbol: o.StockSymbol,
+			Quantity:    o.Quantity,
+			Price:       price,
+			TradeType:   o.TradeType,
+		}
+		if o.OrderType != models.OrderTypeLimit {
+			req.Note = fmt.Sprintf("Triggered automatically by %s order %d at $%.2f", o.OrderType, o.ID, o.TriggerPrice)
+		}
+
+		result := orderExecutor.SubmitTrade(req)
+		if orderStore != nil {
+			status := models.OrderStatusFilled
+			if !result.Success {
+				status = models.OrderStatusCancelled
+			}
+			if err := orderStore.Close(o.ID, status, result.TradeID); err != nil {
+				slog.Error("Failed to persist order status", "order_id", o.ID, "status", status, "error", err)
+			}
+		}
+		if !result.Success {
+			slog.Info("Order cancelled", "order_id", o.ID, "order_type", o.OrderType,
+				"user_id", o.UserID, "symbol", o.StockSymbol, "error", result.Error)
+			continue
+		}
+		slog.Info("Order filled", "order_id", o.ID, "order_type", o.OrderType, "user_id", o.UserID,
+			"symbol", o.StockSymbol, "trade_type", o.TradeType, "quantity", o.Quantity,
+			"price", price, "trade_id", result.TradeID)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidSymbol is the response to a symbol that isn't 1 to 10 letters
+// and digits
+const errInvalidSymbol = "invalid symbol"
+
+// parseUserID reads the :userId path param. If it isn't an integer it
+// responds 400 "invalid user id", and if it isn't the authenticated user it
+// responds 403; either way it returns false.
+func parseUserID(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid user id")
+		return 0, false
+	}
+	if !authorizeUser(c, userID) {
+		return 0, false
+	}
+	return userID, true
+}
+
+// parseSymbol upper-cases a symbol from the path or query. If it isn't 1 to
+// 10 letters and digits it responds 400 "invalid symbol" and returns false.
+func parseSymbol(c *gin.Context, raw string) (string, bool) {
+	symbol, ok := market.CleanSymbol(raw)
+	if !ok {
+		RespondError(c, http.StatusBadRequest, errInvalidSymbol)
+		return "", false
+	}
+	return symbol, true
+}
+
+// parseTimeRange reads the optional from and to query params as RFC3339
+// times; an omitted one is returned as the zero time, meaning unbounded. If
+// either is malformed or from is after to it responds 400 and returns false.
+func parseTimeRange(c *gin.Context) (time.Time, time.Time, bool) {
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, name+" must be an RFC3339 time like 2024-01-31T00:00:00Z")
+			return time.Time{}, time.Time{}, false
+		}
+		bounds[i] = t
+	}
+
+	from, to := bounds[0], bounds[1]
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		RespondError(c, http.StatusBadRequest, "from must not be after to")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
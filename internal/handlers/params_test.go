@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestHandlers_RejectNonNumericUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tp := NewTradeProcessor(1)
+
+	router := gin.New()
+	router.GET("/api/trades/:userId", GetTradeHistory)
+	router.GET("/api/trades/:userId/summary", GetTradeSummary)
+	router.GET("/api/portfolio/:userId", GetPortfolio)
+	router.GET("/api/portfolio/:userId/exposure", GetExposure)
+	router.GET("/api/portfolio/:userId/allocation", GetAllocation)
+	router.GET("/api/portfolio/:userId/performance", GetPortfolioPerformance)
+	router.GET("/api/portfolio/:userId/holdings/:symbol", GetHolding)
+	router.GET("/api/portfolio/:userId/:symbol/breakeven", tp.GetBreakEven)
+	router.GET("/api/orders/:userId", GetOpenOrders)
+	router.GET("/api/users/:userId", GetUser)
+	router.POST("/api/users/:userId/reset", tp.ResetAccount)
+	router.GET("/api/admin/users/:userId/export", tp.ExportAccount)
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/trades/abc"},
+		{http.MethodGet, "/api/trades/abc/summary"},
+		{http.MethodGet, "/api/portfolio/abc"},
+		{http.MethodGet, "/api/portfolio/abc/exposure"},
+		{http.MethodGet, "/api/portfolio/abc/allocation"},
+		{http.MethodGet, "/api/portfolio/abc/performance"},
+		{http.MethodGet, "/api/portfolio/abc/holdings/AAPL"},
+		{http.MethodGet, "/api/portfolio/abc/AAPL/breakeven"},
+		{http.MethodGet, "/api/orders/abc"},
+		{http.MethodGet, "/api/users/1.5"},
+		{http.MethodPost, "/api/users/abc/reset"},
+		{http.MethodGet, "/api/admin/users/abc/export"},
+	}
+
+	for _, r := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(r.method, r.path, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected 400, got %d", r.method, r.path, w.Code)
+			continue
+		}
+
+		var body Envelope
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body.Error == nil || *body.Error != "invalid user id" {
+			t.Errorf("%s %s: expected 'invalid user id', got %s", r.method, r.path, w.Body.String())
+		}
+	}
+}
+
+func TestGetTradeHistory_RejectsBadTimeRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/trades/:userId", GetTradeHistory)
+
+	tests := map[string]string{
+		"?from=yesterday": "from must be an RFC3339 time like 2024-01-31T00:00:00Z",
+		"?to=2024-01-31":  "to must be an RFC3339 time like 2024-01-31T00:00:00Z",
+		"?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z": "from must not be after to",
+	}
+
+	for query, want := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/trades/1"+query, nil))
+
+		var body Envelope
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusBadRequest || body.Error == nil || *body.Error != want {
+			t.Errorf("%q: expected 400 %q, got %d %s", query, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandlers_RejectInvalidSymbols(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tp := NewTradeProcessor(1)
+
+	router := gin.New()
+	router.GET("/api/trades/:userId", GetTradeHistory)
+	router.GET("/api/portfolio/:userId/lots", GetLots)
+	router.GET("/api/portfolio/:userId/:symbol/breakeven", tp.GetBreakEven)
+	router.POST("/api/trades/impact", tp.PreviewTradeImpact)
+	router.POST("/api/trades/batch", tp.Batch)
+
+	long := strings.Repeat("A", 11)
+	paths := []string{
+		"/api/trades/1?symbol=" + long,
+		"/api/trades/1?symbol=AA%3BPL",
+		"/api/portfolio/1/lots?symbol=%C3%84APL",
+		"/api/portfolio/1/" + long + "/breakeven",
+	}
+	for _, path := range paths {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var body Envelope
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusBadRequest || body.Error == nil || *body.Error != errInvalidSymbol {
+			t.Errorf("%s: expected 400 %q, got %d %s", path, errInvalidSymbol, w.Code, w.Body.String())
+		}
+	}
+
+	// Bodies are checked when bound, before the database is touched
+	for _, symbol := range []string{long, strings.Repeat("A", 10000), "AA PL", "ÄAPL", "<b>"} {
+		body := fmt.Sprintf(`{"user_id":1,"stock_symbol":%q,"trade_type":"BUY","quantity":1,"price":100}`, symbol)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/trades/impact", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Impact of %.20q: expected 400, got %d", symbol, w.Code)
+		}
+	}
+
+	var req models.BuyRequest
+	for _, symbol := range []string{long, "AA-PL"} {
+		req = models.BuyRequest{UserID: 1, StockSymbol: symbol, Quantity: 1, Price: 100.0}
+		if err := binding.Validator.ValidateStruct(&req); err == nil {
+			t.Errorf("Expected buy of %q to fail validation", symbol)
+		}
+	}
+	req = models.BuyRequest{UserID: 1, StockSymbol: "aapl", Quantity: 1, Price: 100.0}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		t.Errorf("Expected lower-case symbols to bind, got %v", err)
+	}
+}
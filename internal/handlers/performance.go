@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetPortfolioPerformance handles GET /api/portfolio/:userId/performance
+func GetPortfolioPerformance(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var cashBalance float64
+	var currency string
+	err := db.DB.QueryRow("SELECT cash_balance, currency FROM users WHERE id = $1", userID).Scan(&cashBalance, &currency)
+	if err == sql.ErrNoRows {
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	rows, err := db.DB.Query(`
+        SELECT stock_symbol, quantity, avg_purchase_price
+        FROM portfolios
+        WHERE user_id = $1 AND quantity <> 0
+    `, userID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	defer rows.Close()
+
+	positions := make([]models.Portfolio, 0)
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice); err != nil {
+			continue
+		}
+		positions = append(positions, p)
+	}
+
+	// Every position is valued at the same instant
+	valued := valueHoldings(positions, Prices)
+	holdings := make([]models.Holding, 0, len(positions))
+	for _, p := range positions {
+		holdings = append(holdings, valued[holdingKey{UserID: p.UserID, Symbol: p.StockSymbol}])
+	}
+
+	// Sells, and buys covering a short, realize P&L and close shares at a
+	// basis. Both are read from the running totals, which outlive the
+	// trades pruned from the trades table.
+	var realizedPL, soldBasis float64
+	err = db.DB.QueryRow(`
+        SELECT COALESCE(SUM(realized_pl), 0), COALESCE(SUM(closed_basis), 0)
+        FROM trade_stats
+        WHERE user_id = $1
+    `, userID).Scan(&realizedPL, &soldBasis)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch trades")
+		return
+	}
+
+	performance := computePerformance(cashBalance, holdings, realizedPL, soldBasis)
+	performance.Currency = currency
+	Respond(c, http.StatusOK, performance)
+}
+
+// computePerformance summarizes an account. Percent return is total P&L
+// over the capital put into positions, held and closed; with nothing
+// invested it is 0. Shorts count toward that capital at the value they
+// were sold for, and reduce the cost basis and market value.
+func computePerformance(cash float64, holdings []models.Holding, realizedPL, soldBasis float64) models.PerformanceResponse {
+	perf := models.PerformanceResponse{
+		CashBalance: cash,
+		RealizedPL:  realizedPL,
+	}
+
+	var exposure float64
+	for _, h := range holdings {
+		basis := h.AvgPurchasePrice * float64(h.Quantity)
+		perf.MarketValue += h.CurrentValue
+		perf.CostBasis += basis
+		perf.UnrealizedPL += h.UnrealizedPL
+		exposure += math.Abs(basis)
+	}
+	perf.TotalValue = cash + perf.MarketValue
+
+	if invested := exposure + soldBasis; invested > 0 {
+		perf.PercentReturn = (perf.UnrealizedPL + perf.RealizedPL) / invested * 100
+	}
+
+	return perf
+}
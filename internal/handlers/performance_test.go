@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestComputePerformance_NoHoldings(t *testing.T) {
+	perf := computePerformance(10000.0, nil, 0, 0)
+
+	if perf.TotalValue != 10000.0 || perf.MarketValue != 0 || perf.PercentReturn != 0 {
+		t.Errorf("Expected an all-cash account with 0%% return, got %+v", perf)
+	}
+}
+
+func TestComputePerformance(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 110.0})
+
+	holdings := []models.Holding{
+		valueHolding(models.Portfolio{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 100.0}, prices),
+	}
+
+	// Earlier sold shares that cost 1000 for a 100 gain
+	perf := computePerformance(5000.0, holdings, 100.0, 1000.0)
+
+	if perf.MarketValue != 1100.0 || perf.CostBasis != 1000.0 || perf.UnrealizedPL != 100.0 {
+		t.Errorf("Unexpected holdings totals: %+v", perf)
+	}
+	if perf.TotalValue != 6100.0 {
+		t.Errorf("Expected total value 6100, got %.2f", perf.TotalValue)
+	}
+	if !approxEqual(perf.PercentReturn, 10.0) {
+		t.Errorf("Expected 200 P&L on 2000 invested = 10%%, got %.4f", perf.PercentReturn)
+	}
+}
+
+func TestGetPortfolioPerformance(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "performer", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "GOOGL", Quantity: 10, Price: 100.0})
+	if result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "GOOGL", Quantity: 4, Price: 125.0}); !result.Success {
+		t.Fatalf("Expected sell to succeed, got error: %s", result.Error)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/portfolio/:userId/performance", GetPortfolioPerformance)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/portfolio/%d/performance", userID), nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data models.PerformanceResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	if body.Data.RealizedPL != 100.0 {
+		t.Errorf("Expected realized P&L 4 x 25 = 100, got %.2f", body.Data.RealizedPL)
+	}
+	if body.Data.CostBasis != 600.0 {
+		t.Errorf("Expected remaining cost basis 600, got %.2f", body.Data.CostBasis)
+	}
+}
+
+func TestGetPortfolioPerformance_SurvivesPruning(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "long_history", 10000.0)
+
+	// 20 sells of shares bought at 100: the 5 oldest lost 50 each and the
+	// 15 newest gained 10 each. Only those 15 are kept in trades.
+	for i := 1; i <= 20; i++ {
+		total, realized := 110.0, 10.0
+		if i <= 5 {
+			total, realized = 50.0, -50.0
+		}
+		_, err := database.Exec(`
+            INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, realized_pl, seq)
+            VALUES ($1, 'AAPL', 'SELL', 1, $2, $2, 0, $3, $4)
+        `, userID, total, realized, i)
+		if err != nil {
+			t.Fatalf("Failed to insert trade %d: %v", i, err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/portfolio/:userId/performance", GetPortfolioPerformance)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/portfolio/%d/performance", userID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data models.PerformanceResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	if body.Data.RealizedPL != -100.0 {
+		t.Errorf("Expected realized P&L -250 + 150 = -100, got %.2f", body.Data.RealizedPL)
+	}
+	if !approxEqual(body.Data.PercentReturn, -5.0) {
+		t.Errorf("Expected -100 over 2000 invested = -5%%, got %.2f%%", body.Data.PercentReturn)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// PortfolioValueUpdate is a user's account valued at the latest prices,
+// pushed whenever one of their holdings ticks
+type PortfolioValueUpdate struct {
+	UserID        int              `json:"user_id"`
+	CashBalance   float64          `json:"cash_balance"`
+	HoldingsValue float64          `json:"holdings_value"`
+	TotalValue    float64          `json:"total_value"`
+	UnrealizedPL  float64          `json:"unrealized_pl"`
+	Holdings      []models.Holding `json:"holdings"`
+	Timestamp     time.Time        `json:"timestamp"`
+}
+
+// portfolioLoader reads a user's cash balance and open positions, or
+// returns sql.ErrNoRows if the user doesn't exist
+type portfolioLoader func(userID int) (float64, []models.Portfolio, error)
+
+// HandlePortfolioWebSocket handles GET /ws/portfolio/:userId. Holdings are
+// read once on connect and revalued from the hub's quotes on every tick of
+// a held symbol, so the stream doesn't query the database per tick.
+// Reconnect to pick up trades made since.
+func HandlePortfolioWebSocket(hub *Hub) gin.HandlerFunc {
+	return portfolioStream(hub, loadPortfolio)
+}
+
+// portfolioStream is HandlePortfolioWebSocket with the holdings source
+// swappable for tests
+func portfolioStream(hub *Hub, load portfolioLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		cash, positions, err := load(userID)
+		if err == sql.ErrNoRows {
+			RespondError(c, http.StatusNotFound, ErrUserNotFound)
+			return
+		}
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
+			return
+		}
+
+		conn, client, closeClient := openClient(c, hub)
+		if conn == nil {
+			return
+		}
+		defer closeClient()
+
+		held := make(map[string]bool, len(positions))
+		for _, p := range positions {
+			held[p.StockSymbol] = true
+		}
+
+		go hub.watchClient(conn, client)
+
+		if err := hub.writeJSON(conn, valuePortfolio(userID, cash, positions, Prices, hub.clock.Now())); err != nil {
+			return
+		}
+
+		err = hub.relayUpdates(conn, client, func(update PriceUpdate) (interface{}, bool) {
+			if !held[update.Symbol] {
+				return nil, false
+			}
+			// The hub records each quote before fanning it out
+			return valuePortfolio(userID, cash, positions, Prices, update.Timestamp), true
+		})
+		if err != nil {
+			slog.Debug("WebSocket write failed", "user_id", userID, "error", err)
+		}
+	}
+}
+
+// valuePortfolio values positions at one snapshot of the latest prices
+func valuePortfolio(userID int, cash float64, positions []models.Portfolio, prices *market.PriceStore, at time.Time) PortfolioValueUpdate {
+	update := PortfolioValueUpdate{
+		UserID:      userID,
+		CashBalance: cash,
+		Holdings:    make([]models.Holding, 0, len(positions)),
+		Timestamp:   at,
+	}
+
+	valued := valueHoldings(positions, prices)
+	for _, p := range positions {
+		h := valued[holdingKey{UserID: p.UserID, Symbol: p.StockSymbol}]
+		update.Holdings = append(update.Holdings, h)
+		update.HoldingsValue += h.CurrentValue
+		update.UnrealizedPL += h.UnrealizedPL
+	}
+	update.TotalValue = cash + update.HoldingsValue
+	return update
+}
+
+// loadPortfolio reads a user's cash balance and open positions
+func loadPortfolio(userID int) (float64, []models.Portfolio, error) {
+	var cash float64
+	err := db.DB.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&cash)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := db.DB.Query(`
+        SELECT id, user_id, stock_symbol, quantity, avg_purchase_price, updated_at
+        FROM portfolios
+        WHERE user_id = $1 AND quantity <> 0
+        ORDER BY stock_symbol
+    `, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	positions := make([]models.Portfolio, 0)
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.ID, &p.UserID, &p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice, &p.UpdatedAt); err != nil {
+			return 0, nil, err
+		}
+		positions = append(positions, p)
+	}
+	return cash, positions, rows.Err()
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter is a per-key token bucket. Each key earns rate tokens a
+// second up to burst, and each request spends one.
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	clock     clock.Clock
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows perSecond requests a second per key, with bursts
+// up to burst
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:    perSecond,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		clock:   clock.Real(),
+	}
+}
+
+// Middleware rejects requests over the limit with 429 and a Retry-After
+// header. Requests are keyed by authenticated user, or client IP when
+// auth is off.
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, ok := AuthenticatedUserID(c); ok {
+			key = "user:" + strconv.Itoa(userID)
+		}
+
+		if ok, retryAfter := rl.allow(key); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			RespondError(c, http.StatusTooManyRequests, "Too many trades, slow down")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allow spends a token for key, or reports how long until one is available
+func (rl *RateLimiter) allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.clock.Now()
+	rl.sweep(now)
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens = math.Min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops buckets that have refilled completely, since a fresh bucket
+// behaves the same. It runs at most once per refill period so one-off
+// clients don't accumulate.
+func (rl *RateLimiter) sweep(now time.Time) {
+	refill := time.Duration(rl.burst / rl.rate * float64(time.Second))
+	if now.Sub(rl.lastSweep) < refill {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(rl.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ResetAccount handles POST /api/users/:userId/reset
+func (tp *TradeProcessor) ResetAccount(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	err := tp.ResetUser(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to reset account")
+		return
+	}
+
+	Respond(c, http.StatusOK, gin.H{
+		"message": "Account reset successfully",
+		"user_id": userID,
+	})
+}
+
+// ResetUser clears a user's holdings, trade history and orders and
+// restores Config.StartingBalance, all in one transaction under the user's
+// lock. Orders resting in the book are cancelled too, so none fills
+// against the fresh account; if the reset fails they're put back. Returns
+// sql.ErrNoRows for an unknown user.
+func (tp *TradeProcessor) ResetUser(userID int) (err error) {
+	tp.portfolioMgr.LockUser(userID)
+	defer tp.portfolioMgr.UnlockUser(userID)
+
+	cancelled := Orders.CancelUserOrders(userID)
+	defer func() {
+		if err != nil {
+			for _, o := range cancelled {
+				Orders.AddOrder(o)
+			}
+		}
+	}()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the user row first so the reset can't interleave with another
+	// instance's trade
+	result, err := tx.Exec("UPDATE users SET cash_balance = $2 WHERE id = $1", userID, tp.Config.StartingBalance)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err = tx.Exec("DELETE FROM portfolio_lots WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM portfolios WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM trades WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM trade_stats WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	// Filled orders point at the trades just deleted, so they all go
+	if _, err = tx.Exec("DELETE FROM orders WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	// Deposits before this no longer count toward the account's basis
+	_, err = tx.Exec(`
+        INSERT INTO cash_transactions (user_id, type, amount, balance_after)
+        VALUES ($1, $2, $3, $3)
+    `, userID, models.CashReset, tp.Config.StartingBalance)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	slog.Info("Account reset", "user_id", userID, "orders_cancelled", len(cancelled))
+	return nil
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestResetUser(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "resetme", 10000.0)
+	otherID := db.CreateTestUser(t, database, "bystander", 10000.0)
+
+	tp := NewTradeProcessor(2)
+	tp.Start()
+	defer tp.Stop()
+
+	for _, uid := range []int{userID, otherID} {
+		result := tp.SubmitTrade(models.BuyRequest{UserID: uid, StockSymbol: "AAPL", Quantity: 10, Price: 150.0})
+		if !result.Success {
+			t.Fatalf("Failed to setup trade: %s", result.Error)
+		}
+	}
+
+	if err := tp.ResetUser(userID); err != nil {
+		t.Fatalf("Expected reset to succeed, got: %v", err)
+	}
+
+	var balance float64
+	var holdings, trades int
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	database.QueryRow("SELECT COUNT(*) FROM portfolios WHERE user_id = $1", userID).Scan(&holdings)
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&trades)
+
+	if balance != 10000.0 || holdings != 0 || trades != 0 {
+		t.Errorf("Expected fresh account, got balance %.2f, %d holdings, %d trades", balance, holdings, trades)
+	}
+	if summary, _ := loadTradeSummary(database, userID); summary != (models.TradeSummary{}) {
+		t.Errorf("Expected the trade summary cleared, got %+v", summary)
+	}
+
+	// The other user is untouched
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", otherID).Scan(&balance)
+	database.QueryRow("SELECT COUNT(*) FROM portfolios WHERE user_id = $1", otherID).Scan(&holdings)
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", otherID).Scan(&trades)
+
+	if balance != 8500.0 || holdings != 1 || trades != 1 {
+		t.Errorf("Expected other user unchanged, got balance %.2f, %d holdings, %d trades", balance, holdings, trades)
+	}
+
+	if err := tp.ResetUser(99999); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows for unknown user, got: %v", err)
+	}
+}
+
+func TestResetUser_CancelsOrders(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "resting_orders", 10000.0)
+	otherID := db.CreateTestUser(t, database, "other_orders", 10000.0)
+
+	savedBook, savedStore := Orders, orderStore
+	Orders = NewOrderBook()
+	orderStore = NewDBOrderStore(database)
+	defer func() { Orders, orderStore = savedBook, savedStore }()
+
+	for _, uid := range []int{userID, otherID} {
+		order, err := orderStore.Insert(models.Order{
+			UserID: uid, StockSymbol: "AAPL", Quantity: 10,
+			TradeType: models.TradeTypeBuy, OrderType: models.OrderTypeLimit, LimitPrice: 100.0,
+		})
+		if err != nil {
+			t.Fatalf("Failed to setup order: %v", err)
+		}
+		Orders.AddOrder(order)
+	}
+
+	tp := NewTradeProcessor(1)
+	if err := tp.ResetUser(userID); err != nil {
+		t.Fatalf("Expected reset to succeed, got: %v", err)
+	}
+
+	if open := Orders.OpenOrders(userID); len(open) != 0 {
+		t.Errorf("Expected the reset user's orders gone from the book, got %d", len(open))
+	}
+	if open := Orders.OpenOrders(otherID); len(open) != 1 {
+		t.Errorf("Expected the other user's order to keep resting, got %d", len(open))
+	}
+
+	var stored int
+	database.QueryRow("SELECT COUNT(*) FROM orders WHERE user_id = $1", userID).Scan(&stored)
+	if stored != 0 {
+		t.Errorf("Expected the reset user's orders deleted, got %d rows", stored)
+	}
+}
+
+func TestResetUser_RestartsNetDeposits(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "redeposited", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	netDeposits := func() float64 {
+		t.Helper()
+		accounts, err := loadLeaderboardAccounts()
+		if err != nil {
+			t.Fatalf("Failed to load accounts: %v", err)
+		}
+		for _, a := range accounts {
+			if a.UserID == userID {
+				return a.NetDeposits
+			}
+		}
+		t.Fatalf("User %d missing from the leaderboard", userID)
+		return 0
+	}
+
+	if _, err := tp.MoveCash(userID, models.CashDeposit, 5000.0); err != nil {
+		t.Fatalf("Failed to deposit: %v", err)
+	}
+	if err := tp.ResetUser(userID); err != nil {
+		t.Fatalf("Expected reset to succeed, got: %v", err)
+	}
+	if n := netDeposits(); n != 0 {
+		t.Errorf("Expected deposits before the reset not to count, got %.2f", n)
+	}
+
+	var recorded float64
+	database.QueryRow("SELECT amount FROM cash_transactions WHERE user_id = $1 AND type = 'RESET'", userID).Scan(&recorded)
+	if recorded != tp.Config.StartingBalance {
+		t.Errorf("Expected the reset recorded at %.2f, got %.2f", tp.Config.StartingBalance, recorded)
+	}
+
+	if _, err := tp.MoveCash(userID, models.CashDeposit, 1000.0); err != nil {
+		t.Fatalf("Failed to deposit: %v", err)
+	}
+	if n := netDeposits(); n != 1000.0 {
+		t.Errorf("Expected only the deposit since the reset, got %.2f", n)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import "github.com/gin-gonic/gin"
+
+// LegacyResponses makes every endpoint emit its old bare response body
+// instead of the envelope, for clients that haven't migrated yet
+var LegacyResponses = false
+
+// Meta carries response metadata alongside the payload
+type Meta struct {
+	RequestID  string      `json:"request_id,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"` // Set by list endpoints
+}
+
+// Pagination describes the page of results a list endpoint returned
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	Count      int    `json:"count"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page
+}
+
+// Envelope is the response shape shared by all endpoints
+type Envelope struct {
+	Data  interface{} `json:"data"`
+	Meta  Meta        `json:"meta"`
+	Error *string     `json:"error"`
+}
+
+// Respond writes a successful response
+func Respond(c *gin.Context, status int, data interface{}) {
+	RespondWithMeta(c, status, data, Meta{})
+}
+
+// RespondWithMeta writes a successful response with extra metadata
+func RespondWithMeta(c *gin.Context, status int, data interface{}, meta Meta) {
+	if LegacyResponses {
+		c.JSON(status, data)
+		return
+	}
+
+	meta.RequestID = requestID(c)
+	c.JSON(status, Envelope{Data: data, Meta: meta})
+}
+
+// RespondError writes an error response
+func RespondError(c *gin.Context, status int, message string) {
+	if LegacyResponses {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(status, Envelope{
+		Meta:  Meta{RequestID: requestID(c)},
+		Error: &message,
+	})
+}
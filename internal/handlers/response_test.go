@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestContext returns a gin context backed by a response recorder
+func newTestContext() (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	return c, w
+}
+
+func TestRespond_EnvelopeShape(t *testing.T) {
+	c, w := newTestContext()
+	c.Request.Header.Set("X-Request-ID", "req-123")
+
+	Respond(c, http.StatusOK, gin.H{"status": "healthy"})
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	for _, key := range []string{"data", "meta", "error"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("Expected envelope key %q in %s", key, w.Body.String())
+		}
+	}
+
+	if body["error"] != nil {
+		t.Errorf("Expected null error on success, got %v", body["error"])
+	}
+
+	data := body["data"].(map[string]interface{})
+	if data["status"] != "healthy" {
+		t.Errorf("Expected data.status 'healthy', got %v", data["status"])
+	}
+
+	meta := body["meta"].(map[string]interface{})
+	if meta["request_id"] != "req-123" {
+		t.Errorf("Expected meta.request_id 'req-123', got %v", meta["request_id"])
+	}
+}
+
+func TestRespondError_EnvelopeShape(t *testing.T) {
+	c, w := newTestContext()
+
+	RespondError(c, http.StatusNotFound, "User not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if body["error"] != "User not found" {
+		t.Errorf("Expected error 'User not found', got %v", body["error"])
+	}
+
+	if body["data"] != nil {
+		t.Errorf("Expected null data on error, got %v", body["data"])
+	}
+
+	if _, ok := body["meta"]; !ok {
+		t.Error("Expected meta in error envelope")
+	}
+}
+
+func TestRespond_LegacyShape(t *testing.T) {
+	LegacyResponses = true
+	defer func() { LegacyResponses = false }()
+
+	c, w := newTestContext()
+	Respond(c, http.StatusOK, gin.H{"status": "healthy"})
+
+	if w.Body.String() != `{"status":"healthy"}` {
+		t.Errorf("Expected bare legacy body, got %s", w.Body.String())
+	}
+
+	c, w = newTestContext()
+	RespondError(c, http.StatusBadRequest, "Insufficient funds")
+
+	if w.Body.String() != `{"error":"Insufficient funds"}` {
+		t.Errorf("Expected bare legacy error, got %s", w.Body.String())
+	}
+}
+
+func TestRequestID_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/ping", func(c *gin.Context) {
+		Respond(c, http.StatusOK, gin.H{"request_id": RequestIDFromContext(c.Request.Context())})
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"reuses incoming", "trace-42", true},
+		{"generates when absent", "", false},
+		{"replaces unsafe", "bad id\nwith newline", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if tt.incoming != "" {
+			req.Header.Set(RequestIDHeader, tt.incoming)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		header := w.Header().Get(RequestIDHeader)
+		if tt.reuse && header != tt.incoming {
+			t.Errorf("%s: expected header %q, got %q", tt.name, tt.incoming, header)
+		}
+		if !tt.reuse && (header == "" || header == tt.incoming) {
+			t.Errorf("%s: expected a generated ID, got %q", tt.name, header)
+		}
+
+		var body struct {
+			Data struct {
+				RequestID string `json:"request_id"`
+			} `json:"data"`
+			Meta Meta `json:"meta"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body.Data.RequestID != header || body.Meta.RequestID != header {
+			t.Errorf("%s: context %q and meta %q should match header %q",
+				tt.name, body.Data.RequestID, body.Meta.RequestID, header)
+		}
+	}
+}
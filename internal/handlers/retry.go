@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Transaction retry policy for transient database errors
+const (
+	maxTxAttempts  = 4
+	retryBaseDelay = 20 * time.Millisecond
+)
+
+// retrySleep is swapped out in tests
+var retrySleep = time.Sleep
+
+// withRetry runs fn, re-running it with exponential backoff while it fails
+// with a retryable database error. Results without an error (successes and
+// business rejections like insufficient funds) are returned as is.
+func withRetry(userID int, fn func() (TradeResult, error)) TradeResult {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || !isRetryable(err) {
+			return result
+		}
+
+		if attempt >= maxTxAttempts {
+			slog.Error("Giving up on trade", "user_id", userID, "attempts", attempt, "error", err)
+			if result.Error == ErrDatabaseUnavailable {
+				return result
+			}
+			return TradeResult{
+				Success: false,
+				Kind:    KindUnavailable,
+				Error:   fmt.Sprintf("Trade failed after %d attempts, try again", attempt),
+			}
+		}
+
+		slog.Warn("Retrying trade", "user_id", userID, "attempt", attempt, "delay", delay, "error", err)
+		retrySleep(delay)
+		delay *= 2
+	}
+}
+
+// isRetryable reports whether err is a transient database error: a
+// serialization failure (40001), a deadlock (40P01), a lost connection, or
+// a holding that changed under an optimistic trade
+func isRetryable(err error) bool {
+	if errors.Is(err, errVersionConflict) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01" || pqErr.Code.Class() == "08"
+	}
+
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
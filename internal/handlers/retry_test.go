@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/lib/pq"
+)
+
+// noRetrySleep disables backoff delays for the duration of a test
+func noRetrySleep(t *testing.T) {
+	retrySleep = func(time.Duration) {}
+	t.Cleanup(func() { retrySleep = time.Sleep })
+}
+
+func TestWithRetry_RetriesSerializationFailure(t *testing.T) {
+	noRetrySleep(t)
+
+	attempts := 0
+	result := withRetry(1, func() (TradeResult, error) {
+		attempts++
+		if attempts < 3 {
+			return TradeResult{Success: false, Error: "Transaction commit failed"}, &pq.Error{Code: "40001"}
+		}
+		return TradeResult{Success: true, TradeID: 42}, nil
+	})
+
+	if !result.Success || result.TradeID != 42 {
+		t.Errorf("Expected success after retries, got %+v", result)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestWithRetry_DoesNotRetryBusinessErrors(t *testing.T) {
+	noRetrySleep(t)
+
+	attempts := 0
+	result := withRetry(1, func() (TradeResult, error) {
+		attempts++
+		return TradeResult{Success: false, Kind: KindInsufficientFunds, Error: ErrInsufficientFunds}, nil
+	})
+
+	if attempts != 1 || result.Kind != KindInsufficientFunds {
+		t.Errorf("Expected a single attempt returning the rejection, got %d attempts: %+v", attempts, result)
+	}
+
+	attempts = 0
+	withRetry(1, func() (TradeResult, error) {
+		attempts++
+		return TradeResult{Success: false, Error: "Database error"}, errors.New("syntax error")
+	})
+	if attempts != 1 {
+		t.Errorf("Expected non-transient database errors not to retry, got %d attempts", attempts)
+	}
+}
+
+func TestWithRetry_GivesUp(t *testing.T) {
+	noRetrySleep(t)
+
+	attempts := 0
+	result := withRetry(1, func() (TradeResult, error) {
+		attempts++
+		return TradeResult{Success: false, Error: "Database error"}, &pq.Error{Code: "40P01"}
+	})
+
+	if attempts != maxTxAttempts {
+		t.Errorf("Expected %d attempts, got %d", maxTxAttempts, attempts)
+	}
+	if result.Success || result.Error != "Trade failed after 4 attempts, try again" {
+		t.Errorf("Expected a clear give-up error, got %+v", result)
+	}
+}
+
+func TestOptimisticLocking_RetriesConcurrentUpdate(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+	noRetrySleep(t)
+
+	userID := db.CreateTestUser(t, database, "optimist", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.OptimisticLocking = true
+	tp.Config.TradeIsolation = sql.LevelReadCommitted
+	tp.Start()
+	defer tp.Stop()
+
+	if result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 150.0}); !result.Success {
+		t.Fatalf("Setup buy failed: %s", result.Error)
+	}
+
+	// Another instance adds 5 shares between the sell reading the holding
+	// and writing it back
+	reads := 0
+	positionReadHook = func(int, string) {
+		reads++
+		if reads == 1 {
+			if _, err := database.Exec(
+				"UPDATE portfolios SET quantity = quantity + 5, version = version + 1 WHERE user_id = $1 AND stock_symbol = 'AAPL'",
+				userID,
+			); err != nil {
+				t.Errorf("Concurrent update failed: %v", err)
+			}
+		}
+	}
+	defer func() { positionReadHook = func(int, string) {} }()
+
+	result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 8, Price: 150.0})
+	if !result.Success {
+		t.Fatalf("Expected the sell to succeed on retry, got: %s", result.Error)
+	}
+	if reads != 2 {
+		t.Errorf("Expected the holding read twice, got %d", reads)
+	}
+
+	// Without the version check the sell would write back 10 - 8 and lose
+	// the concurrent 5
+	var quantity, version int
+	database.QueryRow("SELECT quantity, version FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'",
+		userID).Scan(&quantity, &version)
+	if quantity != 7 {
+		t.Errorf("Expected 7 shares left, got %d", quantity)
+	}
+	if version != 3 {
+		t.Errorf("Expected version 3 after the concurrent update and the sell, got %d", version)
+	}
+}
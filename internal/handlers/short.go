@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+// shortMarginOK reports whether cash covers a short exposure plus the
+// required margin on top of it
+func shortMarginOK(cash, exposure, marginPercent float64) bool {
+	return cash >= exposure*(1+marginPercent/100)
+}
+
+// executeShortSell opens or adds to a short position inside tx: the
+// proceeds are credited now, and the shares are owed until bought back.
+// held, currentQuantity and version describe the position row read, which
+// is either absent or already short. Nothing is realized until the short is
+// covered, so the trade has no realized P&L.
+func executeShortSell(tx *sql.Tx, cfg config.Config, req models.BuyRequest, seq int64, held bool, currentQuantity int, avgPrice float64, version int) (TradeResult, error) {
+	proceeds := req.Price * float64(req.Quantity)
+	fee := cfg.Commission.Fee(req.Quantity, req.Price)
+
+	var cash float64
+	var createdAt, now time.Time
+	err := tx.QueryRow(
+		"SELECT cash_balance, created_at, LOCALTIMESTAMP FROM users WHERE id = $1 FOR UPDATE",
+		req.UserID,
+	).Scan(&cash, &createdAt, &now)
+	if err == sql.ErrNoRows {
+		return TradeResult{Success: false, Kind: KindNotFound, Error: ErrUserNotFound}, nil
+	}
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+	}
+
+	// New accounts may not short what they may not buy
+	if msg := accountAgeError(cfg, createdAt, now, req.StockSymbol, proceeds); msg != "" {
+		return TradeResult{Success: false, Kind: KindBadRequest, Error: msg}, nil
+	}
+
+	// 1. Margin: the user's other shorts, valued at the prices they were
+	// sold at, plus this position once the sell is added to it
+	var exposure float64
+	err = tx.QueryRow(`
+        SELECT COALESCE(SUM(-quantity * avg_purchase_price), 0)
+        FROM portfolios
+        WHERE user_id = $1 AND quantity < 0 AND stock_symbol <> $2
+    `, req.UserID, req.StockSymbol).Scan(&exposure)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+	}
+
+	newQuantity := currentQuantity - req.Quantity
+	newAvg := (avgPrice*float64(-currentQuantity) + proceeds) / float64(-newQuantity)
+	exposure += newAvg * float64(-newQuantity)
+
+	if !shortMarginOK(cash+proceeds-fee, exposure, cfg.ShortMarginPercent) {
+		return TradeResult{Success: false, Kind: KindInsufficientMargin, Error: ErrInsufficientMargin}, nil
+	}
+
+	// Position limits cap a short's size the way they cap a long's
+	limit := cfg.PositionLimitFor(req.StockSymbol)
+	if !limit.Allows(-newQuantity, 0, 0) {
+		return TradeResult{Success: false, Kind: KindPositionLimit, Error: ErrPositionLimit}, nil
+	}
+	if limit.MaxPercent > 0 {
+		account, err := loadMarginAccount(tx, req.UserID, cash)
+		if err != nil {
+			return TradeResult{Success: false, Kind: KindInternal, Error: "Database error"}, err
+		}
+		after := account.afterShortSell(req.StockSymbol, req.Quantity, req.Price, proceeds, fee)
+		holding := valueHolding(models.Portfolio{
+			StockSymbol:      req.StockSymbol,
+			Quantity:         newQuantity,
+			AvgPurchasePrice: newAvg,
+		}, Prices)
+		if !limit.Allows(-newQuantity, math.Abs(holding.CurrentValue), after.equity()) {
+			return TradeResult{Success: false, Kind: KindPositionLimit, Error: ErrPositionLimit}, nil
+		}
+	}
+
+	// 2. Record the short
+	if held {
+		err = execVersioned(tx,
+			"UPDATE portfolios SET quantity = $1, avg_purchase_price = $2, version = version + 1, updated_at = NOW() WHERE user_id = $3 AND stock_symbol = $4 AND version = $5",
+			newQuantity, newAvg, req.UserID, req.StockSymbol, version,
+		)
+	} else {
+		err = upsertPortfolio(tx, req.UserID, req.StockSymbol, -req.Quantity, req.Price, version)
+	}
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update portfolio"}, err
+	}
+
+	// 3. Credit the proceeds, less commission
+	err = execOne(tx,
+		"UPDATE users SET cash_balance = cash_balance + $1 WHERE id = $2",
+		proceeds-fee, req.UserID,
+	)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update balance"}, err
+	}
+
+	// 4. Record trade
+	var tradeID int
+	err = tx.QueryRow(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, seq, note, request_id, quoted_price)
+        VALUES ($1, $2, 'SELL', $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10::numeric, 0))
+        RETURNING id
+    `, req.UserID, req.StockSymbol, req.Quantity, req.Price, proceeds, fee, seq, req.Note, req.RequestID, req.QuotedPrice).Scan(&tradeID)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to record trade"}, err
+	}
+
+	return TradeResult{
+		TradeID:     tradeID,
+		Success:     true,
+		Quantity:    req.Quantity,
+		Price:       req.Price,
+		QuotedPrice: req.QuotedPrice,
+		TotalAmount: proceeds,
+		Fee:         fee,
+		Seq:         seq,
+	}, nil
+}
+
+// sellThroughZero sells more shares than are held long inside tx: it sells
+// the whole long position, then shorts the remainder. Each leg is its own
+// trade with its own commission, recorded under the same seq, and the
+// result adds them up.
+func sellThroughZero(tx *sql.Tx, cfg config.Config, req models.BuyRequest, seq int64, longQuantity int) (TradeResult, error) {
+	closing := req
+	closing.Quantity = longQuantity
+	closed, err := executeSell(tx, cfg, closing, seq)
+	if !closed.Success {
+		return closed, err
+	}
+
+	// The long row is gone, so this sell opens a fresh short
+	opening := req
+	opening.Quantity = req.Quantity - longQuantity
+	opened, err := executeSell(tx, cfg, opening, seq)
+	if !opened.Success {
+		return opened, err
+	}
+
+	opened.Quantity = req.Quantity
+	opened.TotalAmount += closed.TotalAmount
+	opened.Fee += closed.Fee
+	opened.PositionQuantity = -opening.Quantity
+	opened.PositionAvgPrice = req.Price
+	return opened, nil
+}
+
+// coverShort buys back shares of a short position inside tx, realizing
+// the difference between the price they were sold at and the price now.
+// A buy can't cover more than the short: turning it into a long position
+// takes a second buy.
+func coverShort(tx *sql.Tx, cfg config.Config, req models.BuyRequest, seq int64, cash float64, currentQuantity int, avgPrice float64, version int) (TradeResult, error) {
+	if req.Quantity > -currentQuantity {
+		return TradeResult{
+			Success: false,
+			Kind:    KindBadRequest,
+			Error:   fmt.Sprintf("You are short %d shares; buy at most that many to cover", -currentQuantity),
+		}, nil
+	}
+
+	totalCost := req.Price * float64(req.Quantity)
+	fee := cfg.Commission.Fee(req.Quantity, req.Price)
+	if cash < totalCost+fee {
+		return TradeResult{Success: false, Kind: KindInsufficientFunds, Error: ErrInsufficientFunds}, nil
+	}
+
+	// 1. Pay for the shares, commission included
+	err := execOne(tx,
+		"UPDATE users SET cash_balance = cash_balance - $1 WHERE id = $2",
+		totalCost+fee, req.UserID,
+	)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update balance"}, err
+	}
+
+	// 2. Shrink or close the short; its average price doesn't change
+	newQuantity := currentQuantity + req.Quantity
+	if newQuantity == 0 {
+		err = execVersioned(tx,
+			"DELETE FROM portfolios WHERE user_id = $1 AND stock_symbol = $2 AND version = $3",
+			req.UserID, req.StockSymbol, version,
+		)
+	} else {
+		err = execVersioned(tx,
+			"UPDATE portfolios SET quantity = $1, version = version + 1, updated_at = NOW() WHERE user_id = $2 AND stock_symbol = $3 AND version = $4",
+			newQuantity, req.UserID, req.StockSymbol, version,
+		)
+	}
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to update portfolio"}, err
+	}
+
+	// 3. Record the trade with what the short made or lost
+	realizedPL := (avgPrice-req.Price)*float64(req.Quantity) - fee
+
+	var tradeID int
+	err = tx.QueryRow(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, realized_pl, seq, note, request_id, quoted_price)
+        VALUES ($1, $2, 'BUY', $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11::numeric, 0))
+        RETURNING id
+    `, req.UserID, req.StockSymbol, req.Quantity, req.Price, totalCost, fee, realizedPL, seq, req.Note, req.RequestID, req.QuotedPrice).Scan(&tradeID)
+	if err != nil {
+		return TradeResult{Success: false, Kind: KindInternal, Error: "Failed to record trade"}, err
+	}
+
+	// The update set the quantity outright, so there's nothing to read back
+	result := TradeResult{
+		TradeID:          tradeID,
+		Success:          true,
+		Quantity:         req.Quantity,
+		Price:            req.Price,
+		QuotedPrice:      req.QuotedPrice,
+		TotalAmount:      totalCost,
+		Fee:              fee,
+		Seq:              seq,
+		PositionQuantity: newQuantity,
+	}
+	if newQuantity != 0 {
+		result.PositionAvgPrice = avgPrice
+	}
+	return result, nil
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestShortMarginOK(t *testing.T) {
+	// $10000 short at 50% margin needs $15000 of cash
+	if !shortMarginOK(15000.0, 10000.0, 50.0) {
+		t.Error("Expected cash at exactly 150% of exposure to be enough")
+	}
+	if shortMarginOK(14999.99, 10000.0, 50.0) {
+		t.Error("Expected cash below 150% of exposure to be rejected")
+	}
+	if !shortMarginOK(10000.0, 10000.0, 0) {
+		t.Error("Expected zero margin to need only the proceeds")
+	}
+}
+
+func TestComputePerformance_Short(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 90.0})
+
+	// Shorted 10 at $100: cash went from 10000 to 11000
+	holdings := []models.Holding{
+		valueHolding(models.Portfolio{StockSymbol: "AAPL", Quantity: -10, AvgPurchasePrice: 100.0}, prices),
+	}
+	perf := computePerformance(11000.0, holdings, 0, 0)
+
+	if perf.TotalValue != 10100.0 || perf.UnrealizedPL != 100.0 {
+		t.Errorf("Expected total 10100 with 100 P&L, got %+v", perf)
+	}
+	if !approxEqual(perf.PercentReturn, 10.0) {
+		t.Errorf("Expected 100 P&L on a 1000 short = 10%%, got %.4f", perf.PercentReturn)
+	}
+}
+
+func TestShortSell_OpenAndCover(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "short_trader", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.ShortSelling = true
+	tp.Start()
+	defer tp.Stop()
+
+	position := func() (int, float64, float64) {
+		t.Helper()
+		var quantity int
+		var avgPrice, cash float64
+		database.QueryRow("SELECT quantity, avg_purchase_price FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID).Scan(&quantity, &avgPrice)
+		database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&cash)
+		return quantity, avgPrice, cash
+	}
+
+	// Open: proceeds are credited and the position goes negative
+	result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 100.0})
+	if !result.Success {
+		t.Fatalf("Expected short to open, got error: %s", result.Error)
+	}
+	if quantity, avgPrice, cash := position(); quantity != -10 || avgPrice != 100.0 || cash != 11000.0 {
+		t.Fatalf("Expected -10 @ 100 with 11000 cash, got %d @ %.2f with %.2f", quantity, avgPrice, cash)
+	}
+
+	// Adding to the short averages the sale price
+	if result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 110.0}); !result.Success {
+		t.Fatalf("Expected short to grow, got error: %s", result.Error)
+	}
+	if quantity, avgPrice, _ := position(); quantity != -20 || avgPrice != 105.0 {
+		t.Errorf("Expected -20 @ 105, got %d @ %.2f", quantity, avgPrice)
+	}
+
+	// A buy can't cover more than the short
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 25, Price: 100.0})
+	if result.Success || result.Kind != KindBadRequest {
+		t.Errorf("Expected over-cover to be rejected, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	// Cover part at a profit, then the rest at a loss
+	if result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 95.0}); !result.Success {
+		t.Fatalf("Expected partial cover, got error: %s", result.Error)
+	}
+	if quantity, _, cash := position(); quantity != -15 || cash != 11625.0 {
+		t.Errorf("Expected -15 with 11625 cash, got %d with %.2f", quantity, cash)
+	}
+	if result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 15, Price: 110.0}); !result.Success {
+		t.Fatalf("Expected full cover, got error: %s", result.Error)
+	}
+	if quantity, _, cash := position(); quantity != 0 || cash != 9975.0 {
+		t.Errorf("Expected position closed with 9975 cash, got %d with %.2f", quantity, cash)
+	}
+
+	var realizedPL float64
+	database.QueryRow("SELECT COALESCE(SUM(realized_pl), 0) FROM trades WHERE user_id = $1 AND trade_type = 'BUY'", userID).Scan(&realizedPL)
+	if realizedPL != -25.0 {
+		t.Errorf("Expected 5*10 - 15*5 = -25 realized on covers, got %.2f", realizedPL)
+	}
+}
+
+func TestShortSell_Margin(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "thin_margin", 1000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	// Off by default: selling what isn't held is still rejected
+	req := models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 100.0}
+	if result := tp.SubmitSellTrade(req); result.Success || result.Kind != KindNoPosition {
+		t.Fatalf("Expected KindNoPosition with shorting off, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	tp.Config.ShortSelling = true
+
+	// $1000 + $1000 of proceeds backs a $1000 short at 50% margin...
+	if result := tp.SubmitSellTrade(req); !result.Success {
+		t.Fatalf("Expected short within margin to open, got error: %s", result.Error)
+	}
+
+	// ...but growing it to $3000 needs $4500, and there would be $4000
+	req.Quantity = 20
+	if result := tp.SubmitSellTrade(req); result.Success || result.Kind != KindInsufficientMargin {
+		t.Errorf("Expected KindInsufficientMargin, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestShortSell_ThroughZero(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "through_zero", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.ShortSelling = true
+	tp.Start()
+	defer tp.Stop()
+
+	if result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 90.0}); !result.Success {
+		t.Fatalf("Expected buy to succeed, got error: %s", result.Error)
+	}
+
+	// Selling 10 of a long 5 closes the long and shorts 5
+	result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 100.0})
+	if !result.Success {
+		t.Fatalf("Expected sell through zero to succeed, got error: %s", result.Error)
+	}
+	if result.Quantity != 10 || result.TotalAmount != 1000.0 || result.PositionQuantity != -5 {
+		t.Errorf("Expected 10 sold for 1000 leaving -5, got %+v", result)
+	}
+
+	var quantity int
+	var avgPrice, cash float64
+	database.QueryRow("SELECT quantity, avg_purchase_price FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID).Scan(&quantity, &avgPrice)
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&cash)
+	if quantity != -5 || avgPrice != 100.0 || cash != 10550.0 {
+		t.Errorf("Expected -5 @ 100 with 10550 cash, got %d @ %.2f with %.2f", quantity, avgPrice, cash)
+	}
+
+	// Only the closing leg realizes anything
+	var sells int
+	var realizedPL float64
+	database.QueryRow("SELECT COUNT(*), COALESCE(SUM(realized_pl), 0) FROM trades WHERE user_id = $1 AND trade_type = 'SELL'", userID).Scan(&sells, &realizedPL)
+	if sells != 2 || realizedPL != 50.0 {
+		t.Errorf("Expected 2 sell legs realizing 50, got %d realizing %.2f", sells, realizedPL)
+	}
+}
+
+func TestShortSell_PositionLimit(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "short_limited", 100000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.ShortSelling = true
+	tp.Config.PositionLimit = config.PositionLimit{MaxShares: 100}
+	tp.Start()
+	defer tp.Stop()
+
+	req := models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 100, Price: 100.0}
+	if result := tp.SubmitSellTrade(req); !result.Success {
+		t.Fatalf("Expected a short landing on the limit to open, got error: %s", result.Error)
+	}
+
+	req.Quantity = 1
+	if result := tp.SubmitSellTrade(req); result.Success || result.Kind != KindPositionLimit {
+		t.Errorf("Expected KindPositionLimit past the limit, got success=%v error=%q", result.Success, result.Error)
+	}
+}
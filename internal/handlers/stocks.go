@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/gin-gonic/gin"
+)
+
+// StockListing is a tradable symbol with its latest quote
+type StockListing struct {
+	Symbol    string    `json:"symbol"`
+	Sector    string    `json:"sector"`
+	TickSize  float64   `json:"tick_size"`
+	Price     float64   `json:"price"`
+	Change    float64   `json:"change"` // Percent change on the last tick
+	Timestamp time.Time `json:"timestamp"`
+	Halted    bool      `json:"halted"` // By hand or by the circuit breaker
+}
+
+// GetStocks handles GET /api/stocks. Quotes come from the same store the
+// WebSocket hub updates, so REST and WebSocket clients see the same prices.
+func GetStocks(c *gin.Context) {
+	stocks := listStocks(Prices, Halts)
+
+	Respond(c, http.StatusOK, gin.H{
+		"stocks": stocks,
+		"count":  len(stocks),
+	})
+}
+
+// SimulationParams is one symbol's simulated walk parameters
+type SimulationParams struct {
+	Symbol string `json:"symbol"`
+	market.SymbolParams
+}
+
+// GetSimulationConfig returns a handler for GET /api/stocks/simulation,
+// listing each symbol's start price, volatility and tick interval
+func GetSimulationConfig(provider *market.SimulatedProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := provider.Config()
+
+		params := make([]SimulationParams, 0, len(cfg))
+		for _, symbol := range market.Symbols() {
+			if p, ok := cfg[symbol]; ok {
+				params = append(params, SimulationParams{Symbol: symbol, SymbolParams: p})
+			}
+		}
+
+		Respond(c, http.StatusOK, gin.H{"symbols": params})
+	}
+}
+
+// listStocks returns every known symbol that has a quote, in symbol order,
+// flagging those halted in halts. Before the first tick it is empty.
+func listStocks(prices *market.PriceStore, halts *market.Halts) []StockListing {
+	stocks := make([]StockListing, 0)
+	for _, symbol := range market.Symbols() {
+		q, ok := prices.Get(symbol)
+		if !ok {
+			continue
+		}
+		stocks = append(stocks, StockListing{
+			Symbol:    symbol,
+			Sector:    market.Sector(symbol),
+			TickSize:  market.TickSize(symbol),
+			Price:     q.Price,
+			Change:    q.Change,
+			Timestamp: q.Timestamp,
+			Halted:    halts.IsHalted(symbol),
+		})
+	}
+	return stocks
+}
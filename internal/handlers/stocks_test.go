@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/gin-gonic/gin"
+)
+
+func TestListStocks(t *testing.T) {
+	prices := market.NewPriceStore()
+
+	halts := market.NewHalts()
+	if stocks := listStocks(prices, halts); stocks == nil || len(stocks) != 0 {
+		t.Errorf("Expected an empty, non-nil list before any ticks, got %v", stocks)
+	}
+
+	now := time.Now()
+	prices.Set(market.Quote{Symbol: "TSLA", Price: 242.05, Change: -1.5, Timestamp: now})
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 151.25, Change: 0.8, Timestamp: now})
+
+	halts.Halt("TSLA")
+
+	stocks := listStocks(prices, halts)
+	if len(stocks) != 2 {
+		t.Fatalf("Expected 2 quoted stocks, got %d", len(stocks))
+	}
+	if stocks[0].Symbol != "AAPL" || stocks[1].Symbol != "TSLA" {
+		t.Errorf("Expected symbol order, got %s, %s", stocks[0].Symbol, stocks[1].Symbol)
+	}
+	if tsla := stocks[1]; tsla.Price != 242.05 || tsla.Change != -1.5 || tsla.TickSize != 0.05 || !tsla.Timestamp.Equal(now) || !tsla.Halted {
+		t.Errorf("Unexpected TSLA listing %+v", tsla)
+	}
+	if stocks[0].Halted {
+		t.Error("Expected AAPL not halted")
+	}
+}
+
+func TestGetPriceHistory_RejectsBadParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/stocks/:symbol/history", GetPriceHistory)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/stocks/ZZZZ/history", http.StatusNotFound},
+		{"/api/stocks/AAPL/history?window=forever", http.StatusBadRequest},
+		{"/api/stocks/AAPL/history?interval=10ms", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.want, w.Code)
+		}
+	}
+}
+
+func TestGetSimulationConfig(t *testing.T) {
+	cfg := market.DefaultSimulationConfig()
+	cfg["TSLA"] = market.SymbolParams{InitialPrice: 250, Volatility: 4, Interval: market.Duration(500 * time.Millisecond)}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/stocks/simulation", GetSimulationConfig(market.NewSimulatedProvider(1, cfg)))
+	router.GET("/api/stocks/:symbol/history", GetPriceHistory)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stocks/simulation", nil))
+
+	var body struct {
+		Data struct {
+			Symbols []SimulationParams `json:"symbols"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(body.Data.Symbols) != len(market.Symbols()) {
+		t.Fatalf("Expected every symbol, got %d", len(body.Data.Symbols))
+	}
+	for _, p := range body.Data.Symbols {
+		if p.Symbol == "TSLA" && (p.Volatility != 4 || p.Interval != market.Duration(500*time.Millisecond)) {
+			t.Errorf("Unexpected TSLA params %+v", p)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTradeSummary handles GET /api/trades/:userId/summary, aggregating the
+// user's whole trade history since their last reset. It reads the running
+// totals in trade_stats, since the trades table keeps only each user's
+// newest trades. Sells and short covers count as a win or loss by the sign
+// of their realized P&L. With no trades every figure is zero.
+func GetTradeSummary(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	summary, err := loadTradeSummary(db.DB, userID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to summarize trades")
+		return
+	}
+	Respond(c, http.StatusOK, summary)
+}
+
+// loadTradeSummary computes userID's TradeSummary from their per-symbol
+// running totals
+func loadTradeSummary(database *sql.DB, userID int) (models.TradeSummary, error) {
+	var s models.TradeSummary
+	err := database.QueryRow(`
+        SELECT COALESCE(SUM(trades), 0),
+               COALESCE(SUM(bought), 0),
+               COALESCE(SUM(sold), 0),
+               COALESCE(SUM(fees), 0),
+               COALESCE(SUM(realized_pl), 0),
+               COALESCE(SUM(wins), 0),
+               COALESCE(SUM(losses), 0)
+        FROM trade_stats
+        WHERE user_id = $1
+    `, userID).Scan(&s.TotalTrades, &s.TotalBought, &s.TotalSold, &s.TotalFees,
+		&s.TotalRealizedPL, &s.Wins, &s.Losses)
+	if err != nil || s.TotalTrades == 0 {
+		return s, err
+	}
+
+	// Ties go to the alphabetically first symbol
+	err = database.QueryRow(`
+        SELECT stock_symbol, trades
+        FROM trade_stats
+        WHERE user_id = $1
+        ORDER BY trades DESC, stock_symbol
+        LIMIT 1
+    `, userID).Scan(&s.MostTradedSymbol, &s.MostTradedSymbolN)
+	return s, err
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetTradeSummary(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "summarized", 10000.0)
+	idleID := db.CreateTestUser(t, database, "idle", 10000.0)
+	otherID := db.CreateTestUser(t, database, "other", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, realized_pl)
+        VALUES ($1, 'AAPL', 'BUY', 10, 150.0, 1500.0, 1.00, NULL),
+               ($1, 'AAPL', 'SELL', 5, 160.0, 800.0, 1.00, 49.00),
+               ($1, 'AAPL', 'SELL', 5, 140.0, 700.0, 1.00, -51.00),
+               ($1, 'MSFT', 'BUY', 2, 380.0, 760.0, 0.50, NULL),
+               ($1, 'MSFT', 'SELL', 2, 380.0, 760.0, 0.50, 0),
+               ($1, 'TSLA', 'BUY', 1, 200.0, 200.0, 0, NULL),
+               ($2, 'TSLA', 'BUY', 100, 200.0, 20000.0, 5.00, NULL)
+    `, userID, otherID)
+	if err != nil {
+		t.Fatalf("Failed to setup trades: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/trades/:userId/summary", GetTradeSummary)
+
+	get := func(id int) models.TradeSummary {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/trades/%d/summary", id), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for user %d, got %d: %s", id, w.Code, w.Body.String())
+		}
+		var body struct {
+			Data models.TradeSummary `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Data
+	}
+
+	want := models.TradeSummary{
+		TotalTrades:       6,
+		TotalBought:       2460.0,
+		TotalSold:         2260.0,
+		TotalFees:         4.0,
+		TotalRealizedPL:   -2.0,
+		MostTradedSymbol:  "AAPL",
+		MostTradedSymbolN: 3,
+		Wins:              1,
+		Losses:            1,
+	}
+	if got := get(userID); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if got := get(idleID); got != (models.TradeSummary{}) {
+		t.Errorf("Expected an all-zero summary with no trades, got %+v", got)
+	}
+}
+
+func TestLoadTradeSummary_SurvivesPruning(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "prolific", 10000.0)
+
+	// Only the 15 newest trades are kept, but all 20 are summarized
+	for i := 1; i <= 20; i++ {
+		symbol, realized := "AAPL", 10.0
+		if i > 15 {
+			symbol, realized = "MSFT", -5.0
+		}
+		_, err := database.Exec(`
+            INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, fee, realized_pl, seq)
+            VALUES ($1, $2, 'SELL', 1, 100.0, 100.0, 1.00, $3, $4)
+        `, userID, symbol, realized, i)
+		if err != nil {
+			t.Fatalf("Failed to insert trade %d: %v", i, err)
+		}
+	}
+
+	var kept int
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&kept)
+	if kept != 15 {
+		t.Fatalf("Expected trades pruned to 15, got %d", kept)
+	}
+
+	summary, err := loadTradeSummary(database, userID)
+	if err != nil {
+		t.Fatalf("Failed to summarize: %v", err)
+	}
+
+	want := models.TradeSummary{
+		TotalTrades:       20,
+		TotalSold:         2000.0,
+		TotalFees:         20.0,
+		TotalRealizedPL:   125.0,
+		MostTradedSymbol:  "AAPL",
+		MostTradedSymbolN: 15,
+		Wins:              15,
+		Losses:            5,
+	}
+	if summary != want {
+		t.Errorf("Expected %+v, got %+v", want, summary)
+	}
+}
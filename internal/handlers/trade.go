@@ -2,296 +2,292 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/atharvakonge/stock-trading-simulator/internal/db"
 	"github.com/atharvakonge/stock-trading-simulator/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
-// BuyStock handles POST /api/trades/buy
-func BuyStock(c *gin.Context) {
-	var req models.BuyRequest
-
-	// Parse JSON request body
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Start database transaction
-	tx, err := db.DB.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed"})
-		return
-	}
-	defer tx.Rollback() // Rollback if we don't commit
-
-	// Calculate total cost
-	totalCost := req.Price * float64(req.Quantity)
-
-	// 1. Check user has enough cash
-	var cashBalance float64
-	err = tx.QueryRow(
-		"SELECT cash_balance FROM users WHERE id = $1 FOR UPDATE",
-		req.UserID,
-	).Scan(&cashBalance)
-
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
-	}
-
-	if cashBalance < totalCost {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient funds"})
-		return
-	}
-
-	// 2. Deduct cash from user
-	_, err = tx.Exec(
-		"UPDATE users SET cash_balance = cash_balance - $1 WHERE id = $2",
-		totalCost, req.UserID,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update balance"})
-		return
-	}
-
-	// 3. Update portfolio (or insert if doesn't exist)
-	_, err = tx.Exec(`
-        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
-        VALUES ($1, $2, $3, $4)
-        ON CONFLICT (user_id, stock_symbol) 
-        DO UPDATE SET 
-            quantity = portfolios.quantity + $3,
-            avg_purchase_price = (
-                (portfolios.avg_purchase_price * portfolios.quantity) + ($4 * $3)
-            ) / (portfolios.quantity + $3),
-            updated_at = NOW()
-    `, req.UserID, req.StockSymbol, req.Quantity, req.Price)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update portfolio"})
-		return
-	}
-
-	// 4. Record trade in history
-	var tradeID int
-	err = tx.QueryRow(`
-        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount)
-        VALUES ($1, $2, 'BUY', $3, $4, $5)
-        RETURNING id
-    `, req.UserID, req.StockSymbol, req.Quantity, req.Price, totalCost).Scan(&tradeID)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record trade"})
-		return
-	}
-
-	// Commit transaction (all or nothing!)
-	if err = tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
-		return
-	}
-
-	// Success!
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Trade executed successfully",
-		"trade_id":    tradeID,
-		"total_cost":  totalCost,
-		"new_balance": cashBalance - totalCost,
-	})
-}
-
 // GetPortfolio handles GET /api/portfolio/:userId
 func GetPortfolio(c *gin.Context) {
-	userID := c.Param("userId")
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
 
 	// Get user's cash balance
 	var cashBalance float64
+	var currency string
 	err := db.DB.QueryRow(
-		"SELECT cash_balance FROM users WHERE id = $1",
+		"SELECT cash_balance, currency FROM users WHERE id = $1",
 		userID,
-	).Scan(&cashBalance)
+	).Scan(&cashBalance, &currency)
 
 	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		RespondError(c, http.StatusInternalServerError, "Database error")
 		return
 	}
 
 	// Get user's portfolio
 	rows, err := db.DB.Query(`
-        SELECT id, user_id, stock_symbol, quantity, avg_purchase_price, updated_at
+        SELECT id, user_id, stock_symbol, quantity, avg_purchase_price, margin_used, updated_at
         FROM portfolios
-        WHERE user_id = $1 AND quantity > 0
+        WHERE user_id = $1 AND quantity <> 0
         ORDER BY stock_symbol
     `, userID)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolio"})
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch portfolio")
 		return
 	}
 	defer rows.Close()
 
-	portfolio := make([]models.Portfolio, 0)
-	totalValue := cashBalance // Start with cash
-
+	positions := make([]models.Portfolio, 0)
 	for rows.Next() {
 		var p models.Portfolio
-		err := rows.Scan(&p.ID, &p.UserID, &p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice, &p.UpdatedAt)
+		err := rows.Scan(&p.ID, &p.UserID, &p.StockSymbol, &p.Quantity, &p.AvgPurchasePrice, &p.MarginUsed, &p.UpdatedAt)
 		if err != nil {
 			continue
 		}
-		portfolio = append(portfolio, p)
-		// For now, use avg purchase price as "current value"
-		totalValue += p.AvgPurchasePrice * float64(p.Quantity)
+		positions = append(positions, p)
 	}
 
-	c.JSON(http.StatusOK, models.PortfolioResponse{
-		Portfolio:   portfolio,
-		CashBalance: cashBalance,
-		TotalValue:  totalValue,
-	})
-}
-
-// SellStock handles POST /api/trades/sell
-func SellStock(c *gin.Context) {
-	var req models.BuyRequest // Reuse same struct
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+	// Every position is valued at the same instant
+	valued := valueHoldings(positions, Prices)
+	portfolio := make([]models.Holding, 0, len(positions))
+	totalValue := cashBalance // Start with cash
+	totalUnrealizedPL := 0.0
 
-	tx, err := db.DB.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed"})
-		return
+	for _, p := range positions {
+		h := valued[holdingKey{UserID: p.UserID, Symbol: p.StockSymbol}]
+		portfolio = append(portfolio, h)
+		totalValue += h.CurrentValue
+		totalUnrealizedPL += h.UnrealizedPL
 	}
-	defer tx.Rollback()
 
-	totalProceeds := req.Price * float64(req.Quantity)
+	Respond(c, http.StatusOK, models.PortfolioResponse{
+		Portfolio:         portfolio,
+		CashBalance:       cashBalance,
+		TotalValue:        totalValue,
+		TotalUnrealizedPL: totalUnrealizedPL,
+		Currency:          currency,
+	})
+}
 
-	// 1. Check user owns enough shares
-	var currentQuantity int
-	err = tx.QueryRow(
-		"SELECT quantity FROM portfolios WHERE user_id = $1 AND stock_symbol = $2 FOR UPDATE",
-		req.UserID, req.StockSymbol,
-	).Scan(&currentQuantity)
+// Trade history page sizes for the limit query parameter
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
 
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You don't own this stock"})
-		return
-	}
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+// GetTradeHistory handles
+// GET /api/trades/:userId?symbol=AAPL&type=BUY&from=...&to=...
+// Every filter is optional and they combine. from and to are inclusive
+// RFC3339 bounds on when the trade was made. Trades come newest first,
+// limit at a time (default 50, at most 500); meta.pagination.next_cursor,
+// passed back as cursor, fetches the next page.
+func GetTradeHistory(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
 		return
 	}
 
-	if currentQuantity < req.Quantity {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Insufficient shares. You own %d, trying to sell %d",
-				currentQuantity, req.Quantity),
-		})
+	tradeType := c.Query("type")
+	if tradeType != "" && tradeType != models.TradeTypeBuy && tradeType != models.TradeTypeSell {
+		RespondError(c, http.StatusBadRequest, "type must be BUY or SELL")
 		return
 	}
 
-	// 2. Update portfolio (reduce quantity)
-	newQuantity := currentQuantity - req.Quantity
-	if newQuantity == 0 {
-		// Delete portfolio entry if selling all
-		_, err = tx.Exec(
-			"DELETE FROM portfolios WHERE user_id = $1 AND stock_symbol = $2",
-			req.UserID, req.StockSymbol,
-		)
-	} else {
-		// Update quantity
-		_, err = tx.Exec(
-			"UPDATE portfolios SET quantity = $1, updated_at = NOW() WHERE user_id = $2 AND stock_symbol = $3",
-			newQuantity, req.UserID, req.StockSymbol,
-		)
-	}
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update portfolio"})
-		return
+	var symbol string
+	if raw := c.Query("symbol"); raw != "" {
+		if symbol, ok = parseSymbol(c, raw); !ok {
+			return
+		}
 	}
 
-	// 3. Add proceeds to user's cash
-	_, err = tx.Exec(
-		"UPDATE users SET cash_balance = cash_balance + $1 WHERE id = $2",
-		totalProceeds, req.UserID,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update balance"})
+	from, to, ok := parseTimeRange(c)
+	if !ok {
 		return
 	}
 
-	// 4. Record trade
-	var tradeID int
-	err = tx.QueryRow(`
-        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount)
-        VALUES ($1, $2, 'SELL', $3, $4, $5)
-        RETURNING id
-    `, req.UserID, req.StockSymbol, req.Quantity, req.Price, totalProceeds).Scan(&tradeID)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record trade"})
-		return
+	limit := defaultHistoryLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxHistoryLimit {
+			RespondError(c, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
 	}
 
-	if err = tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
-		return
+	var after *historyCursor
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeHistoryCursor(v)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		after = &cursor
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "Stock sold successfully",
-		"trade_id":       tradeID,
-		"total_proceeds": totalProceeds,
+	query, args := tradeHistoryQuery(userID, tradeHistoryFilter{
+		Symbol:    symbol,
+		TradeType: tradeType,
+		From:      from,
+		To:        to,
+		Limit:     limit + 1, // One extra to tell whether there's another page
+		After:     after,
 	})
-}
-
-// GetTradeHistory handles GET /api/trades/:userId
-func GetTradeHistory(c *gin.Context) {
-	userID := c.Param("userId")
-
-	rows, err := db.DB.Query(`
-        SELECT id, stock_symbol, trade_type, quantity, price, total_amount, created_at
-        FROM trades
-        WHERE user_id = $1
-        ORDER BY created_at DESC
-        LIMIT 50
-    `, userID)
+	rows, err := db.DB.Query(query, args...)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trades"})
+		RespondError(c, http.StatusInternalServerError, "Failed to fetch trades")
 		return
 	}
 	defer rows.Close()
 
 	var trades []models.Trade
+	var last historyCursor
+	page := Pagination{Limit: limit}
 	for rows.Next() {
 		var t models.Trade
+		var seq sql.NullInt64
 		err := rows.Scan(&t.ID, &t.StockSymbol, &t.TradeType, &t.Quantity,
-			&t.Price, &t.TotalAmount, &t.CreatedAt)
+			&t.Price, &t.QuotedPrice, &t.TotalAmount, &t.Fee, &t.RealizedPL, &t.Note, &t.CreatedAt, &seq)
 		if err != nil {
 			continue
 		}
+		if len(trades) == limit {
+			page.HasMore = true
+			page.NextCursor = last.encode()
+			break
+		}
 		trades = append(trades, t)
+		last = historyCursor{Seq: seq, CreatedAt: t.CreatedAt, ID: t.ID}
 	}
+	page.Count = len(trades)
 
-	c.JSON(http.StatusOK, gin.H{
+	RespondWithMeta(c, http.StatusOK, gin.H{
 		"trades": trades,
 		"count":  len(trades),
-	})
+	}, Meta{Pagination: &page})
+}
+
+// historyCursor is where a page of trade history ended: the last trade's
+// position in the history's order. Seq is null for trades made before
+// submission order was recorded.
+type historyCursor struct {
+	Seq       sql.NullInt64
+	CreatedAt time.Time
+	ID        int
+}
+
+// historyCursorTime formats created_at, which has no time zone, exactly
+const historyCursorTime = "2006-01-02 15:04:05.999999"
+
+// encode makes the cursor an opaque URL-safe token
+func (hc historyCursor) encode() string {
+	seq := ""
+	if hc.Seq.Valid {
+		seq = strconv.FormatInt(hc.Seq.Int64, 10)
+	}
+	raw := seq + "|" + hc.CreatedAt.Format(historyCursorTime) + "|" + strconv.Itoa(hc.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeHistoryCursor parses a token made by historyCursor.encode
+func decodeHistoryCursor(token string) (historyCursor, error) {
+	var hc historyCursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return hc, err
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return hc, fmt.Errorf("cursor has %d parts, want 3", len(parts))
+	}
+	if parts[0] != "" {
+		if hc.Seq.Int64, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+			return hc, err
+		}
+		hc.Seq.Valid = true
+	}
+	if hc.CreatedAt, err = time.Parse(historyCursorTime, parts[1]); err != nil {
+		return hc, err
+	}
+	if hc.ID, err = strconv.Atoi(parts[2]); err != nil {
+		return hc, err
+	}
+	return hc, nil
+}
+
+// tradeHistoryFilter narrows trade history. Zero fields don't filter.
+type tradeHistoryFilter struct {
+	Symbol    string
+	TradeType string
+	From, To  time.Time      // Inclusive bounds on created_at
+	Limit     int            // Rows to return; zero means defaultHistoryLimit
+	After     *historyCursor // Start after this trade
+}
+
+// tradeHistoryQuery builds the history query, adding a WHERE clause for
+// each non-empty filter
+func tradeHistoryQuery(userID int, filter tradeHistoryFilter) (string, []interface{}) {
+	query := `
+        SELECT id, stock_symbol, trade_type, quantity, price, quoted_price, total_amount, fee, realized_pl, COALESCE(note, ''), created_at, seq
+        FROM trades
+        WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if filter.Symbol != "" {
+		args = append(args, filter.Symbol)
+		query += fmt.Sprintf(" AND stock_symbol = $%d", len(args))
+	}
+	if filter.TradeType != "" {
+		args = append(args, filter.TradeType)
+		query += fmt.Sprintf(" AND trade_type = $%d", len(args))
+	}
+	// created_at holds the server's local time, so compare it as that
+	// instant whatever offset the bound was given in
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(" AND created_at >= $%d::timestamptz", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(" AND created_at <= $%d::timestamptz", len(args))
+	}
+
+	// Everything after the cursor in the order below, where null seqs sort
+	// last
+	if after := filter.After; after != nil {
+		args = append(args, after.CreatedAt.Format(historyCursorTime), after.ID)
+		n := len(args)
+		if after.Seq.Valid {
+			args = append(args, after.Seq.Int64)
+			query += fmt.Sprintf(" AND (seq < $%[1]d OR seq IS NULL OR (seq = $%[1]d AND (created_at, id) < ($%[2]d::timestamp, $%[3]d)))",
+				len(args), n-1, n)
+		} else {
+			query += fmt.Sprintf(" AND seq IS NULL AND (created_at, id) < ($%d::timestamp, $%d)", n-1, n)
+		}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	query += fmt.Sprintf(`
+        ORDER BY seq DESC NULLS LAST, created_at DESC, id DESC
+        LIMIT %d`, limit)
+	return query, args
 }
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// confirmationBuffer is how many confirmations a subscriber may fall
+// behind before the feed drops it
+const confirmationBuffer = 16
+
+// TradeConfirmation is pushed to a user's trade stream when one of their
+// trades commits
+type TradeConfirmation struct {
+	TradeID     int       `json:"trade_id"`
+	UserID      int       `json:"user_id"`
+	StockSymbol string    `json:"stock_symbol"`
+	TradeType   string    `json:"trade_type"`
+	Quantity    int       `json:"quantity"`
+	Price       float64   `json:"price"`
+	TotalAmount float64   `json:"total_amount"`
+	Fee         float64   `json:"fee"`
+	Seq         int64     `json:"seq"`
+	RequestID   string    `json:"request_id,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// newTradeConfirmation describes a committed trade
+func newTradeConfirmation(req models.BuyRequest, result TradeResult, at time.Time) TradeConfirmation {
+	tradeType := req.TradeType
+	if tradeType == "" {
+		tradeType = models.TradeTypeBuy
+	}
+	return TradeConfirmation{
+		TradeID:     result.TradeID,
+		UserID:      req.UserID,
+		StockSymbol: req.StockSymbol,
+		TradeType:   tradeType,
+		Quantity:    result.Quantity,
+		Price:       result.Price,
+		TotalAmount: result.TotalAmount,
+		Fee:         result.Fee,
+		Seq:         result.Seq,
+		RequestID:   req.RequestID,
+		Timestamp:   at,
+	}
+}
+
+// TradeFeed fans committed trades out to each user's subscribers. A user
+// may have any number of subscribers; each gets every confirmation.
+type TradeFeed struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan TradeConfirmation]bool
+}
+
+// NewTradeFeed creates a feed with no subscribers
+func NewTradeFeed() *TradeFeed {
+	return &TradeFeed{subscribers: make(map[int]map[chan TradeConfirmation]bool)}
+}
+
+// Subscribe returns a channel of userID's confirmations and a func that
+// unsubscribes. The channel is closed on unsubscribe, or early if the
+// subscriber falls too far behind. The func is safe to call more than once.
+func (f *TradeFeed) Subscribe(userID int) (<-chan TradeConfirmation, func()) {
+	ch := make(chan TradeConfirmation, confirmationBuffer)
+
+	f.mu.Lock()
+	if f.subscribers[userID] == nil {
+		f.subscribers[userID] = make(map[chan TradeConfirmation]bool)
+	}
+	f.subscribers[userID][ch] = true
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.remove(userID, ch)
+	}
+}
+
+// Publish sends c to every subscriber of c.UserID without blocking
+func (f *TradeFeed) Publish(c TradeConfirmation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers[c.UserID] {
+		select {
+		case ch <- c:
+		default:
+			// Too slow to keep up; drop it rather than stall the worker
+			slog.Warn("Dropping slow trade subscriber", "user_id", c.UserID)
+			f.remove(c.UserID, ch)
+		}
+	}
+}
+
+// Subscribers returns how many subscribers userID has
+func (f *TradeFeed) Subscribers(userID int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscribers[userID])
+}
+
+// remove unsubscribes ch if it is still subscribed. The caller holds mu.
+func (f *TradeFeed) remove(userID int, ch chan TradeConfirmation) {
+	if !f.subscribers[userID][ch] {
+		return
+	}
+	delete(f.subscribers[userID], ch)
+	close(ch)
+	if len(f.subscribers[userID]) == 0 {
+		delete(f.subscribers, userID)
+	}
+}
+
+// HandleTradeWebSocket handles GET /ws/trades/:userId, pushing a
+// TradeConfirmation for each of the user's trades that commits while the
+// socket is open. Rejected trades aren't pushed. The connection counts
+// against hub's limit and closes when hub stops.
+func (tp *TradeProcessor) HandleTradeWebSocket(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		conn, closeConn := openConn(c, hub)
+		if conn == nil {
+			return
+		}
+		defer closeConn()
+
+		confirmations, unsubscribe := tp.Trades.Subscribe(userID)
+		defer unsubscribe()
+
+		go hub.watchConn(conn, unsubscribe)
+
+		if err := hub.relayConfirmations(conn, confirmations); err != nil {
+			slog.Debug("WebSocket write failed", "user_id", userID, "error", err)
+		}
+	}
+}
+
+// relayConfirmations writes each confirmation, and pings the client in
+// between, until the subscription ends, the hub stops, or a write fails or
+// times out
+func (h *Hub) relayConfirmations(conn *websocket.Conn, confirmations <-chan TradeConfirmation) error {
+	ticker := time.NewTicker(h.pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case confirmation, ok := <-confirmations:
+			if !ok {
+				// Unsubscribed: too slow, or gone
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+					time.Now().Add(h.writeWait))
+				return nil
+			}
+			if err := h.writeJSON(conn, confirmation); err != nil {
+				return err
+			}
+
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.writeWait)); err != nil {
+				return err
+			}
+
+		case <-h.stopCh:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+				time.Now().Add(h.writeWait))
+			return nil
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// dialTrades connects a WebSocket client to userID's trade stream
+func dialTrades(t *testing.T, server *httptest.Server, userID int) *websocket.Conn {
+	url := fmt.Sprintf("ws%s/ws/trades/%d", strings.TrimPrefix(server.URL, "http"), userID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	return conn
+}
+
+// waitForSubscribers waits until userID has n trade subscribers
+func waitForSubscribers(t *testing.T, feed *TradeFeed, userID, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for feed.Subscribers(userID) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d subscribers for user %d, have %d", n, userID, feed.Subscribers(userID))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTradeFeed_Subscribers(t *testing.T) {
+	feed := NewTradeFeed()
+
+	first, unsubscribeFirst := feed.Subscribe(1)
+	second, unsubscribeSecond := feed.Subscribe(1)
+	other, unsubscribeOther := feed.Subscribe(2)
+	defer unsubscribeOther()
+
+	feed.Publish(TradeConfirmation{TradeID: 42, UserID: 1})
+
+	for i, ch := range []<-chan TradeConfirmation{first, second} {
+		select {
+		case c := <-ch:
+			if c.TradeID != 42 {
+				t.Errorf("Subscriber %d expected trade 42, got %+v", i, c)
+			}
+		default:
+			t.Errorf("Subscriber %d got no confirmation", i)
+		}
+	}
+	select {
+	case c := <-other:
+		t.Errorf("Expected nothing for user 2, got %+v", c)
+	default:
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst() // Safe to repeat
+	if _, ok := <-first; ok {
+		t.Error("Expected the channel to be closed on unsubscribe")
+	}
+	if n := feed.Subscribers(1); n != 1 {
+		t.Errorf("Expected 1 subscriber left, got %d", n)
+	}
+
+	unsubscribeSecond()
+	if n := feed.Subscribers(1); n != 0 {
+		t.Errorf("Expected no subscribers left, got %d", n)
+	}
+}
+
+func TestTradeWebSocket_CleansUpOnDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hub := NewHub(newFakeProvider())
+	hub.Start()
+	defer hub.Stop()
+
+	tp := NewTradeProcessor(1)
+	router := gin.New()
+	router.GET("/ws/trades/:userId", tp.HandleTradeWebSocket(hub))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	first := dialTrades(t, server, 7)
+	defer first.Close()
+	second := dialTrades(t, server, 7)
+	waitForSubscribers(t, tp.Trades, 7, 2)
+
+	tp.Trades.Publish(TradeConfirmation{TradeID: 42, UserID: 7})
+	for i, conn := range []*websocket.Conn{first, second} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var c TradeConfirmation
+		if err := conn.ReadJSON(&c); err != nil {
+			t.Fatalf("Client %d failed to read confirmation: %v", i, err)
+		}
+		if c.TradeID != 42 {
+			t.Errorf("Client %d expected trade 42, got %+v", i, c)
+		}
+	}
+
+	second.Close()
+	waitForSubscribers(t, tp.Trades, 7, 1)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Connections() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 1 connection left, have %d", hub.Connections())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTradeWebSocket_PushesCommittedTrade(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	gin.SetMode(gin.TestMode)
+	userID := db.CreateTestUser(t, database, "streamuser", 10000.0)
+
+	hub := NewHub(newFakeProvider())
+	hub.Start()
+	defer hub.Stop()
+
+	committed := time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC)
+	tp := NewTradeProcessor(1)
+	tp.clock = clock.NewFake(committed)
+	tp.Start()
+	defer tp.Stop()
+
+	router := gin.New()
+	router.GET("/ws/trades/:userId", tp.HandleTradeWebSocket(hub))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn := dialTrades(t, server, userID)
+	defer conn.Close()
+	waitForSubscribers(t, tp.Trades, userID, 1)
+
+	// A rejected trade isn't pushed; the committed one after it is
+	tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1000, Price: 150.0})
+	result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 150.0})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var c TradeConfirmation
+	if err := conn.ReadJSON(&c); err != nil {
+		t.Fatalf("Failed to read confirmation: %v", err)
+	}
+	if c.TradeID != result.TradeID || c.UserID != userID || c.StockSymbol != "AAPL" ||
+		c.TradeType != models.TradeTypeBuy || c.Quantity != 10 || c.TotalAmount != 1500.0 {
+		t.Errorf("Expected confirmation of trade %d, got %+v", result.TradeID, c)
+	}
+	if !c.Timestamp.Equal(committed) {
+		t.Errorf("Expected the confirmation stamped %v, got %v", committed, c.Timestamp)
+	}
+}
@@ -1,10 +1,29 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/config"
 	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/metrics"
 	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestBuyStock_Success(t *testing.T) {
@@ -94,8 +113,11 @@ func TestBuyStock_InsufficientFunds(t *testing.T) {
 		t.Error("Expected trade to fail due to insufficient funds")
 	}
 
-	if result.Error != "Insufficient funds" {
-		t.Errorf("Expected 'Insufficient funds' error, got: %s", result.Error)
+	if result.Kind != KindInsufficientFunds {
+		t.Errorf("Expected KindInsufficientFunds, got kind=%d error=%q", result.Kind, result.Error)
+	}
+	if result.HTTPStatus() != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", result.HTTPStatus())
 	}
 
 	// Verify balance unchanged
@@ -130,8 +152,35 @@ func TestBuyStock_InvalidUser(t *testing.T) {
 		t.Error("Expected trade to fail for invalid user")
 	}
 
-	if result.Error != "User not found" {
-		t.Errorf("Expected 'User not found' error, got: %s", result.Error)
+	if result.Kind != KindNotFound {
+		t.Errorf("Expected KindNotFound, got kind=%d error=%q", result.Kind, result.Error)
+	}
+	if result.HTTPStatus() != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", result.HTTPStatus())
+	}
+}
+
+func TestTradeResult_HTTPStatus(t *testing.T) {
+	tests := []struct {
+		result TradeResult
+		want   int
+	}{
+		{TradeResult{Success: true}, http.StatusOK},
+		{TradeResult{Kind: KindBadRequest, Error: ErrUnknownSymbol}, http.StatusBadRequest},
+		{TradeResult{Kind: KindNotFound, Error: ErrUserNotFound}, http.StatusNotFound},
+		{TradeResult{Kind: KindInsufficientFunds, Error: ErrInsufficientFunds}, http.StatusBadRequest},
+		{TradeResult{Kind: KindNoPosition, Error: ErrNoPosition}, http.StatusBadRequest},
+		{TradeResult{Kind: KindInsufficientShares, Error: ErrInsufficientShares}, http.StatusBadRequest},
+		{TradeResult{Kind: KindInternal, Error: "Transaction failed"}, http.StatusInternalServerError},
+		{TradeResult{Kind: KindTimeout, Error: ErrTradeTimedOut}, http.StatusGatewayTimeout},
+		{TradeResult{Kind: KindUnavailable, Error: ErrSystemBusy}, http.StatusServiceUnavailable},
+		// The status follows the kind, never the wording
+		{TradeResult{Kind: KindInternal, Error: ErrUserNotFound}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := tt.result.HTTPStatus(); got != tt.want {
+			t.Errorf("HTTPStatus() of %+v = %d, want %d", tt.result, got, tt.want)
+		}
 	}
 }
 
@@ -261,6 +310,54 @@ func TestConcurrentBuying_DifferentUsers(t *testing.T) {
 	}
 }
 
+func TestConcurrentFirstBuy_NewSymbol(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "first_buyer", 100000.0)
+
+	tp := NewTradeProcessor(10)
+	tp.Start()
+	defer tp.Stop()
+
+	// Every trade races to create the same portfolio row
+	numTrades := 20
+	results := make(chan TradeResult, numTrades)
+
+	for i := 0; i < numTrades; i++ {
+		go func() {
+			req := models.BuyRequest{
+				UserID:      userID,
+				StockSymbol: "AMZN",
+				Quantity:    2,
+				Price:       100.0,
+			}
+			results <- tp.SubmitTrade(req)
+		}()
+	}
+
+	for i := 0; i < numTrades; i++ {
+		result := <-results
+		if !result.Success {
+			t.Errorf("Expected first-buy to succeed, got error: %s", result.Error)
+		}
+	}
+
+	var quantity int
+	err := database.QueryRow(
+		"SELECT quantity FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AMZN'",
+		userID,
+	).Scan(&quantity)
+	if err != nil {
+		t.Fatalf("Failed to query portfolio: %v", err)
+	}
+
+	if quantity != numTrades*2 {
+		t.Errorf("Expected quantity %d, got %d", numTrades*2, quantity)
+	}
+}
+
 func BenchmarkTradeProcessing(b *testing.B) {
 	database := db.SetupTestDB(&testing.T{})
 	defer database.Close()
@@ -324,28 +421,12 @@ func TestSellStock_Success(t *testing.T) {
 		t.Fatalf("Failed to setup portfolio: %v", err)
 	}
 
-	// Now sell via HTTP handler
-	// Since SellStock is an HTTP handler, we need to test it differently
-	// For now, let's manually execute the sell logic
-
-	// Update portfolio (reduce quantity)
-	_, err = database.Exec(`
-        UPDATE portfolios 
-        SET quantity = quantity - $1 
-        WHERE user_id = $2 AND stock_symbol = $3
-    `, 5, userID, "AAPL")
-	if err != nil {
-		t.Fatalf("Failed to sell: %v", err)
+	result := sellInTx(t, database, models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 150.0})
+	if !result.Success {
+		t.Fatalf("Expected sell to succeed, got error: %s", result.Error)
 	}
-
-	// Update user balance (add proceeds)
-	_, err = database.Exec(`
-        UPDATE users 
-        SET cash_balance = cash_balance + $1 
-        WHERE id = $2
-    `, 5*150.0, userID)
-	if err != nil {
-		t.Fatalf("Failed to update balance: %v", err)
+	if result.TotalAmount != 750.0 || result.Quantity != 5 {
+		t.Errorf("Expected 5 shares sold for 750, got %d for %.2f", result.Quantity, result.TotalAmount)
 	}
 
 	// Verify sold quantity
@@ -377,6 +458,1600 @@ func TestSellStock_Success(t *testing.T) {
 }
 
 func TestSellStock_InsufficientShares(t *testing.T) {
-	// Test selling more shares than owned
-	// Should fail with appropriate error
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "short_seller", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 3, 150.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	result := sellInTx(t, database, models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 150.0})
+	if result.Success || result.Kind != KindInsufficientShares {
+		t.Fatalf("Expected KindInsufficientShares, got success=%v error=%q", result.Success, result.Error)
+	}
+	if result.Error != "Insufficient shares. You own 3, trying to sell 5" {
+		t.Errorf("Expected the message to give both quantities, got %q", result.Error)
+	}
+
+	// Nothing changed
+	var quantity int
+	var balance float64
+	database.QueryRow("SELECT quantity FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID).Scan(&quantity)
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if quantity != 3 || balance != 10000.0 {
+		t.Errorf("Expected 3 shares and $10000 untouched, got %d shares and %.2f", quantity, balance)
+	}
+}
+
+func TestSellStock_NotOwned(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "empty_seller", 10000.0)
+
+	result := sellInTx(t, database, models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 150.0})
+	if result.Success || result.Kind != KindNoPosition {
+		t.Errorf("Expected KindNoPosition, got success=%v error=%q", result.Success, result.Error)
+	}
+	if result.HTTPStatus() != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", result.HTTPStatus())
+	}
+}
+
+// sellInTx runs executeSell in its own transaction, committing on success
+func sellInTx(t *testing.T, database *sql.DB, req models.BuyRequest) TradeResult {
+	t.Helper()
+
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := executeSell(tx, config.Default(), req, nextTradeSeq())
+	if err != nil {
+		t.Fatalf("Sell hit a database error: %v", err)
+	}
+	if result.Success {
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit sell: %v", err)
+		}
+	}
+	return result
+}
+
+func TestConcentrationWarning_Threshold(t *testing.T) {
+	// TSLA worth $4500 of a $10000 account is 45%
+	warning := concentrationWarning("TSLA", 4500.0, 10000.0, 30.0)
+	if warning != "TSLA now 45% of your portfolio" {
+		t.Errorf("Expected concentration warning above threshold, got %q", warning)
+	}
+
+	if warning := concentrationWarning("TSLA", 2000.0, 10000.0, 30.0); warning != "" {
+		t.Errorf("Expected no warning below threshold, got %q", warning)
+	}
+
+	if warning := concentrationWarning("TSLA", 0, 0, 30.0); warning != "" {
+		t.Errorf("Expected no warning for an empty account, got %q", warning)
+	}
+}
+
+func TestBuyStock_ConcentrationWarning(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "concentrated", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.ConcentrationWarningPercent = 40.0
+	tp.Start()
+	defer tp.Stop()
+
+	// $3000 of $10000 is 30%, below the threshold
+	result := tp.SubmitTrade(models.BuyRequest{
+		UserID:      userID,
+		StockSymbol: "TSLA",
+		Quantity:    12,
+		Price:       250.0,
+	})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+	if result.Warning != "" {
+		t.Errorf("Expected no warning at 30%%, got %q", result.Warning)
+	}
+
+	// Another $2000 takes TSLA to 50%
+	result = tp.SubmitTrade(models.BuyRequest{
+		UserID:      userID,
+		StockSymbol: "TSLA",
+		Quantity:    8,
+		Price:       250.0,
+	})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+	if result.Warning != "TSLA now 50% of your portfolio" {
+		t.Errorf("Expected concentration warning at 50%%, got %q", result.Warning)
+	}
+}
+
+func TestBuyStock_ConcentrationWarningAtLivePrices(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 100.0, Timestamp: time.Now()})
+
+	userID := db.CreateTestUser(t, database, "rallied", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.ConcentrationWarningPercent = 40.0
+	tp.Start()
+	defer tp.Stop()
+
+	result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 20, Price: 100.0})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+
+	// At cost AAPL would be $2300 of $10000, 23%. At $300 it is
+	// 21 × $300 = $6300 of $7700 cash + $6300, 45%.
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 300.0, Timestamp: time.Now()})
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 300.0})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+	if result.Warning != "AAPL now 45% of your portfolio" {
+		t.Errorf("Expected a warning at the live price, got %q", result.Warning)
+	}
+}
+
+func TestBuyStock_PositionLimit(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 100.0, Timestamp: time.Now()})
+	Prices.Set(market.Quote{Symbol: "MSFT", Price: 10.0, Timestamp: time.Now()})
+
+	userID := db.CreateTestUser(t, database, "limited", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.PositionLimit = config.PositionLimit{MaxPercent: 25}
+	tp.Config.SymbolPositionLimits = map[string]config.PositionLimit{"MSFT": {MaxShares: 50}}
+	tp.Start()
+	defer tp.Stop()
+
+	// $2500 of $10000 is exactly 25%
+	result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 25, Price: 100.0})
+	if !result.Success {
+		t.Fatalf("Expected a buy at the percent limit to succeed, got error: %s", result.Error)
+	}
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+	if result.Success || result.Kind != KindPositionLimit || result.Error != ErrPositionLimit {
+		t.Errorf("Expected KindPositionLimit past 25%%, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	// MSFT's own limit replaces the percent one
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "MSFT", Quantity: 40, Price: 10.0})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "MSFT", Quantity: 10, Price: 10.0})
+	if !result.Success {
+		t.Fatalf("Expected a buy up to 50 shares to succeed, got error: %s", result.Error)
+	}
+	result = tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "MSFT", Quantity: 1, Price: 10.0})
+	if result.Success || result.Kind != KindPositionLimit {
+		t.Errorf("Expected KindPositionLimit past 50 shares, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	var quantity int
+	database.QueryRow("SELECT quantity FROM portfolios WHERE user_id = $1 AND stock_symbol = 'MSFT'", userID).Scan(&quantity)
+	if quantity != 50 {
+		t.Errorf("Expected the rejected buy to leave 50 shares, got %d", quantity)
+	}
+}
+
+func TestBuyStock_ReturnsPosition(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "positioned", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	buys := []struct {
+		quantity     int
+		price        float64
+		wantQuantity int
+		wantAvgPrice float64
+	}{
+		{10, 150.0, 10, 150.0},
+		{30, 130.0, 40, 135.0}, // (10 * 150 + 30 * 130) / 40
+	}
+
+	for _, buy := range buys {
+		result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: buy.quantity, Price: buy.price})
+		if !result.Success {
+			t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+		}
+		if result.PositionQuantity != buy.wantQuantity || result.PositionAvgPrice != buy.wantAvgPrice {
+			t.Errorf("Expected position %d @ %.2f, got %d @ %.2f",
+				buy.wantQuantity, buy.wantAvgPrice, result.PositionQuantity, result.PositionAvgPrice)
+		}
+
+		var quantity int
+		var avgPrice float64
+		database.QueryRow("SELECT quantity, avg_purchase_price FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'",
+			userID).Scan(&quantity, &avgPrice)
+		if result.PositionQuantity != quantity || result.PositionAvgPrice != avgPrice {
+			t.Errorf("Expected the returned position %d @ %.2f to match the database's %d @ %.2f",
+				result.PositionQuantity, result.PositionAvgPrice, quantity, avgPrice)
+		}
+	}
+}
+
+func TestTradeHistory_OrderedBySubmission(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "ordered", 100000.0)
+
+	tp := NewTradeProcessor(5)
+	tp.Start()
+	defer tp.Stop()
+
+	// Workers may commit these in any order
+	numTrades := 10
+	results := make(chan TradeResult, numTrades)
+	for i := 0; i < numTrades; i++ {
+		go func() {
+			results <- tp.SubmitTrade(models.BuyRequest{
+				UserID:      userID,
+				StockSymbol: "AAPL",
+				Quantity:    1,
+				Price:       100.0,
+			})
+		}()
+	}
+
+	bySeq := make([]TradeResult, 0, numTrades)
+	for i := 0; i < numTrades; i++ {
+		result := <-results
+		if !result.Success {
+			t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+		}
+		bySeq = append(bySeq, result)
+	}
+	sort.Slice(bySeq, func(i, j int) bool { return bySeq[i].Seq > bySeq[j].Seq })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/trades/:userId", GetTradeHistory)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/trades/%d", userID), nil))
+
+	var body struct {
+		Data struct {
+			Trades []models.Trade `json:"trades"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode history: %v", err)
+	}
+
+	if len(body.Data.Trades) != numTrades {
+		t.Fatalf("Expected %d trades in history, got %d", numTrades, len(body.Data.Trades))
+	}
+
+	// Newest submission first
+	for i, trade := range body.Data.Trades {
+		if trade.ID != bySeq[i].TradeID {
+			t.Errorf("Position %d: expected trade %d, got %d", i, bySeq[i].TradeID, trade.ID)
+		}
+	}
+}
+
+func TestAccountAgeError(t *testing.T) {
+	cfg := config.Default()
+	cfg.MinAccountAge = 72 * time.Hour
+	cfg.AgeRestrictedSymbols = []string{"TSLA"}
+	cfg.AgeRestrictedOrderValue = 5000.0
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tooNew := now.Add(-24 * time.Hour)
+	oldEnough := now.Add(-96 * time.Hour)
+
+	msg := accountAgeError(cfg, tooNew, now, "TSLA", 100.0)
+	if msg != "Account too new to trade TSLA until 2026-01-12T12:00:00Z" {
+		t.Errorf("Expected restricted symbol error, got %q", msg)
+	}
+
+	msg = accountAgeError(cfg, tooNew, now, "AAPL", 6000.0)
+	if msg != "Account too new for orders over $5000.00 until 2026-01-12T12:00:00Z" {
+		t.Errorf("Expected order size error, got %q", msg)
+	}
+
+	if msg := accountAgeError(cfg, tooNew, now, "AAPL", 1000.0); msg != "" {
+		t.Errorf("Expected small unrestricted order to pass, got %q", msg)
+	}
+
+	if msg := accountAgeError(cfg, oldEnough, now, "TSLA", 6000.0); msg != "" {
+		t.Errorf("Expected old account to pass, got %q", msg)
+	}
+
+	if msg := accountAgeError(config.Default(), tooNew, now, "TSLA", 6000.0); msg != "" {
+		t.Errorf("Expected check disabled by default, got %q", msg)
+	}
+}
+
+func TestBuyStock_MinAccountAge(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	newUser := db.CreateTestUser(t, database, "new_account", 10000.0)
+	oldUser := db.CreateTestUser(t, database, "old_account", 10000.0)
+
+	_, err := database.Exec("UPDATE users SET created_at = NOW() - INTERVAL '30 days' WHERE id = $1", oldUser)
+	if err != nil {
+		t.Fatalf("Failed to age account: %v", err)
+	}
+
+	tp := NewTradeProcessor(1)
+	tp.Config.MinAccountAge = 7 * 24 * time.Hour
+	tp.Config.AgeRestrictedSymbols = []string{"TSLA"}
+	tp.Start()
+	defer tp.Stop()
+
+	req := models.BuyRequest{UserID: newUser, StockSymbol: "TSLA", Quantity: 1, Price: 250.0}
+	if result := tp.SubmitTrade(req); result.Success || !strings.HasPrefix(result.Error, "Account too new to trade TSLA") {
+		t.Errorf("Expected new account to be rejected, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	req.UserID = oldUser
+	if result := tp.SubmitTrade(req); !result.Success {
+		t.Errorf("Expected old account to trade, got error: %s", result.Error)
+	}
+
+	// Shorting is held to the same rule
+	tp.Config.ShortSelling = true
+	req.UserID = newUser
+	if result := tp.SubmitSellTrade(req); result.Success || !strings.HasPrefix(result.Error, "Account too new to trade TSLA") {
+		t.Errorf("Expected new account's short to be rejected, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestTrade_Commission(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	// Enough for the shares but not the commission
+	userID := db.CreateTestUser(t, database, "fee_payer", 1000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.Commission = config.Commission{Flat: 5.0}
+	tp.Start()
+	defer tp.Stop()
+
+	req := models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 100.0}
+	if result := tp.SubmitTrade(req); result.Success || result.Kind != KindInsufficientFunds {
+		t.Errorf("Expected commission to make the buy unaffordable, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	req.Quantity = 9
+	result := tp.SubmitTrade(req)
+	if !result.Success || result.Fee != 5.0 {
+		t.Fatalf("Expected buy with $5 fee, got success=%v fee=%.2f error=%q", result.Success, result.Fee, result.Error)
+	}
+
+	result = tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 9, Price: 100.0})
+	if !result.Success || result.Fee != 5.0 {
+		t.Fatalf("Expected sell with $5 fee, got success=%v fee=%.2f error=%q", result.Success, result.Fee, result.Error)
+	}
+
+	var cash, fees float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&cash)
+	database.QueryRow("SELECT SUM(fee) FROM trades WHERE user_id = $1", userID).Scan(&fees)
+
+	if cash != 990.0 {
+		t.Errorf("Expected cash 1000 - 905 + 895 = 990, got %.2f", cash)
+	}
+	if fees != 10.0 {
+		t.Errorf("Expected $10 of fees recorded, got %.2f", fees)
+	}
+}
+
+func TestStats_Idle(t *testing.T) {
+	tp := NewTradeProcessor(3)
+
+	stats := tp.Stats()
+	if stats.Workers != 3 || stats.QueueCapacity != 100 || stats.QueueLength != 0 {
+		t.Errorf("Unexpected idle stats: %+v", stats)
+	}
+}
+
+func TestRegisterMetrics_Scrape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tp := NewTradeProcessor(1)
+	registry := metrics.NewRegistry()
+	if err := tp.RegisterMetrics(registry); err != nil {
+		t.Fatalf("Failed to register metrics: %v", err)
+	}
+
+	// Queue a trade with no worker running to show up in queue_depth
+	tp.tradeQueue <- TradeRequest{Request: models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, Price: 1}}
+
+	router := gin.New()
+	router.GET("/metrics", gin.WrapH(metrics.Handler(registry)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`trade_total{outcome="success",type="buy"}`,
+		`trade_total{outcome="failure",type="sell"}`,
+		"trade_duration_seconds_bucket",
+		"queue_depth 1",
+		"active_workers 0",
+		"websocket_connections",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in scrape, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestStats_CountsTrades(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "stats_trader", 1000.0)
+
+	tp := NewTradeProcessor(5)
+	tp.Start()
+	defer tp.Stop()
+
+	// Read stats concurrently with trades; run with -race
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				tp.Stats()
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Only 10 of these are affordable
+			tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+		}()
+	}
+	wg.Wait()
+	close(done)
+
+	stats := tp.Stats()
+	if stats.TradesProcessed != 12 {
+		t.Errorf("Expected 12 trades processed, got %d", stats.TradesProcessed)
+	}
+	if stats.TradesFailed != 2 {
+		t.Errorf("Expected 2 failed trades, got %d", stats.TradesFailed)
+	}
+	if stats.ActiveWorkers != 0 || stats.QueueLength != 0 {
+		t.Errorf("Expected an idle pool after all trades returned, got %+v", stats)
+	}
+}
+
+func TestSubmitTradeWithContext_TimesOut(t *testing.T) {
+	// Workers not started, so nothing will ever pick the trade up
+	tp := NewTradeProcessor(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan TradeResult)
+	go func() {
+		done <- tp.SubmitTradeWithContext(ctx, models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+	}()
+
+	select {
+	case result := <-done:
+		if result.Success || result.Kind != KindTimeout {
+			t.Errorf("Expected %q, got success=%v error=%q", ErrTradeTimedOut, result.Success, result.Error)
+		}
+		if result.HTTPStatus() != http.StatusGatewayTimeout {
+			t.Errorf("Expected 504 for a timed out trade, got %d", result.HTTPStatus())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SubmitTradeWithContext blocked past its deadline")
+	}
+
+	// The abandoned trade is still queued; a worker must skip it rather
+	// than execute it (it would need a database to execute)
+	tp.Start()
+	defer tp.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for tp.Stats().TradesProcessed == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats := tp.Stats(); stats.TradesProcessed != 1 || stats.TradesFailed != 1 {
+		t.Errorf("Expected the expired trade to be skipped as failed, got %+v", stats)
+	}
+}
+
+func TestSubmitTrade_MarketClosed(t *testing.T) {
+	// Workers not started; nothing reaches the database
+	tp := NewTradeProcessor(1)
+	tp.Config.MarketHours = market.Hours{Enabled: true, Open: 9 * time.Hour, Close: 17 * time.Hour, Location: time.UTC, ClosedWeekends: true}
+	req := models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, Price: 100.0}
+
+	// Saturday midday
+	fake := clock.NewFake(time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC))
+	tp.clock = fake
+	for _, tradeType := range []string{models.TradeTypeBuy, models.TradeTypeSell} {
+		req.TradeType = tradeType
+		result := tp.SubmitTrade(req)
+		if result.Kind != KindMarketClosed || result.Error != ErrMarketClosed {
+			t.Errorf("%s: expected %q, got kind %v error %q", tradeType, ErrMarketClosed, result.Kind, result.Error)
+		}
+	}
+
+	// Wednesday midday passes the check and is queued, where the expired
+	// context ends it
+	fake.Set(time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := tp.SubmitTradeWithContext(ctx, req); result.Kind != KindTimeout {
+		t.Errorf("Expected the open market to queue the trade, got kind %v error %q", result.Kind, result.Error)
+	}
+}
+
+func TestSubmitTradeWithContext_PropagatesRequestID(t *testing.T) {
+	// Workers not started; the already-expired context ends the wait, so no
+	// database is needed
+	tp := NewTradeProcessor(1)
+	req := models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, Price: 100.0}
+
+	ctx, cancel := context.WithCancel(WithRequestID(context.Background(), "req-abc"))
+	cancel()
+
+	if result := tp.SubmitTradeWithContext(ctx, req); result.RequestID != "req-abc" {
+		t.Errorf("Expected request ID req-abc in result, got %q", result.RequestID)
+	}
+
+	// Without one in the context, a fresh ID is generated per trade
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	first := tp.SubmitTradeWithContext(ctx, req)
+	second := tp.SubmitTradeWithContext(ctx, req)
+	if first.RequestID == "" || first.RequestID == second.RequestID {
+		t.Errorf("Expected distinct generated IDs, got %q and %q", first.RequestID, second.RequestID)
+	}
+}
+
+// recordSpans points tp's tracer at an in-memory exporter
+func recordSpans(tp *TradeProcessor) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp.Tracer = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+	return exporter
+}
+
+// spansByName indexes spans by name, failing on duplicates
+func spansByName(t *testing.T, spans tracetest.SpanStubs) map[string]tracetest.SpanStub {
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		if _, dup := byName[span.Name]; dup {
+			t.Fatalf("Expected one %q span, got several", span.Name)
+		}
+		byName[span.Name] = span
+	}
+	return byName
+}
+
+// spanAttribute returns the value of key on span, or "" if unset
+func spanAttribute(span tracetest.SpanStub, key string) string {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestSubmitTrade_Spans(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "traceduser", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	exporter := recordSpans(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 150.0})
+	if !result.Success {
+		t.Fatalf("Expected trade to succeed, got error: %s", result.Error)
+	}
+
+	spans := spansByName(t, exporter.GetSpans())
+	submit, ok := spans["SubmitTrade"]
+	if !ok {
+		t.Fatalf("Expected a SubmitTrade span, got %+v", spans)
+	}
+	for _, name := range []string{"trade.queue_wait", "trade.transaction"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("Expected a %s span", name)
+		}
+		if span.Parent.SpanID() != submit.SpanContext.SpanID() {
+			t.Errorf("Expected %s to be a child of SubmitTrade", name)
+		}
+	}
+
+	tx := spans["trade.transaction"]
+	if got := spanAttribute(tx, "user_id"); got != fmt.Sprint(userID) {
+		t.Errorf("Expected user_id %d, got %q", userID, got)
+	}
+	if got := spanAttribute(tx, "symbol"); got != "AAPL" {
+		t.Errorf("Expected symbol AAPL, got %q", got)
+	}
+	if got := spanAttribute(tx, "outcome"); got != "success" {
+		t.Errorf("Expected outcome success, got %q", got)
+	}
+}
+
+func TestSubmitTrade_SpansWithoutTransaction(t *testing.T) {
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+
+	tp := NewTradeProcessor(1)
+	exporter := recordSpans(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	// An unquoted MARKET order is rejected by the worker before any
+	// database work
+	result := tp.SubmitTrade(models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, OrderType: models.OrderTypeMarket})
+	if result.Kind != KindNoMarketPrice {
+		t.Fatalf("Expected KindNoMarketPrice, got %v (%s)", result.Kind, result.Error)
+	}
+
+	spans := spansByName(t, exporter.GetSpans())
+	if _, ok := spans["trade.transaction"]; ok {
+		t.Error("Expected no transaction span for a trade rejected before the database")
+	}
+	submit, ok := spans["SubmitTrade"]
+	if !ok {
+		t.Fatal("Expected a SubmitTrade span")
+	}
+	if queued, ok := spans["trade.queue_wait"]; !ok || queued.Parent.SpanID() != submit.SpanContext.SpanID() {
+		t.Errorf("Expected a trade.queue_wait child of SubmitTrade, got %+v", queued)
+	}
+	if got := spanAttribute(submit, "outcome"); got != "failure" || submit.Status.Code != codes.Error {
+		t.Errorf("Expected a failed SubmitTrade span, got outcome %q status %+v", got, submit.Status)
+	}
+}
+
+func TestSubmitTrade_RejectsWhenQueueFull(t *testing.T) {
+	// Workers not started, so queued trades stay queued
+	tp := NewTradeProcessor(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	capacity := tp.Stats().QueueCapacity
+	for i := 0; i < capacity; i++ {
+		go tp.SubmitTradeWithContext(ctx, models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for tp.Stats().QueueLength < capacity && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := tp.Stats().QueueLength; n != capacity {
+		t.Fatalf("Expected a full queue of %d, got %d", capacity, n)
+	}
+
+	done := make(chan TradeResult)
+	go func() {
+		done <- tp.SubmitTrade(models.BuyRequest{UserID: 1, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+	}()
+
+	select {
+	case result := <-done:
+		if result.Success || result.Error != ErrSystemBusy {
+			t.Errorf("Expected %q, got success=%v error=%q", ErrSystemBusy, result.Success, result.Error)
+		}
+		if result.HTTPStatus() != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 for a full queue, got %d", result.HTTPStatus())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SubmitTrade blocked on a full queue")
+	}
+}
+
+func TestStop_DrainsQueuedTrades(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "draining", 100000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+
+	numTrades := 20
+	results := make(chan TradeResult, numTrades)
+	for i := 0; i < numTrades; i++ {
+		go func() {
+			results <- tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+		}()
+	}
+
+	// Stop while trades are still queued behind the single worker
+	deadline := time.Now().Add(2 * time.Second)
+	for tp.Stats().QueueLength == 0 && tp.Stats().TradesProcessed == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	tp.Stop()
+
+	succeeded := 0
+	for i := 0; i < numTrades; i++ {
+		select {
+		case result := <-results:
+			if result.Success {
+				succeeded++
+			} else if result.Error != errProcessorStopped {
+				t.Errorf("Unexpected failure: %s", result.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("SubmitTrade caller %d still blocked after Stop", i)
+		}
+	}
+
+	// The trade limit trigger keeps each user's 15 most recent trades
+	var tradeCount int
+	database.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&tradeCount)
+	if succeeded == 0 || tradeCount != min(succeeded, 15) {
+		t.Errorf("Expected every successful result recorded, got %d successes and %d trades", succeeded, tradeCount)
+	}
+}
+
+func TestTwoProcessors_SameUser(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "two_instances", 1000.0)
+
+	// Separate processors have separate user locks, like two API instances
+	first := NewTradeProcessor(5)
+	first.Start()
+	defer first.Stop()
+	second := NewTradeProcessor(5)
+	second.Start()
+	defer second.Stop()
+
+	// 20 buys of $100 against $1000: exactly 10 can succeed
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		tp := first
+		if i%2 == 1 {
+			tp = second
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0})
+			if result.Success {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var cash float64
+	var quantity int
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&cash)
+	database.QueryRow("SELECT quantity FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID).Scan(&quantity)
+
+	if cash < 0 || cash != 1000.0-float64(succeeded)*100.0 {
+		t.Errorf("Expected cash to match %d successful buys, got %.2f", succeeded, cash)
+	}
+	if quantity != succeeded {
+		t.Errorf("Expected %d shares, got %d", succeeded, quantity)
+	}
+}
+
+func TestSubmitTrade_AfterStop(t *testing.T) {
+	tp := NewTradeProcessor(2)
+	tp.Start()
+	tp.Stop()
+
+	done := make(chan TradeResult)
+	go func() {
+		done <- tp.SubmitTrade(models.BuyRequest{
+			UserID:      1,
+			StockSymbol: "AAPL",
+			Quantity:    1,
+			Price:       100.0,
+		})
+	}()
+
+	select {
+	case result := <-done:
+		if result.Success || result.Error != "processor stopped" {
+			t.Errorf("Expected 'processor stopped', got success=%v error=%q", result.Success, result.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SubmitTrade blocked after Stop")
+	}
+
+	// A second Stop must not panic
+	tp.Stop()
+}
+
+func TestConcurrentSelling_SameHolding(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "concurrent_seller", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 5, 100.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	tp := NewTradeProcessor(5)
+	tp.Start()
+	defer tp.Stop()
+
+	// 10 one-share sells against a 5-share holding
+	numSells := 10
+	results := make(chan TradeResult, numSells)
+	for i := 0; i < numSells; i++ {
+		go func() {
+			results <- tp.SubmitSellTrade(models.BuyRequest{
+				UserID:      userID,
+				StockSymbol: "AAPL",
+				Quantity:    1,
+				Price:       120.0,
+			})
+		}()
+	}
+
+	successCount := 0
+	for i := 0; i < numSells; i++ {
+		result := <-results
+		if result.Success {
+			successCount++
+		} else if result.Kind != KindNoPosition {
+			t.Errorf("Unexpected sell error: %s", result.Error)
+		}
+	}
+
+	if successCount != 5 {
+		t.Errorf("Expected exactly 5 successful sells, got %d", successCount)
+	}
+
+	var balance float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if balance != 10000.0+5*120.0 {
+		t.Errorf("Expected balance %.2f, got %.2f", 10000.0+5*120.0, balance)
+	}
+}
+
+func TestSubmitSellTrade_Errors(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "sell_errors", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO portfolios (user_id, stock_symbol, quantity, avg_purchase_price)
+        VALUES ($1, 'AAPL', 3, 100.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup portfolio: %v", err)
+	}
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "MSFT", Quantity: 1, Price: 100.0})
+	if result.Success || result.Kind != KindNoPosition {
+		t.Errorf("Expected KindNoPosition, got success=%v error=%q", result.Success, result.Error)
+	}
+
+	result = tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 5, Price: 100.0})
+	if result.Success || result.Kind != KindInsufficientShares {
+		t.Errorf("Expected insufficient shares, got success=%v error=%q", result.Success, result.Error)
+	}
+}
+
+func TestSubmitTrade_UnknownSymbol(t *testing.T) {
+	// Rejected before queueing, so no workers or database are needed
+	tp := NewTradeProcessor(1)
+
+	result := tp.SubmitTrade(models.BuyRequest{UserID: 1, StockSymbol: "BANANA", Quantity: 1, Price: 1.0})
+	if result.Success || result.Error != ErrUnknownSymbol {
+		t.Errorf("Expected %q, got success=%v error=%q", ErrUnknownSymbol, result.Success, result.Error)
+	}
+	if result.HTTPStatus() != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", result.HTTPStatus())
+	}
+}
+
+func TestSubmitTrade_NormalizesSymbolCase(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "symbol_case", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	for _, symbol := range []string{"aapl", "AAPL", " Aapl "} {
+		result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: symbol, Quantity: 1, Price: 100.0})
+		if !result.Success {
+			t.Fatalf("Buy of %q failed: %s", symbol, result.Error)
+		}
+	}
+
+	result := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "aapl", Quantity: 1, Price: 100.0})
+	if !result.Success {
+		t.Fatalf("Sell of aapl failed: %s", result.Error)
+	}
+
+	var symbol string
+	var quantity int
+	err := database.QueryRow(
+		"SELECT stock_symbol, quantity FROM portfolios WHERE user_id = $1", userID,
+	).Scan(&symbol, &quantity)
+	if err != nil {
+		t.Fatalf("Expected a single holding: %v", err)
+	}
+	if symbol != "AAPL" || quantity != 2 {
+		t.Errorf("Expected 2 AAPL, got %d %s", quantity, symbol)
+	}
+
+	var distinct int
+	database.QueryRow("SELECT COUNT(DISTINCT stock_symbol) FROM trades WHERE user_id = $1", userID).Scan(&distinct)
+	if distinct != 1 {
+		t.Errorf("Expected trades under one symbol, got %d", distinct)
+	}
+}
+
+func TestPriceInRange_Boundary(t *testing.T) {
+	tests := []struct {
+		price     float64
+		tolerance float64
+		want      bool
+	}{
+		{100.0, 5, true},
+		{105.0, 5, true},
+		{95.0, 5, true},
+		{105.01, 5, false},
+		{94.99, 5, false},
+		{1.0, 5, false},
+		{1.0, 0, true}, // Check disabled
+	}
+
+	for _, tt := range tests {
+		if got := priceInRange(tt.price, 100.0, tt.tolerance); got != tt.want {
+			t.Errorf("priceInRange(%.2f, 100, %.0f%%) = %v, want %v", tt.price, tt.tolerance, got, tt.want)
+		}
+	}
+}
+
+func TestSubmitTrade_PriceOutOfRange(t *testing.T) {
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 150.0, Timestamp: time.Now()})
+
+	// Never started: trades that pass the guard wait in the queue until
+	// the context expires
+	tp := NewTradeProcessor(1)
+	tp.Config.PriceTolerancePercent = 5
+
+	submit := func(symbol, tradeType string, price float64) TradeResult {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		return tp.SubmitTradeWithContext(ctx, models.BuyRequest{
+			UserID: 1, StockSymbol: symbol, Quantity: 1, Price: price, TradeType: tradeType,
+		})
+	}
+
+	if result := submit("AAPL", models.TradeTypeBuy, 1.0); result.Error != ErrPriceOutOfRange {
+		t.Errorf("Expected %q for a $1 buy, got %q", ErrPriceOutOfRange, result.Error)
+	}
+	if result := submit("AAPL", models.TradeTypeBuy, 157.5); result.Kind != KindTimeout {
+		t.Errorf("Expected a buy at the boundary to be queued, got %q", result.Error)
+	}
+	if result := submit("AAPL", models.TradeTypeSell, 1.0); result.Kind != KindTimeout {
+		t.Errorf("Expected sells to skip the check, got %q", result.Error)
+	}
+
+	if result := submit("MSFT", models.TradeTypeBuy, 1.0); result.Kind != KindTimeout {
+		t.Errorf("Expected unquoted symbols to skip the check, got %q", result.Error)
+	}
+}
+
+func TestSubmitTrade_MarketOrderSlippage(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 150.0, Timestamp: time.Now()})
+
+	userID := db.CreateTestUser(t, database, "slipped", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Config.Slippage = config.Slippage{BasisPoints: 20}
+	tp.Start()
+	defer tp.Stop()
+
+	// 20bp of $150 is $0.30, paid on top of the quote
+	buy := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, OrderType: models.OrderTypeMarket})
+	if !buy.Success {
+		t.Fatalf("Market buy failed: %s", buy.Error)
+	}
+	if buy.Price != 150.30 || buy.QuotedPrice != 150.0 || buy.TotalAmount != 1503.0 {
+		t.Errorf("Expected 10 filled at 150.30 off a 150 quote, got %.2f off %.2f for %.2f", buy.Price, buy.QuotedPrice, buy.TotalAmount)
+	}
+
+	// ...and taken off it on a sell
+	sell := tp.SubmitSellTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, OrderType: models.OrderTypeMarket})
+	if !sell.Success {
+		t.Fatalf("Market sell failed: %s", sell.Error)
+	}
+	if sell.Price != 149.70 || sell.QuotedPrice != 150.0 {
+		t.Errorf("Expected the sell filled at 149.70 off a 150 quote, got %.2f off %.2f", sell.Price, sell.QuotedPrice)
+	}
+
+	for _, result := range []TradeResult{buy, sell} {
+		var price, quoted float64
+		database.QueryRow("SELECT price, quoted_price FROM trades WHERE id = $1", result.TradeID).Scan(&price, &quoted)
+		if price != result.Price || quoted != 150.0 {
+			t.Errorf("Expected trade %d recorded at %.2f quoted at 150, got %.2f quoted at %.2f", result.TradeID, result.Price, price, quoted)
+		}
+	}
+
+	// A priced trade fills at its price, with no quote recorded
+	priced := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 150.0})
+	if !priced.Success || priced.Price != 150.0 || priced.QuotedPrice != 0 {
+		t.Fatalf("Expected a priced buy filled at 150 without slippage, got %+v", priced)
+	}
+	var quoted sql.NullFloat64
+	database.QueryRow("SELECT quoted_price FROM trades WHERE id = $1", priced.TradeID).Scan(&quoted)
+	if quoted.Valid {
+		t.Errorf("Expected no quoted price on a priced trade, got %.2f", quoted.Float64)
+	}
+}
+
+func TestSubmitTrade_MarketOrderWithoutQuote(t *testing.T) {
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+
+	// Rejected before the database is touched
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	result := tp.SubmitTrade(models.BuyRequest{
+		UserID: 1, StockSymbol: "AAPL", Quantity: 1, OrderType: models.OrderTypeMarket,
+	})
+	if result.Success || result.Kind != KindNoMarketPrice {
+		t.Errorf("Expected %q, got success=%v error=%q", ErrNoMarketPrice, result.Success, result.Error)
+	}
+}
+
+func TestSubmitTrade_MarketOrderFillsAtQuote(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 150.0, Timestamp: time.Now()})
+
+	userID := db.CreateTestUser(t, database, "market_order", 10000.0)
+
+	tp := NewTradeProcessor(1)
+	tp.Start()
+	defer tp.Stop()
+
+	// The client's price is ignored
+	result := tp.SubmitTrade(models.BuyRequest{
+		UserID: userID, StockSymbol: "AAPL", Quantity: 2, Price: 1.0, OrderType: models.OrderTypeMarket,
+	})
+	if !result.Success {
+		t.Fatalf("Market buy failed: %s", result.Error)
+	}
+	if result.Price != 150.0 || result.TotalAmount != 300.0 {
+		t.Errorf("Expected fill at 150 for 300, got %.2f for %.2f", result.Price, result.TotalAmount)
+	}
+
+	var price float64
+	database.QueryRow("SELECT price FROM trades WHERE id = $1", result.TradeID).Scan(&price)
+	if price != 150.0 {
+		t.Errorf("Expected trade recorded at 150, got %.2f", price)
+	}
+
+	Prices.Set(market.Quote{Symbol: "AAPL", Price: 160.0, Timestamp: time.Now()})
+	result = tp.SubmitSellTrade(models.BuyRequest{
+		UserID: userID, StockSymbol: "AAPL", Quantity: 1, OrderType: models.OrderTypeMarket,
+	})
+	if !result.Success || result.Price != 160.0 {
+		t.Errorf("Expected market sell at 160, got success=%v price=%.2f error=%q", result.Success, result.Price, result.Error)
+	}
+}
+
+func TestProcessTrade_UserBusy(t *testing.T) {
+	tp := NewTradeProcessor(1)
+	tp.Config.UserLockTimeout = 20 * time.Millisecond
+
+	// A stuck trade holds the user's lock; nothing reaches the database
+	tp.portfolioMgr.LockUser(5)
+	defer tp.portfolioMgr.UnlockUser(5)
+
+	req := models.BuyRequest{UserID: 5, StockSymbol: "AAPL", Quantity: 1, Price: 100.0}
+	for _, result := range []TradeResult{
+		tp.processBuyTrade(context.Background(), req, nextTradeSeq()),
+		tp.processSellTrade(context.Background(), req, nextTradeSeq()),
+	} {
+		if result.Success || result.Error != ErrUserBusy {
+			t.Errorf("Expected %q, got success=%v error=%q", ErrUserBusy, result.Success, result.Error)
+		}
+		if result.HTTPStatus() != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503, got %d", result.HTTPStatus())
+		}
+	}
+}
+
+func TestWorkers_ResizeUnderLoad(t *testing.T) {
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+
+	tp := NewTradeProcessor(2)
+	tp.Start()
+
+	// Unquoted market orders keep the workers busy without a database
+	var executed atomic.Int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				result := tp.SubmitTrade(models.BuyRequest{
+					UserID: userID, StockSymbol: "AAPL", Quantity: 1, OrderType: models.OrderTypeMarket,
+				})
+				switch result.Error {
+				case ErrNoMarketPrice:
+					executed.Add(1)
+				case ErrSystemBusy:
+				default:
+					t.Errorf("Unexpected result under resize: %+v", result)
+				}
+			}
+		}(i + 1)
+	}
+
+	// waitForProgress waits for more trades to be executed by the current pool
+	waitForProgress := func() {
+		t.Helper()
+		start := executed.Load()
+		deadline := time.Now().Add(2 * time.Second)
+		for executed.Load() < start+50 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if executed.Load() < start+50 {
+			t.Fatalf("Pool of %d workers stalled", tp.Stats().Workers)
+		}
+	}
+
+	waitForProgress()
+	if n := tp.AddWorkers(3); n != 5 {
+		t.Errorf("Expected 5 workers after adding 3, got %d", n)
+	}
+	waitForProgress()
+	if n := tp.RemoveWorkers(4); n != 1 {
+		t.Errorf("Expected 1 worker after removing 4, got %d", n)
+	}
+	waitForProgress()
+	if n := tp.RemoveWorkers(1); n != 1 {
+		t.Errorf("Expected the last worker to be kept, got %d", n)
+	}
+	if n := tp.Stats().Workers; n != 1 {
+		t.Errorf("Expected Stats to report 1 worker, got %d", n)
+	}
+
+	close(stop)
+	wg.Wait()
+
+	stats := tp.Stats()
+	if stats.TradesProcessed != executed.Load() {
+		t.Errorf("Expected every queued trade answered: processed %d, executed %d", stats.TradesProcessed, executed.Load())
+	}
+
+	tp.Stop()
+	if n := tp.AddWorkers(2); n != 1 {
+		t.Errorf("Expected AddWorkers after Stop to do nothing, got %d", n)
+	}
+}
+
+func TestExecOne_RequiresOneRow(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "exec_one", 10000.0)
+
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = execOne(tx, "UPDATE users SET cash_balance = cash_balance + 1 WHERE id = $1", userID)
+	if err != nil {
+		t.Errorf("Expected one-row update to succeed, got %v", err)
+	}
+
+	err = execOne(tx, "DELETE FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'", userID)
+	if !errors.Is(err, errUnexpectedRows) {
+		t.Errorf("Expected errUnexpectedRows for a missing row, got %v", err)
+	}
+}
+
+func TestSubmitTrade_DispatchesByTradeType(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "mixed_trader", 10000.0)
+
+	tp := NewTradeProcessor(5)
+	tp.Start()
+	defer tp.Stop()
+
+	// Empty TradeType defaults to BUY
+	result := tp.SubmitTrade(models.BuyRequest{UserID: userID, StockSymbol: "AAPL", Quantity: 10, Price: 100.0})
+	if !result.Success {
+		t.Fatalf("Expected default buy to succeed, got error: %s", result.Error)
+	}
+
+	// Interleaved buys and sells for one user serialize on the same lock
+	numPairs := 5
+	results := make(chan TradeResult, numPairs*2)
+	for i := 0; i < numPairs; i++ {
+		go func() {
+			results <- tp.SubmitTrade(models.BuyRequest{
+				UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0, TradeType: models.TradeTypeBuy,
+			})
+		}()
+		go func() {
+			results <- tp.SubmitTrade(models.BuyRequest{
+				UserID: userID, StockSymbol: "AAPL", Quantity: 1, Price: 100.0, TradeType: models.TradeTypeSell,
+			})
+		}()
+	}
+
+	for i := 0; i < numPairs*2; i++ {
+		if result := <-results; !result.Success {
+			t.Errorf("Expected trade to succeed, got error: %s", result.Error)
+		}
+	}
+
+	var quantity int
+	database.QueryRow(
+		"SELECT quantity FROM portfolios WHERE user_id = $1 AND stock_symbol = 'AAPL'",
+		userID,
+	).Scan(&quantity)
+
+	if quantity != 10 {
+		t.Errorf("Expected quantity back at 10, got %d", quantity)
+	}
+
+	var balance float64
+	database.QueryRow("SELECT cash_balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if balance != 9000.0 {
+		t.Errorf("Expected balance 9000.00, got %.2f", balance)
+	}
+}
+
+func TestTradeHistoryQuery_Filters(t *testing.T) {
+	query, args := tradeHistoryQuery(7, tradeHistoryFilter{})
+	if len(args) != 1 || strings.Contains(query, "stock_symbol =") || strings.Contains(query, "trade_type =") || strings.Contains(query, "created_at >") {
+		t.Errorf("Expected unfiltered query, got %q with %v", query, args)
+	}
+
+	query, args = tradeHistoryQuery(7, tradeHistoryFilter{Symbol: "AAPL", TradeType: "SELL"})
+	if !strings.Contains(query, "stock_symbol = $2") || !strings.Contains(query, "trade_type = $3") {
+		t.Errorf("Expected both filters, got %q", query)
+	}
+	if len(args) != 3 || args[1] != "AAPL" || args[2] != "SELL" {
+		t.Errorf("Expected args [7 AAPL SELL], got %v", args)
+	}
+
+	// Each bound is optional
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query, args = tradeHistoryQuery(7, tradeHistoryFilter{Symbol: "AAPL", From: from})
+	if !strings.Contains(query, "created_at >= $3") || strings.Contains(query, "created_at <=") {
+		t.Errorf("Expected only a lower bound, got %q", query)
+	}
+	if len(args) != 3 || args[2] != from {
+		t.Errorf("Expected args [7 AAPL %v], got %v", from, args)
+	}
+}
+
+func TestHistoryCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 1, 17, 10, 0, 0, 123456000, time.UTC)
+	cursors := []historyCursor{
+		{Seq: sql.NullInt64{Int64: 42, Valid: true}, CreatedAt: created, ID: 7},
+		{CreatedAt: created, ID: 8}, // From before seqs were recorded
+	}
+
+	for _, want := range cursors {
+		got, err := decodeHistoryCursor(want.encode())
+		if err != nil || got.Seq != want.Seq || !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+			t.Errorf("Expected %+v back, got %+v (%v)", want, got, err)
+		}
+	}
+
+	for _, bad := range []string{"not base64!", "bm9waXBlcw"} {
+		if _, err := decodeHistoryCursor(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	query, args := tradeHistoryQuery(7, tradeHistoryFilter{After: &cursors[0], Limit: 11})
+	if !strings.Contains(query, "seq < $4") || !strings.Contains(query, "($2::timestamp, $3)") || !strings.Contains(query, "LIMIT 11") {
+		t.Errorf("Expected the query to start after the cursor, got %q", query)
+	}
+	if len(args) != 4 || args[3] != int64(42) {
+		t.Errorf("Expected args [7 created 7 42], got %v", args)
+	}
+}
+
+func TestGetTradeHistory_Pages(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "paged", 10000.0)
+
+	// Two trades share a seq, as the legs of a sell through zero do, and
+	// one predates seqs
+	_, err := database.Exec(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, seq)
+        VALUES ($1, 'AAPL', 'BUY', 1, 150.0, 150.0, NULL),
+               ($1, 'AAPL', 'BUY', 2, 150.0, 300.0, 1),
+               ($1, 'AAPL', 'SELL', 3, 150.0, 450.0, 2),
+               ($1, 'AAPL', 'SELL', 4, 150.0, 600.0, 2),
+               ($1, 'AAPL', 'BUY', 5, 150.0, 750.0, 3)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup trades: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/trades/:userId", GetTradeHistory)
+
+	var quantities []int
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+			fmt.Sprintf("/api/trades/%d?limit=2&cursor=%s", userID, cursor), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var body struct {
+			Data struct {
+				Trades []models.Trade `json:"trades"`
+			} `json:"data"`
+			Meta Meta `json:"meta"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		for _, tr := range body.Data.Trades {
+			quantities = append(quantities, tr.Quantity)
+		}
+
+		page := body.Meta.Pagination
+		if page == nil || page.Limit != 2 || page.Count != len(body.Data.Trades) || page.HasMore != (page.NextCursor != "") {
+			t.Fatalf("Unexpected pagination %+v", page)
+		}
+		if !page.HasMore {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	// Newest seq first, ties by ID, then the trade without a seq
+	if fmt.Sprint(quantities) != "[5 4 3 2 1]" {
+		t.Errorf("Expected every trade once in order [5 4 3 2 1], got %v", quantities)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/trades/%d?cursor=garbage!", userID), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad cursor, got %d", w.Code)
+	}
+}
+
+func TestGetTradeHistory_Filters(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "filtered", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount)
+        VALUES ($1, 'AAPL', 'BUY', 10, 150.0, 1500.0),
+               ($1, 'AAPL', 'SELL', 5, 155.0, 775.0),
+               ($1, 'MSFT', 'BUY', 2, 380.0, 760.0)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup trades: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/trades/:userId", GetTradeHistory)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"?symbol=AAPL", 2},
+		{"?type=BUY", 2},
+		{"?symbol=AAPL&type=BUY", 1},
+		{"?symbol=TSLA", 0},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/trades/%d%s", userID, tt.query), nil))
+
+		var body struct {
+			Data struct {
+				Count int `json:"count"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+
+		if w.Code != http.StatusOK || body.Data.Count != tt.want {
+			t.Errorf("%q: expected 200 with %d trades, got %d with %d", tt.query, tt.want, w.Code, body.Data.Count)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/trades/%d?type=HOLD", userID), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown type, got %d", w.Code)
+	}
+}
+
+func TestGetTradeHistory_DateRange(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "dated", 10000.0)
+
+	_, err := database.Exec(`
+        INSERT INTO trades (user_id, stock_symbol, trade_type, quantity, price, total_amount, created_at)
+        VALUES ($1, 'AAPL', 'BUY', 10, 150.0, 1500.0, '2023-12-31 12:00:00Z'::timestamptz),
+               ($1, 'AAPL', 'BUY', 1, 150.0, 150.0, '2024-01-01 00:00:00Z'::timestamptz),
+               ($1, 'MSFT', 'BUY', 2, 380.0, 760.0, '2024-01-15 09:30:00Z'::timestamptz),
+               ($1, 'AAPL', 'SELL', 5, 155.0, 775.0, '2024-01-31 23:59:59Z'::timestamptz),
+               ($1, 'AAPL', 'SELL', 5, 160.0, 800.0, '2024-02-01 00:00:00Z'::timestamptz)
+    `, userID)
+	if err != nil {
+		t.Fatalf("Failed to setup trades: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/trades/:userId", GetTradeHistory)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 5},
+		{"?from=2024-01-01T00:00:00Z&to=2024-01-31T23:59:59Z", 3},
+		{"?from=2024-01-01T00:00:00Z", 4},
+		{"?to=2023-12-31T23:59:59Z", 1},
+		{"?symbol=AAPL&type=SELL&from=2024-01-01T00:00:00Z&to=2024-01-31T23:59:59Z", 1},
+		{"?from=2025-01-01T00:00:00Z", 0},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/trades/%d%s", userID, tt.query), nil))
+
+		var body struct {
+			Data struct {
+				Count int `json:"count"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+
+		if w.Code != http.StatusOK || body.Data.Count != tt.want {
+			t.Errorf("%q: expected 200 with %d trades, got %d with %d", tt.query, tt.want, w.Code, body.Data.Count)
+		}
+	}
 }
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUser handles GET /api/users/:userId
+func GetUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	err := db.DB.QueryRow(`
+        SELECT id, username, email, cash_balance, currency, created_at
+        FROM users
+        WHERE id = $1
+    `, userID).Scan(&user.ID, &user.Username, &user.Email, &user.CashBalance, &user.Currency, &user.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		RespondError(c, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	Respond(c, http.StatusOK, user)
+}
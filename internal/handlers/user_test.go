@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/db"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetUser_InvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/users/:userId", GetUser)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/abc", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-numeric id, got %d", w.Code)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	database := db.SetupTestDB(t)
+	defer database.Close()
+	defer db.CleanupTestDB(t, database)
+
+	userID := db.CreateTestUser(t, database, "profile_user", 4321.5)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/users/:userId", GetUser)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/users/%d", userID), nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data models.User `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	if body.Data.ID != userID || !strings.HasPrefix(body.Data.Username, "profile_user") || body.Data.CashBalance != 4321.5 {
+		t.Errorf("Unexpected user: %+v", body.Data)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/users/%d", userID+1000), nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d", w.Code)
+	}
+}
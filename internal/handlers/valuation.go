@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+// Prices is the shared latest-price store written by the price simulator
+var Prices = market.NewPriceStore()
+
+// valueHolding values a position at the latest market price, falling back
+// to its avg purchase price (and flagging it) when no price is known yet
+func valueHolding(p models.Portfolio, prices *market.PriceStore) models.Holding {
+	q, ok := prices.Get(p.StockSymbol)
+	return valueAtQuote(p, q, ok)
+}
+
+// holdingKey identifies one user's position in one symbol
+type holdingKey struct {
+	UserID int
+	Symbol string
+}
+
+// valueHoldings values positions across any number of users against one
+// snapshot of prices, so every position is valued at the same instant.
+// The price store is read once rather than once per position.
+func valueHoldings(positions []models.Portfolio, prices *market.PriceStore) map[holdingKey]models.Holding {
+	snapshot := prices.Snapshot()
+
+	values := make(map[holdingKey]models.Holding, len(positions))
+	for _, p := range positions {
+		q, ok := snapshot[p.StockSymbol]
+		values[holdingKey{UserID: p.UserID, Symbol: p.StockSymbol}] = valueAtQuote(p, q, ok)
+	}
+	return values
+}
+
+// valueAtQuote values a position at q, or at its avg purchase price if
+// there is no quote (ok false)
+func valueAtQuote(p models.Portfolio, q market.Quote, ok bool) models.Holding {
+	h := models.Holding{
+		Portfolio:    p,
+		CurrentPrice: p.AvgPurchasePrice,
+	}
+
+	if ok {
+		h.CurrentPrice = q.Price
+	} else {
+		h.PriceUnavailable = true
+	}
+
+	h.CurrentValue = h.CurrentPrice * float64(p.Quantity)
+	h.UnrealizedPL = h.CurrentValue - p.AvgPurchasePrice*float64(p.Quantity)
+	return h
+}
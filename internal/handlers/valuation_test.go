@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+)
+
+func TestValueHolding_UsesMarketPrice(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 160.0})
+
+	h := valueHolding(models.Portfolio{StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 150.0}, prices)
+
+	if h.PriceUnavailable {
+		t.Error("Expected market price to be used")
+	}
+	if h.CurrentValue != 1600.0 {
+		t.Errorf("Expected current value 1600, got %.2f", h.CurrentValue)
+	}
+	if h.UnrealizedPL != 100.0 {
+		t.Errorf("Expected unrealized P&L 100, got %.2f", h.UnrealizedPL)
+	}
+}
+
+func TestValueHolding_FallsBackWithoutPrice(t *testing.T) {
+	h := valueHolding(models.Portfolio{StockSymbol: "MSFT", Quantity: 2, AvgPurchasePrice: 380.0}, market.NewPriceStore())
+
+	if !h.PriceUnavailable {
+		t.Error("Expected missing price to be flagged")
+	}
+	if h.CurrentValue != 760.0 || h.UnrealizedPL != 0 {
+		t.Errorf("Expected value at basis with zero P&L, got value %.2f P&L %.2f", h.CurrentValue, h.UnrealizedPL)
+	}
+}
+
+func TestValueHolding_Short(t *testing.T) {
+	prices := market.NewPriceStore()
+	short := models.Portfolio{StockSymbol: "AAPL", Quantity: -10, AvgPurchasePrice: 100.0}
+
+	// A short profits when the price falls...
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 90.0})
+	h := valueHolding(short, prices)
+	if h.CurrentValue != -900.0 || h.UnrealizedPL != 100.0 {
+		t.Errorf("Expected value -900 and P&L 100 at $90, got value %.2f P&L %.2f", h.CurrentValue, h.UnrealizedPL)
+	}
+
+	// ...and loses when it rises
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 110.0})
+	h = valueHolding(short, prices)
+	if h.CurrentValue != -1100.0 || h.UnrealizedPL != -100.0 {
+		t.Errorf("Expected value -1100 and P&L -100 at $110, got value %.2f P&L %.2f", h.CurrentValue, h.UnrealizedPL)
+	}
+}
+
+func TestValueHoldings_AcrossUsers(t *testing.T) {
+	prices := market.NewPriceStore()
+	prices.Set(market.Quote{Symbol: "AAPL", Price: 200.0})
+
+	values := valueHoldings([]models.Portfolio{
+		{UserID: 1, StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 150.0},
+		{UserID: 2, StockSymbol: "AAPL", Quantity: -5, AvgPurchasePrice: 210.0},
+		{UserID: 2, StockSymbol: "MSFT", Quantity: 2, AvgPurchasePrice: 380.0},
+	}, prices)
+
+	if len(values) != 3 {
+		t.Fatalf("Expected 3 valued holdings, got %d", len(values))
+	}
+	if h := values[holdingKey{UserID: 1, Symbol: "AAPL"}]; h.CurrentValue != 2000.0 || h.UnrealizedPL != 500.0 {
+		t.Errorf("Expected user 1's AAPL worth 2000 with P&L 500, got %+v", h)
+	}
+	if h := values[holdingKey{UserID: 2, Symbol: "AAPL"}]; h.CurrentValue != -1000.0 || h.UnrealizedPL != 50.0 {
+		t.Errorf("Expected user 2's AAPL short worth -1000 with P&L 50, got %+v", h)
+	}
+	if h := values[holdingKey{UserID: 2, Symbol: "MSFT"}]; !h.PriceUnavailable || h.CurrentValue != 760.0 {
+		t.Errorf("Expected unquoted MSFT valued at cost and flagged, got %+v", h)
+	}
+}
+
+// BenchmarkValueHoldings values growing numbers of holdings across many
+// users. ns/holding should stay flat as the count grows.
+func BenchmarkValueHoldings(b *testing.B) {
+	symbols := []string{"AAPL", "MSFT", "GOOGL", "AMZN", "TSLA", "NVDA", "META", "NFLX"}
+	prices := market.NewPriceStore()
+	for i, symbol := range symbols {
+		prices.Set(market.Quote{Symbol: symbol, Price: float64(100 + i)})
+	}
+
+	for _, n := range []int{1000, 10000, 100000} {
+		positions := make([]models.Portfolio, n)
+		for i := range positions {
+			positions[i] = models.Portfolio{
+				UserID:           i / len(symbols),
+				StockSymbol:      symbols[i%len(symbols)],
+				Quantity:         10,
+				AvgPurchasePrice: 100.0,
+			}
+		}
+
+		b.Run(fmt.Sprintf("holdings=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				valueHoldings(positions, prices)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/holding")
+		})
+	}
+}
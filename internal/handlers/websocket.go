@@ -2,9 +2,10 @@ package handlers
 
 import (
 	// "encoding/json"
-	"log"
-	"math/rand"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,69 +20,189 @@ type PriceUpdate struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
-// WebSocket upgrader
+// WebSocket upgrader. Allows every origin until AllowOrigins says otherwise.
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins (for development and demo)
 	},
 }
 
-// HandleWebSocket handles WebSocket connections for price updates
-func HandleWebSocket(c *gin.Context) {
+// errOriginNotAllowed is returned when a page's origin may not open a WebSocket
+const errOriginNotAllowed = "origin not allowed"
+
+// AllowOrigins restricts WebSocket upgrades to pages served by this host
+// and the comma-separated origins in list, e.g.
+// "https://app.example.com,http://localhost:3000". With allowAll every
+// origin is accepted. Requests without an Origin header don't come from a
+// browser page and are always accepted. Call once at startup.
+func AllowOrigins(list string, allowAll bool) {
+	upgrader.CheckOrigin = originChecker(list, allowAll)
+}
+
+// originChecker builds a CheckOrigin func for AllowOrigins
+func originChecker(list string, allowAll bool) func(*http.Request) bool {
+	allowed := make(map[string]bool)
+	for _, origin := range strings.Split(list, ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			allowed[strings.ToLower(origin)] = true
+		}
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if allowAll || origin == "" || allowed[strings.ToLower(origin)] {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// Default WebSocket keepalive timings, see Hub
+const (
+	// wsWriteWait bounds each write to a client
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long a client may go without answering a ping
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod is how often clients are pinged; shorter than wsPongWait
+	wsPingPeriod = 54 * time.Second
+)
+
+// wsMaxMessageSize bounds what a client may send; they only send control
+// frames
+const wsMaxMessageSize = 512
+
+// errTooManyConnections is returned when the hub is at MaxConnections
+const errTooManyConnections = "too many WebSocket connections"
+
+// HandleWebSocket returns a handler that registers each connection with
+// hub and relays its price updates
+func HandleWebSocket(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conn, client, closeClient := openClient(c, hub)
+		if conn == nil {
+			return
+		}
+		defer closeClient()
+
+		slog.Debug("WebSocket client connected", "remote_addr", c.ClientIP())
+
+		go hub.watchClient(conn, client)
+
+		err := hub.relayUpdates(conn, client, func(update PriceUpdate) (interface{}, bool) {
+			return update, true
+		})
+		if err != nil {
+			slog.Debug("WebSocket write failed", "remote_addr", c.ClientIP(), "error", err)
+		}
+	}
+}
+
+// openClient takes a connection slot, upgrades the request and registers
+// the connection with hub. On failure it responds itself and returns a nil
+// conn; otherwise call the returned func to release everything.
+func openClient(c *gin.Context, hub *Hub) (*websocket.Conn, *hubClient, func()) {
+	conn, closeConn := openConn(c, hub)
+	if conn == nil {
+		return nil, nil, nil
+	}
+
+	client := hub.addClient()
+	if client == nil {
+		closeConn()
+		return nil, nil, nil
+	}
+
+	return conn, client, func() {
+		hub.removeClient(client)
+		closeConn()
+	}
+}
+
+// openConn is openClient for streams that don't take price updates: the
+// connection counts against hub's limit but isn't registered for quotes
+func openConn(c *gin.Context, hub *Hub) (*websocket.Conn, func()) {
+	if !upgrader.CheckOrigin(c.Request) {
+		RespondError(c, http.StatusForbidden, errOriginNotAllowed)
+		return nil, nil
+	}
+	if !hub.acquireConn() {
+		RespondError(c, http.StatusServiceUnavailable, errTooManyConnections)
+		return nil, nil
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Println("WebSocket upgrade error:", err)
-		return
+		hub.releaseConn()
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return nil, nil
 	}
-	defer conn.Close()
 
-	log.Println("Client connected to WebSocket")
+	return conn, func() {
+		conn.Close()
+		hub.releaseConn()
+	}
+}
 
-	// Stock symbols to simulate
-	symbols := []string{"AAPL", "GOOGL", "MSFT", "TSLA", "AMZN"}
+// watchClient reads until the client goes away or misses a pong, then
+// unregisters it, which ends its relayUpdates loop
+func (h *Hub) watchClient(conn *websocket.Conn, client *hubClient) {
+	h.watchConn(conn, func() { h.removeClient(client) })
+}
 
-	// Initial prices
-	prices := map[string]float64{
-		"AAPL":  150.00,
-		"GOOGL": 140.00,
-		"MSFT":  380.00,
-		"TSLA":  250.00,
-		"AMZN":  180.00,
+// watchConn reads until the client goes away or misses a pong, then calls
+// gone
+func (h *Hub) watchConn(conn *websocket.Conn, gone func()) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	})
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			gone()
+			return
+		}
 	}
+}
 
-	// Send price updates every second
-	ticker := time.NewTicker(1 * time.Second)
+// relayUpdates writes each update that render turns into a message, and
+// pings the client in between, until the hub drops the client or a write
+// fails or times out
+func (h *Hub) relayUpdates(conn *websocket.Conn, client *hubClient, render func(PriceUpdate) (interface{}, bool)) error {
+	ticker := time.NewTicker(h.pingPeriod)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			// Pick random stock
-			symbol := symbols[rand.Intn(len(symbols))]
-
-			// Simulate price change (-2% to +2%)
-			changePercent := (rand.Float64() - 0.5) * 4
-			oldPrice := prices[symbol]
-			newPrice := oldPrice * (1 + changePercent/100)
-			prices[symbol] = newPrice
-
-			// Create update
-			update := PriceUpdate{
-				Symbol:    symbol,
-				Price:     newPrice,
-				Change:    changePercent,
-				Timestamp: time.Now(),
+		case update, ok := <-client.send:
+			if !ok {
+				// Unregistered: stopped, too slow, or gone
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+					time.Now().Add(h.writeWait))
+				return nil
 			}
-
-			// Send to client
-			if err := conn.WriteJSON(update); err != nil {
-				log.Println("WebSocket write error:", err)
-				return
+			msg, ok := render(update)
+			if !ok {
+				continue
+			}
+			if err := h.writeJSON(conn, msg); err != nil {
+				return err
 			}
 
-			log.Printf("Sent price update: %s = $%.2f (%.2f%%)",
-				symbol, newPrice, changePercent)
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.writeWait)); err != nil {
+				return err
+			}
 		}
 	}
 }
+
+// writeJSON writes v to conn, giving up after the hub's write wait
+func (h *Hub) writeJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(h.writeWait))
+	return conn.WriteJSON(v)
+}
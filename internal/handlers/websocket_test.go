@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+	"github.com/atharvakonge/stock-trading-simulator/internal/market"
+	"github.com/atharvakonge/stock-trading-simulator/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// fakeProvider hands out one channel per symbol that tests write to, and
+// reports prices tests set
+type fakeProvider struct {
+	channels map[string]chan market.Quote
+	prices   map[string]float64
+}
+
+func newFakeProvider() *fakeProvider {
+	fp := &fakeProvider{channels: make(map[string]chan market.Quote)}
+	for _, symbol := range market.Symbols() {
+		fp.channels[symbol] = make(chan market.Quote, 1)
+	}
+	return fp
+}
+
+func (fp *fakeProvider) GetPrice(symbol string) (float64, error) {
+	price, ok := fp.prices[symbol]
+	if !ok {
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
+	return price, nil
+}
+
+func (fp *fakeProvider) Subscribe(symbol string) (<-chan market.Quote, func()) {
+	return fp.channels[symbol], func() {}
+}
+
+// dialPrices connects a WebSocket client to the test server
+func dialPrices(t *testing.T, server *httptest.Server) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/prices", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	return conn
+}
+
+func TestHub_BroadcastsSameQuoteToAllClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider := newFakeProvider()
+
+	hub := NewHub(provider)
+	hub.Start()
+	defer hub.Stop()
+
+	router := gin.New()
+	router.GET("/ws/prices", HandleWebSocket(hub))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	first := dialPrices(t, server)
+	defer first.Close()
+	second := dialPrices(t, server)
+	defer second.Close()
+
+	// Keep publishing until both clients have registered and read a quote
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case provider.channels["MSFT"] <- market.Quote{Symbol: "MSFT", Price: 412.35, Timestamp: time.Now()}:
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}()
+
+	for i, conn := range []*websocket.Conn{first, second} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var update PriceUpdate
+		if err := conn.ReadJSON(&update); err != nil {
+			t.Fatalf("Client %d failed to read update: %v", i, err)
+		}
+		if update.Symbol != "MSFT" || update.Price != 412.35 {
+			t.Errorf("Client %d expected MSFT at 412.35, got %+v", i, update)
+		}
+	}
+
+	if q, ok := Prices.Get("MSFT"); !ok || q.Price != 412.35 {
+		t.Errorf("Expected hub to record MSFT at 412.35, got %+v", q)
+	}
+}
+
+func TestPortfolioStream_RevaluesOnTick(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider := newFakeProvider()
+
+	saved := Prices
+	Prices = market.NewPriceStore()
+	defer func() { Prices = saved }()
+
+	connected := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	hub := NewHub(provider)
+	hub.clock = clock.NewFake(connected)
+	hub.Start()
+	defer hub.Stop()
+
+	load := func(userID int) (float64, []models.Portfolio, error) {
+		return 1000.0, []models.Portfolio{
+			{UserID: userID, StockSymbol: "AAPL", Quantity: 10, AvgPurchasePrice: 100.0},
+		}, nil
+	}
+
+	router := gin.New()
+	router.GET("/ws/portfolio/:userId", portfolioStream(hub, load))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/portfolio/7", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	read := func() PortfolioValueUpdate {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var update PortfolioValueUpdate
+		if err := conn.ReadJSON(&update); err != nil {
+			t.Fatalf("Failed to read update: %v", err)
+		}
+		return update
+	}
+
+	// Unquoted holdings are valued at cost
+	if update := read(); update.UserID != 7 || update.TotalValue != 2000.0 || !update.Timestamp.Equal(connected) {
+		t.Fatalf("Expected initial value 2000 for user 7 at %v, got %+v", connected, update)
+	}
+
+	// The client is registered once the first update arrives. Ticks in
+	// symbols the user doesn't hold are not pushed.
+	provider.channels["MSFT"] <- market.Quote{Symbol: "MSFT", Price: 400.0, Timestamp: time.Now()}
+	provider.channels["AAPL"] <- market.Quote{Symbol: "AAPL", Price: 110.0, Timestamp: time.Now()}
+	if update := read(); update.TotalValue != 2100.0 || update.UnrealizedPL != 100.0 {
+		t.Errorf("Expected value 2100 after AAPL rose to 110, got %+v", update)
+	}
+
+	provider.channels["AAPL"] <- market.Quote{Symbol: "AAPL", Price: 90.0, Timestamp: time.Now()}
+	if update := read(); update.TotalValue != 1900.0 || update.HoldingsValue != 900.0 {
+		t.Errorf("Expected value 1900 after AAPL fell to 90, got %+v", update)
+	}
+}
+
+func TestHandleWebSocket_ConnectionLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hub := NewHub(newFakeProvider())
+	hub.MaxConnections = 1
+	hub.Start()
+	defer hub.Stop()
+
+	router := gin.New()
+	router.GET("/ws/prices", HandleWebSocket(hub))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	first := dialPrices(t, server)
+	defer first.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/prices", nil)
+	if err == nil {
+		t.Fatal("Expected the second connection to be refused")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %+v", resp)
+	}
+}
+
+func TestHandleWebSocket_DropsUnresponsiveClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hub := NewHub(newFakeProvider())
+	hub.pongWait, hub.pingPeriod = 100*time.Millisecond, 50*time.Millisecond
+	hub.Start()
+	defer hub.Stop()
+
+	router := gin.New()
+	router.GET("/ws/prices", HandleWebSocket(hub))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Never reads, so never answers pings
+	conn := dialPrices(t, server)
+	defer conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Connections() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the silent client to be dropped, still have %d connections", hub.Connections())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOriginChecker(t *testing.T) {
+	check := originChecker("https://app.example.com, http://localhost:3000/", false)
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://localhost:3000", true},
+		{"http://trader.local:8080", true}, // Same host as the request
+		{"", true},                         // Not a browser
+		{"https://evil.example.com", false},
+		{"http://localhost:4000", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://trader.local:8080/ws/prices", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := check(req); got != tt.want {
+			t.Errorf("Origin %q: got %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ws/prices", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	if !originChecker("", true)(req) {
+		t.Error("Expected allow-all to accept any origin")
+	}
+}
+
+func TestHandleWebSocket_RejectsDisallowedOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	saved := upgrader.CheckOrigin
+	AllowOrigins("https://app.example.com", false)
+	defer func() { upgrader.CheckOrigin = saved }()
+
+	hub := NewHub(newFakeProvider())
+	hub.Start()
+	defer hub.Stop()
+
+	router := gin.New()
+	router.GET("/ws/prices", HandleWebSocket(hub))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/prices"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example.com"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a disallowed origin, got %+v", resp)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://app.example.com"}})
+	if err != nil {
+		t.Fatalf("Expected an allowed origin to connect: %v", err)
+	}
+	conn.Close()
+}
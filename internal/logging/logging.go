@@ -0,0 +1,39 @@
+// Package logging configures the process-wide structured logger.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ParseLevel maps a LOG_LEVEL value (debug, info, warn, error) to a level,
+// defaulting to info
+func ParseLevel(value string) slog.Level {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Setup installs a logger writing to w as the slog default, which also
+// routes the standard log package through it. JSON is for release mode;
+// otherwise output is key=value text for reading in a terminal.
+func Setup(w io.Writer, level string, json bool) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler = slog.NewTextHandler(w, opts)
+	if json {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger
+}
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+		"loud":  slog.LevelInfo,
+	}
+	for value, want := range tests {
+		if got := ParseLevel(value); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestSetup_JSON(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	Setup(&buf, "warn", true)
+
+	slog.Info("dropped")
+	slog.Warn("trade failed", "user_id", 7)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "trade failed" || entry["user_id"] != float64(7) {
+		t.Errorf("Unexpected entry %v", entry)
+	}
+}
@@ -0,0 +1,114 @@
+package market
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+)
+
+// pricePoint is a price seen at a time
+type pricePoint struct {
+	at    time.Time
+	price float64
+}
+
+// CircuitBreaker trips a symbol's halt when its price moves more than
+// Percent, either way, within Window, and lifts it again after Cooldown.
+// Safe for concurrent use.
+type CircuitBreaker struct {
+	Percent  float64
+	Window   time.Duration
+	Cooldown time.Duration
+
+	halts *Halts
+	clock clock.Clock
+
+	mu      sync.Mutex
+	recent  map[string][]pricePoint // Oldest first, none older than Window
+	tripped map[string]time.Time    // When each tripped symbol resumes
+}
+
+// NewCircuitBreaker creates a breaker that trips symbols in halts
+func NewCircuitBreaker(percent float64, window, cooldown time.Duration, halts *Halts) *CircuitBreaker {
+	return &CircuitBreaker{
+		Percent:  percent,
+		Window:   window,
+		Cooldown: cooldown,
+		halts:    halts,
+		clock:    clock.Real(),
+		recent:   make(map[string][]pricePoint),
+		tripped:  make(map[string]time.Time),
+	}
+}
+
+// Observe records a new price for q's symbol, tripping the breaker if it
+// is more than Percent away from any price seen within Window. Prices seen
+// while tripped are ignored, unless the halt was lifted by hand. A nil
+// breaker does nothing.
+func (cb *CircuitBreaker) Observe(q Quote) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if _, ok := cb.tripped[q.Symbol]; ok {
+		if cb.halts.IsTripped(q.Symbol) {
+			return
+		}
+		// Resumed by hand before the cooldown ran out
+		delete(cb.tripped, q.Symbol)
+	}
+
+	now := cb.clock.Now()
+	points := cb.recent[q.Symbol]
+	for len(points) > 0 && now.Sub(points[0].at) > cb.Window {
+		points = points[1:]
+	}
+
+	for _, p := range points {
+		move := math.Abs(q.Price-p.price) / p.price * 100
+		if move > cb.Percent {
+			resumeAt := now.Add(cb.Cooldown)
+			cb.tripped[q.Symbol] = resumeAt
+			delete(cb.recent, q.Symbol)
+			cb.halts.Trip(q.Symbol)
+			slog.Warn("Circuit breaker tripped", "symbol", q.Symbol,
+				"move_percent", math.Round(move*100)/100, "from_price", p.price, "to_price", q.Price,
+				"resume_at", resumeAt)
+			return
+		}
+	}
+
+	cb.recent[q.Symbol] = append(points, pricePoint{at: now, price: q.Price})
+}
+
+// Expire lifts symbol's halt once its cooldown is over. Call it
+// periodically: a halted symbol's frozen price doesn't tick, so Observe
+// can't. A nil breaker does nothing.
+func (cb *CircuitBreaker) Expire(symbol string) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	resumeAt, ok := cb.tripped[symbol]
+	if !ok {
+		return
+	}
+	if !cb.halts.IsTripped(symbol) {
+		// Resumed by hand; there's nothing left to lift
+		delete(cb.tripped, symbol)
+		return
+	}
+	if cb.clock.Now().Before(resumeAt) {
+		return
+	}
+	delete(cb.tripped, symbol)
+	cb.halts.Untrip(symbol)
+	slog.Info("Circuit breaker reset", "symbol", symbol)
+}
@@ -0,0 +1,140 @@
+package market
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+)
+
+func TestCircuitBreaker_TripsAndResets(t *testing.T) {
+	halts := NewHalts()
+	cb := NewCircuitBreaker(10, time.Minute, 5*time.Minute, halts)
+	fake := clock.NewFake(time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC))
+	cb.clock = fake
+
+	// A steady climb never moves 10% within any minute
+	price := 100.0
+	for i := 0; i < 20; i++ {
+		cb.Observe(Quote{Symbol: "TSLA", Price: price})
+		price *= 1.02
+		fake.Advance(20 * time.Second)
+	}
+	if halts.IsHalted("TSLA") {
+		t.Fatal("Expected a gradual move not to trip the breaker")
+	}
+
+	// A crash of more than 10% within the window does
+	start := price
+	cb.Observe(Quote{Symbol: "TSLA", Price: start})
+	fake.Advance(10 * time.Second)
+	cb.Observe(Quote{Symbol: "TSLA", Price: start * 0.95})
+	fake.Advance(10 * time.Second)
+	if halts.IsHalted("TSLA") {
+		t.Fatal("Expected a 5% move not to trip the breaker")
+	}
+	cb.Observe(Quote{Symbol: "TSLA", Price: start * 0.89})
+	if !halts.IsHalted("TSLA") {
+		t.Fatal("Expected an 11% drop within the window to trip the breaker")
+	}
+	if halts.IsHalted("AAPL") {
+		t.Error("Expected other symbols to keep trading")
+	}
+
+	// Still halted until the cooldown is over
+	fake.Advance(5*time.Minute - time.Second)
+	cb.Expire("TSLA")
+	if !halts.IsHalted("TSLA") {
+		t.Fatal("Expected TSLA halted during the cooldown")
+	}
+
+	fake.Advance(time.Second)
+	cb.Expire("TSLA")
+	if halts.IsHalted("TSLA") {
+		t.Fatal("Expected TSLA to resume after the cooldown")
+	}
+
+	// Prices from before the halt don't count against it afterwards
+	cb.Observe(Quote{Symbol: "TSLA", Price: start})
+	if halts.IsHalted("TSLA") {
+		t.Error("Expected a fresh window after resuming")
+	}
+}
+
+func TestCircuitBreaker_KeepsManualHalt(t *testing.T) {
+	halts := NewHalts()
+	cb := NewCircuitBreaker(10, time.Minute, time.Minute, halts)
+	fake := clock.NewFake(time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC))
+	cb.clock = fake
+
+	cb.Observe(Quote{Symbol: "AAPL", Price: 100})
+	cb.Observe(Quote{Symbol: "AAPL", Price: 120})
+	halts.Halt("AAPL")
+
+	fake.Advance(time.Minute)
+	cb.Expire("AAPL")
+	if !halts.IsHalted("AAPL") {
+		t.Error("Expected the breaker's reset to leave a halt by hand in place")
+	}
+}
+
+func TestCircuitBreaker_ManualResume(t *testing.T) {
+	halts := NewHalts()
+	cb := NewCircuitBreaker(10, time.Minute, 5*time.Minute, halts)
+	fake := clock.NewFake(time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC))
+	cb.clock = fake
+
+	cb.Observe(Quote{Symbol: "AAPL", Price: 100})
+	cb.Observe(Quote{Symbol: "AAPL", Price: 120})
+	if !halts.IsHalted("AAPL") {
+		t.Fatal("Expected a 20% move to trip the breaker")
+	}
+
+	// Resumed by hand, the breaker watches prices again straight away
+	halts.Resume("AAPL")
+	fake.Advance(time.Second)
+	cb.Observe(Quote{Symbol: "AAPL", Price: 120})
+	cb.Observe(Quote{Symbol: "AAPL", Price: 96})
+	if !halts.IsHalted("AAPL") {
+		t.Error("Expected a 20% move after a manual resume to trip the breaker again")
+	}
+}
+
+func TestSimulatedProvider_CircuitBreaker(t *testing.T) {
+	cfg := DefaultSimulationConfig()
+	for symbol, params := range cfg {
+		params.Interval = Duration(5 * time.Millisecond)
+		cfg[symbol] = params
+	}
+	sp := NewSimulatedProvider(1, cfg)
+	sp.Halts = NewHalts()
+	sp.Breaker = NewCircuitBreaker(10, time.Minute, time.Minute, sp.Halts)
+	fake := clock.NewFake(time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC))
+	sp.clock = fake
+	sp.Breaker.clock = fake
+
+	// A sharp simulated move
+	sp.tick("AAPL", 0)
+	sp.tick("AAPL", -15)
+	if !sp.Halts.IsHalted("AAPL") {
+		t.Fatal("Expected the crash to halt AAPL")
+	}
+	frozen, _ := sp.GetPrice("AAPL")
+
+	sp.Start()
+	defer sp.Stop()
+	time.Sleep(50 * time.Millisecond)
+	if price, _ := sp.GetPrice("AAPL"); price != frozen {
+		t.Errorf("Expected AAPL frozen at %.2f while halted, got %.2f", frozen, price)
+	}
+
+	// The tick loop lifts the halt once the cooldown passes
+	fake.Advance(time.Minute)
+	deadline := time.Now().Add(time.Second)
+	for sp.Halts.IsHalted("AAPL") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected AAPL to resume after the cooldown")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
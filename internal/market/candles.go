@@ -0,0 +1,43 @@
+package market
+
+import "time"
+
+// Candle is the open, high, low and close of the prices in one interval
+type Candle struct {
+	Start time.Time `json:"start"`
+	Open  float64   `json:"open"`
+	High  float64   `json:"high"`
+	Low   float64   `json:"low"`
+	Close float64   `json:"close"`
+}
+
+// Aggregate buckets quotes, which must be in time order, into candles of
+// the given interval. Buckets are aligned to multiples of interval since
+// the Unix epoch; intervals with no quotes produce no candle.
+func Aggregate(quotes []Quote, interval time.Duration) []Candle {
+	candles := make([]Candle, 0)
+	if interval <= 0 {
+		return candles
+	}
+
+	for _, q := range quotes {
+		start := q.Timestamp.Truncate(interval)
+
+		if n := len(candles); n > 0 && candles[n-1].Start.Equal(start) {
+			c := &candles[n-1]
+			c.High = max(c.High, q.Price)
+			c.Low = min(c.Low, q.Price)
+			c.Close = q.Price
+			continue
+		}
+
+		candles = append(candles, Candle{
+			Start: start,
+			Open:  q.Price,
+			High:  q.Price,
+			Low:   q.Price,
+			Close: q.Price,
+		})
+	}
+	return candles
+}
@@ -0,0 +1,47 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregate(t *testing.T) {
+	base := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	at := func(seconds int, price float64) Quote {
+		return Quote{Symbol: "AAPL", Price: price, Timestamp: base.Add(time.Duration(seconds) * time.Second)}
+	}
+
+	quotes := []Quote{
+		// 09:30 bucket
+		at(0, 150.00), at(10, 152.50), at(30, 149.25), at(59, 151.00),
+		// 09:31 bucket has a single tick
+		at(75, 151.10),
+		// 09:32 skipped, 09:33 bucket
+		at(185, 153.00), at(190, 152.00),
+	}
+
+	got := Aggregate(quotes, time.Minute)
+	want := []Candle{
+		{Start: base, Open: 150.00, High: 152.50, Low: 149.25, Close: 151.00},
+		{Start: base.Add(time.Minute), Open: 151.10, High: 151.10, Low: 151.10, Close: 151.10},
+		{Start: base.Add(3 * time.Minute), Open: 153.00, High: 153.00, Low: 152.00, Close: 152.00},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d candles, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Candle %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestAggregate_Empty(t *testing.T) {
+	if got := Aggregate(nil, time.Minute); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty, non-nil slice, got %v", got)
+	}
+	if got := Aggregate([]Quote{{Price: 1, Timestamp: time.Now()}}, 0); len(got) != 0 {
+		t.Errorf("Expected no candles for a zero interval, got %v", got)
+	}
+}
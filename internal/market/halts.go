@@ -0,0 +1,89 @@
+package market
+
+import (
+	"sort"
+	"sync"
+)
+
+// Halts is the set of symbols whose trading is halted, safe for concurrent
+// use. A symbol is halted by hand with Halt, or tripped by a
+// CircuitBreaker; either one stops it trading. A nil *Halts halts nothing.
+type Halts struct {
+	mu      sync.RWMutex
+	manual  map[string]bool
+	tripped map[string]bool
+}
+
+// NewHalts creates a registry with nothing halted
+func NewHalts() *Halts {
+	return &Halts{manual: make(map[string]bool), tripped: make(map[string]bool)}
+}
+
+// Halt stops trading in symbol until Resume. Halting it again is a no-op.
+func (h *Halts) Halt(symbol string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.manual[symbol] = true
+}
+
+// Resume lets symbol trade again, lifting a tripped circuit breaker too.
+// Resuming one that isn't halted is a no-op.
+func (h *Halts) Resume(symbol string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.manual, symbol)
+	delete(h.tripped, symbol)
+}
+
+// Trip halts symbol on behalf of a circuit breaker until Untrip
+func (h *Halts) Trip(symbol string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tripped[symbol] = true
+}
+
+// Untrip lifts a circuit breaker halt. A halt by hand stays in place.
+func (h *Halts) Untrip(symbol string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.tripped, symbol)
+}
+
+// IsTripped reports whether a circuit breaker has symbol halted, and it
+// hasn't been resumed by hand since
+func (h *Halts) IsTripped(symbol string) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.tripped[symbol]
+}
+
+// IsHalted reports whether trading in symbol is halted
+func (h *Halts) IsHalted(symbol string) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.manual[symbol] || h.tripped[symbol]
+}
+
+// Halted returns the halted symbols, sorted
+func (h *Halts) Halted() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	symbols := make([]string, 0, len(h.manual)+len(h.tripped))
+	for symbol := range h.manual {
+		symbols = append(symbols, symbol)
+	}
+	for symbol := range h.tripped {
+		if !h.manual[symbol] {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
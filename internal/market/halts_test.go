@@ -0,0 +1,58 @@
+package market
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHalts_HaltAndResume(t *testing.T) {
+	h := NewHalts()
+	if h.IsHalted("AAPL") {
+		t.Error("Expected nothing halted at first")
+	}
+
+	h.Halt("TSLA")
+	h.Halt("AAPL")
+	h.Halt("AAPL")
+	if !h.IsHalted("AAPL") || !h.IsHalted("TSLA") || h.IsHalted("MSFT") {
+		t.Error("Expected only AAPL and TSLA halted")
+	}
+	if got := h.Halted(); !reflect.DeepEqual(got, []string{"AAPL", "TSLA"}) {
+		t.Errorf("Expected [AAPL TSLA], got %v", got)
+	}
+
+	h.Resume("AAPL")
+	h.Resume("MSFT")
+	if h.IsHalted("AAPL") || !h.IsHalted("TSLA") {
+		t.Error("Expected AAPL resumed and TSLA still halted")
+	}
+
+	var none *Halts
+	if none.IsHalted("AAPL") {
+		t.Error("Expected a nil registry to halt nothing")
+	}
+}
+
+func TestHalts_ConcurrentAccess(t *testing.T) {
+	h := NewHalts()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				h.Halt("MSFT")
+			} else {
+				h.Resume("MSFT")
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			h.IsHalted("MSFT")
+			h.Halted()
+		}()
+	}
+	wg.Wait()
+}
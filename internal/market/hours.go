@@ -0,0 +1,47 @@
+package market
+
+import (
+	"fmt"
+	"time"
+)
+
+// Hours is when the market trades: between Open and Close each day, in
+// Location, and optionally not on weekends. Unless Enabled it is always
+// open.
+type Hours struct {
+	Enabled        bool
+	Open           time.Duration // Since midnight
+	Close          time.Duration // Since midnight; after Open
+	Location       *time.Location
+	ClosedWeekends bool
+}
+
+// IsOpen reports whether the market trades at t. Open is inclusive and
+// Close exclusive.
+func (h Hours) IsOpen(t time.Time) bool {
+	if !h.Enabled {
+		return true
+	}
+
+	if h.Location != nil {
+		t = t.In(h.Location)
+	}
+	if h.ClosedWeekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+
+	// Wall-clock time, so daylight saving changes don't shift the session
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	return clock >= h.Open && clock < h.Close
+}
+
+// ParseClock parses a time of day like "09:30" into the time since
+// midnight
+func ParseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time of day must look like 09:30: %w", err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,53 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHours_IsOpen(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("No zone data: %v", err)
+	}
+	hours := Hours{
+		Enabled:        true,
+		Open:           9*time.Hour + 30*time.Minute,
+		Close:          16 * time.Hour,
+		Location:       newYork,
+		ClosedWeekends: true,
+	}
+
+	// 2024-01-17 is a Wednesday; New York is UTC-5 in January
+	tests := []struct {
+		name  string
+		hours Hours
+		at    time.Time
+		want  bool
+	}{
+		{"before open", hours, time.Date(2024, 1, 17, 9, 29, 59, 0, newYork), false},
+		{"at open", hours, time.Date(2024, 1, 17, 9, 30, 0, 0, newYork), true},
+		{"midday in UTC", hours, time.Date(2024, 1, 17, 17, 0, 0, 0, time.UTC), true},
+		{"at close", hours, time.Date(2024, 1, 17, 16, 0, 0, 0, newYork), false},
+		{"Saturday", hours, time.Date(2024, 1, 20, 12, 0, 0, 0, newYork), false},
+		{"Saturday, weekends open", Hours{Enabled: true, Open: hours.Open, Close: hours.Close, Location: newYork}, time.Date(2024, 1, 20, 12, 0, 0, 0, newYork), true},
+		{"disabled", Hours{}, time.Date(2024, 1, 20, 3, 0, 0, 0, newYork), true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.hours.IsOpen(tt.at); got != tt.want {
+			t.Errorf("%s: IsOpen(%v) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	if d, err := ParseClock("09:30"); err != nil || d != 9*time.Hour+30*time.Minute {
+		t.Errorf("Expected 9h30m, got %v (err=%v)", d, err)
+	}
+	for _, value := range []string{"", "9am", "25:00"} {
+		if _, err := ParseClock(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
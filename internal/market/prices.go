@@ -0,0 +1,56 @@
+package market
+
+import (
+	"sync"
+	"time"
+)
+
+// Quote is the latest known price for a symbol
+type Quote struct {
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	Change    float64   `json:"change"` // Percent change from the previous price
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PriceStore holds the latest quote per symbol, safe for concurrent use
+type PriceStore struct {
+	mu     sync.RWMutex
+	quotes map[string]Quote
+}
+
+// NewPriceStore creates an empty price store
+func NewPriceStore() *PriceStore {
+	return &PriceStore{
+		quotes: make(map[string]Quote),
+	}
+}
+
+// Set records the latest quote for its symbol
+func (ps *PriceStore) Set(q Quote) {
+	ps.mu.Lock()
+	ps.quotes[q.Symbol] = q
+	ps.mu.Unlock()
+}
+
+// Get returns the latest quote for a symbol
+func (ps *PriceStore) Get(symbol string) (Quote, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	q, ok := ps.quotes[symbol]
+	return q, ok
+}
+
+// Snapshot returns a copy of every latest quote, all read at the same
+// instant, so values computed from it agree even as prices keep ticking
+func (ps *PriceStore) Snapshot() map[string]Quote {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	snapshot := make(map[string]Quote, len(ps.quotes))
+	for symbol, q := range ps.quotes {
+		snapshot[symbol] = q
+	}
+	return snapshot
+}
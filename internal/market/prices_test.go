@@ -0,0 +1,64 @@
+package market
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriceStore_SetGet(t *testing.T) {
+	ps := NewPriceStore()
+
+	if _, ok := ps.Get("AAPL"); ok {
+		t.Error("Expected no quote before any update")
+	}
+
+	ps.Set(Quote{Symbol: "AAPL", Price: 150.0, Timestamp: time.Now()})
+	ps.Set(Quote{Symbol: "AAPL", Price: 151.5, Change: 1.0, Timestamp: time.Now()})
+
+	q, ok := ps.Get("AAPL")
+	if !ok || q.Price != 151.5 {
+		t.Errorf("Expected latest AAPL price 151.5, got %v (ok=%v)", q.Price, ok)
+	}
+}
+
+func TestPriceStore_ConcurrentAccess(t *testing.T) {
+	ps := NewPriceStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			ps.Set(Quote{Symbol: "MSFT", Price: float64(380 + i)})
+		}(i)
+		go func() {
+			defer wg.Done()
+			ps.Get("MSFT")
+		}()
+	}
+	wg.Wait()
+
+	if _, ok := ps.Get("MSFT"); !ok {
+		t.Error("Expected a MSFT quote after concurrent updates")
+	}
+}
+
+func TestPriceStore_Snapshot(t *testing.T) {
+	ps := NewPriceStore()
+	ps.Set(Quote{Symbol: "AAPL", Price: 150.0})
+	ps.Set(Quote{Symbol: "MSFT", Price: 380.0})
+
+	snapshot := ps.Snapshot()
+	ps.Set(Quote{Symbol: "AAPL", Price: 155.0})
+
+	if len(snapshot) != 2 || snapshot["AAPL"].Price != 150.0 || snapshot["MSFT"].Price != 380.0 {
+		t.Errorf("Expected the prices as of the snapshot, got %+v", snapshot)
+	}
+
+	// Changing the copy doesn't touch the store
+	delete(snapshot, "MSFT")
+	if _, ok := ps.Get("MSFT"); !ok {
+		t.Error("Expected the snapshot to be a copy")
+	}
+}
@@ -0,0 +1,185 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+)
+
+// MarketDataProvider is a source of prices. Implementations may simulate
+// prices, call an external API or replay recorded data.
+type MarketDataProvider interface {
+	// GetPrice returns the latest price for a symbol
+	GetPrice(symbol string) (float64, error)
+
+	// Subscribe streams quotes for a symbol until the returned cancel
+	// func is called
+	Subscribe(symbol string) (<-chan Quote, func())
+}
+
+// subscriberBuffer is how many quotes a slow subscriber may fall behind
+// before further quotes to it are dropped
+const subscriberBuffer = 16
+
+// SimulatedProvider random-walks prices for the known symbols. Each symbol
+// ticks on its own interval, moving by up to its volatility either way.
+// Each walk is driven by its own generator derived from the seed, so the
+// same seed always produces the same prices.
+type SimulatedProvider struct {
+	// Hours pauses the walks while the market is closed. Set before Start.
+	Hours Hours
+
+	// Halts, if set, freezes the price of each halted symbol until it
+	// resumes. Set before Start.
+	Halts *Halts
+
+	// Breaker, if set, watches every tick and halts symbols that move too
+	// far too fast. Set before Start.
+	Breaker *CircuitBreaker
+
+	seed     int64
+	config   SimulationConfig
+	clock    clock.Clock // Checked against Hours, and stamps quotes
+	stopCh   chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	prices map[string]float64
+	subs   map[string]map[chan Quote]struct{}
+	rngs   map[string]*rand.Rand // Each used only by its symbol's goroutine
+}
+
+// NewSimulatedProvider creates a provider whose price paths are determined
+// by seed and shaped by cfg. A nil cfg uses DefaultSimulationConfig.
+func NewSimulatedProvider(seed int64, cfg SimulationConfig) *SimulatedProvider {
+	if cfg == nil {
+		cfg = DefaultSimulationConfig()
+	}
+
+	prices := make(map[string]float64, len(cfg))
+	rngs := make(map[string]*rand.Rand, len(cfg))
+	for i, symbol := range Symbols() {
+		prices[symbol] = cfg[symbol].InitialPrice
+		rngs[symbol] = rand.New(rand.NewSource(seed + int64(i)))
+	}
+
+	return &SimulatedProvider{
+		seed:   seed,
+		config: cfg,
+		clock:  clock.Real(),
+		stopCh: make(chan struct{}),
+		prices: prices,
+		subs:   make(map[string]map[chan Quote]struct{}),
+		rngs:   rngs,
+	}
+}
+
+// Start begins a random walk per symbol
+func (sp *SimulatedProvider) Start() {
+	for _, symbol := range Symbols() {
+		go sp.run(symbol)
+	}
+}
+
+// Stop ends the random walks. Later calls are no-ops.
+func (sp *SimulatedProvider) Stop() {
+	sp.stopOnce.Do(func() { close(sp.stopCh) })
+}
+
+// Seed returns the seed the price paths were generated from
+func (sp *SimulatedProvider) Seed() int64 {
+	return sp.seed
+}
+
+// Config returns the walk parameters in effect
+func (sp *SimulatedProvider) Config() SimulationConfig {
+	return sp.config
+}
+
+func (sp *SimulatedProvider) run(symbol string) {
+	ticker := time.NewTicker(time.Duration(sp.config[symbol].Interval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sp.stopCh:
+			return
+		case <-ticker.C:
+			sp.Breaker.Expire(symbol)
+			if sp.Hours.IsOpen(sp.clock.Now()) && !sp.Halts.IsHalted(symbol) {
+				sp.step(symbol)
+			}
+		}
+	}
+}
+
+// step moves symbol by a random amount within its volatility
+func (sp *SimulatedProvider) step(symbol string) {
+	volatility := sp.config[symbol].Volatility
+	sp.tick(symbol, (sp.rngs[symbol].Float64()*2-1)*volatility)
+}
+
+// tick moves symbol by changePercent and publishes the quote
+func (sp *SimulatedProvider) tick(symbol string, changePercent float64) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	oldPrice := sp.prices[symbol]
+	newPrice, change := NextPrice(symbol, oldPrice, changePercent)
+	sp.prices[symbol] = newPrice
+
+	q := Quote{Symbol: symbol, Price: newPrice, Change: change, Timestamp: sp.clock.Now()}
+	sp.Breaker.Observe(q)
+	for ch := range sp.subs[symbol] {
+		select {
+		case ch <- q:
+		default:
+			log.Printf("Dropping %s quote for a slow subscriber", symbol)
+		}
+	}
+}
+
+// GetPrice returns the current simulated price
+func (sp *SimulatedProvider) GetPrice(symbol string) (float64, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	price, ok := sp.prices[symbol]
+	if !ok {
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
+	return price, nil
+}
+
+// Subscribe streams quotes for symbol until cancel is called
+func (sp *SimulatedProvider) Subscribe(symbol string) (<-chan Quote, func()) {
+	ch := make(chan Quote, subscriberBuffer)
+
+	sp.mu.Lock()
+	if sp.subs[symbol] == nil {
+		sp.subs[symbol] = make(map[chan Quote]struct{})
+	}
+	sp.subs[symbol][ch] = struct{}{}
+	sp.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			sp.mu.Lock()
+			delete(sp.subs[symbol], ch)
+			sp.mu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+// NextPrice applies a percent move to oldPrice, snaps the result to the
+// symbol's tick size and returns it with the effective percent change
+func NextPrice(symbol string, oldPrice, changePercent float64) (float64, float64) {
+	newPrice := SnapToTick(oldPrice*(1+changePercent/100), TickSize(symbol))
+	return newPrice, (newPrice - oldPrice) / oldPrice * 100
+}
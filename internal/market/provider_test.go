@@ -0,0 +1,172 @@
+package market
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/atharvakonge/stock-trading-simulator/internal/clock"
+)
+
+func TestNextPrice_SnapsToTick(t *testing.T) {
+	// TSLA has a $0.05 tick: 250 * 1.0013 = 250.325 → 250.35
+	price, change := NextPrice("TSLA", 250.0, 0.13)
+	if price != 250.35 {
+		t.Errorf("Expected TSLA price snapped to 250.35, got %v", price)
+	}
+
+	expectedChange := (250.35 - 250.0) / 250.0 * 100
+	if math.Abs(change-expectedChange) > 1e-9 {
+		t.Errorf("Expected change %.4f%%, got %.4f%%", expectedChange, change)
+	}
+
+	// AAPL has a $0.01 tick: 150 * 1.00123 = 150.1845 → 150.18
+	price, _ = NextPrice("AAPL", 150.0, 0.123)
+	if price != 150.18 {
+		t.Errorf("Expected AAPL price snapped to 150.18, got %v", price)
+	}
+}
+
+func TestSimulatedProvider_SubscribeAndCancel(t *testing.T) {
+	sp := NewSimulatedProvider(1, nil)
+
+	quotes, cancel := sp.Subscribe("AAPL")
+	sp.tick("AAPL", 1.0)
+
+	select {
+	case q := <-quotes:
+		if q.Symbol != "AAPL" || q.Price != 151.5 {
+			t.Errorf("Expected AAPL at 151.5, got %+v", q)
+		}
+	default:
+		t.Fatal("Expected a quote after a tick")
+	}
+
+	if price, err := sp.GetPrice("AAPL"); err != nil || price != 151.5 {
+		t.Errorf("Expected GetPrice 151.5, got %v (err=%v)", price, err)
+	}
+
+	cancel()
+	sp.tick("AAPL", 1.0)
+	select {
+	case q := <-quotes:
+		t.Errorf("Expected no quotes after cancel, got %+v", q)
+	default:
+	}
+
+	if _, err := sp.GetPrice("NOPE"); err == nil {
+		t.Error("Expected error for an unknown symbol")
+	}
+}
+
+func TestSimulatedProvider_PausesWhileClosed(t *testing.T) {
+	cfg := DefaultSimulationConfig()
+	for symbol, params := range cfg {
+		params.Interval = Duration(5 * time.Millisecond)
+		cfg[symbol] = params
+	}
+	sp := NewSimulatedProvider(1, cfg)
+	sp.Hours = Hours{Enabled: true, Open: 9 * time.Hour, Close: 17 * time.Hour, Location: time.UTC}
+
+	// A clock the test moves from night to midday
+	fake := clock.NewFake(time.Date(2024, 1, 17, 3, 0, 0, 0, time.UTC))
+	sp.clock = fake
+
+	quotes, cancel := sp.Subscribe("AAPL")
+	defer cancel()
+	sp.Start()
+	defer sp.Stop()
+
+	select {
+	case q := <-quotes:
+		t.Fatalf("Expected no ticks while closed, got %+v", q)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fake.Set(time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC))
+
+	select {
+	case <-quotes:
+	case <-time.After(time.Second):
+		t.Fatal("Expected ticks to resume once open")
+	}
+}
+
+// pricePath steps each symbol in turn n times and returns every symbol's
+// price after each step
+func pricePath(sp *SimulatedProvider, n int) [][]float64 {
+	path := make([][]float64, 0, n)
+	for i := 0; i < n; i++ {
+		sp.step(Symbols()[i%len(Symbols())])
+		prices := make([]float64, 0, len(Symbols()))
+		for _, symbol := range Symbols() {
+			price, _ := sp.GetPrice(symbol)
+			prices = append(prices, price)
+		}
+		path = append(path, prices)
+	}
+	return path
+}
+
+func TestSimulatedProvider_FreezesHaltedSymbols(t *testing.T) {
+	cfg := DefaultSimulationConfig()
+	for symbol, params := range cfg {
+		params.Interval = Duration(5 * time.Millisecond)
+		cfg[symbol] = params
+	}
+	sp := NewSimulatedProvider(1, cfg)
+	sp.Halts = NewHalts()
+	sp.Halts.Halt("AAPL")
+
+	halted, cancelHalted := sp.Subscribe("AAPL")
+	defer cancelHalted()
+	trading, cancelTrading := sp.Subscribe("MSFT")
+	defer cancelTrading()
+	sp.Start()
+	defer sp.Stop()
+
+	select {
+	case <-trading:
+	case <-time.After(time.Second):
+		t.Fatal("Expected MSFT to keep ticking")
+	}
+	select {
+	case q := <-halted:
+		t.Fatalf("Expected no AAPL ticks while halted, got %+v", q)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sp.Halts.Resume("AAPL")
+
+	select {
+	case <-halted:
+	case <-time.After(time.Second):
+		t.Fatal("Expected AAPL ticks once resumed")
+	}
+}
+
+func TestSimulatedProvider_SameSeedSamePath(t *testing.T) {
+	a := pricePath(NewSimulatedProvider(42, nil), 100)
+	b := pricePath(NewSimulatedProvider(42, nil), 100)
+
+	for i := range a {
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				t.Fatalf("Step %d, %s: seed 42 gave %v and %v", i, Symbols()[j], a[i][j], b[i][j])
+			}
+		}
+	}
+
+	c := pricePath(NewSimulatedProvider(43, nil), 100)
+	same := true
+	for i := range a {
+		for j := range a[i] {
+			if a[i][j] != c[i][j] {
+				same = false
+			}
+		}
+	}
+	if same {
+		t.Error("Expected a different seed to give a different path")
+	}
+}
@@ -0,0 +1,114 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Simulation defaults for symbols without their own parameters
+const (
+	DefaultVolatility   = 2.0 // Percent
+	DefaultTickInterval = time.Second
+)
+
+// Duration is a time.Duration that reads and writes JSON as a string
+// like "500ms"
+type Duration time.Duration
+
+// MarshalJSON writes d as a duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// SymbolParams shapes one symbol's simulated random walk
+type SymbolParams struct {
+	InitialPrice float64  `json:"initial_price"`
+	Volatility   float64  `json:"volatility"` // Largest percent move per tick, up or down
+	Interval     Duration `json:"interval"`   // Time between ticks
+}
+
+// SimulationConfig maps each symbol to its walk parameters
+type SimulationConfig map[string]SymbolParams
+
+// simulatedStartPrices seeds the random walk
+var simulatedStartPrices = map[string]float64{
+	"AAPL":  150.00,
+	"GOOGL": 140.00,
+	"MSFT":  380.00,
+	"TSLA":  250.00,
+	"AMZN":  180.00,
+}
+
+// DefaultSimulationConfig returns the parameters used when nothing is
+// overridden: the built-in start prices, DefaultVolatility and
+// DefaultTickInterval for every known symbol
+func DefaultSimulationConfig() SimulationConfig {
+	cfg := make(SimulationConfig, len(simulatedStartPrices))
+	for symbol, price := range simulatedStartPrices {
+		cfg[symbol] = SymbolParams{
+			InitialPrice: price,
+			Volatility:   DefaultVolatility,
+			Interval:     Duration(DefaultTickInterval),
+		}
+	}
+	return cfg
+}
+
+// LoadSimulationConfig reads per-symbol overrides from a JSON file shaped
+// like {"TSLA": {"volatility": 4, "interval": "500ms"}} and applies them
+// over the defaults. Omitted fields keep their default.
+func LoadSimulationConfig(path string) (SimulationConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading simulation config: %w", err)
+	}
+
+	var overrides SimulationConfig
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parsing simulation config: %w", err)
+	}
+
+	return DefaultSimulationConfig().merge(overrides)
+}
+
+// merge returns cfg with each non-zero override field applied, rejecting
+// unknown symbols and negative values
+func (cfg SimulationConfig) merge(overrides SimulationConfig) (SimulationConfig, error) {
+	for symbol, o := range overrides {
+		params, ok := cfg[symbol]
+		if !ok {
+			return nil, fmt.Errorf("simulation config: unknown symbol %s", symbol)
+		}
+		if o.InitialPrice < 0 || o.Volatility < 0 || o.Interval < 0 {
+			return nil, fmt.Errorf("simulation config: %s has a negative value", symbol)
+		}
+
+		if o.InitialPrice > 0 {
+			params.InitialPrice = o.InitialPrice
+		}
+		if o.Volatility > 0 {
+			params.Volatility = o.Volatility
+		}
+		if o.Interval > 0 {
+			params.Interval = o.Interval
+		}
+		cfg[symbol] = params
+	}
+	return cfg, nil
+}
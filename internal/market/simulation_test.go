@@ -0,0 +1,88 @@
+package market
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadSimulationConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "simulation.json")
+	data := `{"TSLA": {"volatility": 5, "interval": "250ms"}, "MSFT": {"initial_price": 400}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadSimulationConfig(path)
+	if err != nil {
+		t.Fatalf("LoadSimulationConfig: %v", err)
+	}
+
+	want := map[string]SymbolParams{
+		"TSLA": {InitialPrice: 250, Volatility: 5, Interval: Duration(250 * time.Millisecond)},
+		"MSFT": {InitialPrice: 400, Volatility: DefaultVolatility, Interval: Duration(DefaultTickInterval)},
+		"AAPL": {InitialPrice: 150, Volatility: DefaultVolatility, Interval: Duration(DefaultTickInterval)},
+	}
+	for symbol, params := range want {
+		if cfg[symbol] != params {
+			t.Errorf("%s: expected %+v, got %+v", symbol, params, cfg[symbol])
+		}
+	}
+
+	for name, bad := range map[string]string{
+		"unknown symbol": `{"NOPE": {"volatility": 1}}`,
+		"negative":       `{"AAPL": {"volatility": -1}}`,
+		"bad interval":   `{"AAPL": {"interval": "soon"}}`,
+	} {
+		os.WriteFile(path, []byte(bad), 0o644)
+		if _, err := LoadSimulationConfig(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSimulatedProvider_PerSymbolVolatility(t *testing.T) {
+	cfg := DefaultSimulationConfig()
+	cfg["TSLA"] = SymbolParams{InitialPrice: 250, Volatility: 8, Interval: Duration(time.Second)}
+	cfg["MSFT"] = SymbolParams{InitialPrice: 380, Volatility: 0.5, Interval: Duration(time.Second)}
+	sp := NewSimulatedProvider(7, cfg)
+
+	largest := func(symbol string) float64 {
+		quotes, cancel := sp.Subscribe(symbol)
+		defer cancel()
+
+		biggest := 0.0
+		for i := 0; i < subscriberBuffer; i++ {
+			sp.step(symbol)
+			q := <-quotes
+			biggest = math.Max(biggest, math.Abs(q.Change))
+		}
+		return biggest
+	}
+
+	tsla, msft := largest("TSLA"), largest("MSFT")
+	if msft > 0.5+0.01 {
+		t.Errorf("MSFT moved %.3f%%, beyond its 0.5%% volatility", msft)
+	}
+	if tsla <= msft {
+		t.Errorf("Expected TSLA (%.3f%%) to move more than MSFT (%.3f%%)", tsla, msft)
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	b, err := json.Marshal(SymbolParams{Interval: Duration(1500 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var back SymbolParams
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatalf("Round trip of %s failed: %v", b, err)
+	}
+	if back.Interval != Duration(1500*time.Millisecond) {
+		t.Errorf("Expected 1.5s, got %v", time.Duration(back.Interval))
+	}
+}
@@ -0,0 +1,109 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultTickSize is used for symbols without explicit metadata
+const DefaultTickSize = 0.01
+
+// SymbolInfo holds static metadata for a tradable symbol
+type SymbolInfo struct {
+	Symbol   string  `json:"symbol"`
+	Sector   string  `json:"sector"`
+	TickSize float64 `json:"tick_size"` // Minimum price increment
+}
+
+// UnknownSector groups symbols without metadata
+const UnknownSector = "unknown"
+
+// symbols is the metadata for every symbol the simulator knows about
+var symbols = map[string]SymbolInfo{
+	"AAPL":  {Symbol: "AAPL", Sector: "Technology", TickSize: 0.01},
+	"GOOGL": {Symbol: "GOOGL", Sector: "Communication Services", TickSize: 0.01},
+	"MSFT":  {Symbol: "MSFT", Sector: "Technology", TickSize: 0.01},
+	"TSLA":  {Symbol: "TSLA", Sector: "Consumer Discretionary", TickSize: 0.05},
+	"AMZN":  {Symbol: "AMZN", Sector: "Consumer Discretionary", TickSize: 0.01},
+}
+
+// Lookup returns the metadata for a symbol
+func Lookup(symbol string) (SymbolInfo, bool) {
+	info, ok := symbols[symbol]
+	return info, ok
+}
+
+// MaxSymbolLength is the longest symbol accepted, the width of the
+// stock_symbol columns
+const MaxSymbolLength = 10
+
+// CleanSymbol upper-cases and trims a client-supplied symbol, reporting
+// whether it looks like a symbol at all: 1 to MaxSymbolLength ASCII letters
+// and digits. Unlike NormalizeSymbol, it needn't be listed.
+func CleanSymbol(symbol string) (string, bool) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" || len(symbol) > MaxSymbolLength {
+		return symbol, false
+	}
+	for _, r := range symbol {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return symbol, false
+		}
+	}
+	return symbol, true
+}
+
+// NormalizeSymbol upper-cases and trims a client-supplied symbol and
+// reports whether the result is a known, tradable symbol
+func NormalizeSymbol(symbol string) (string, bool) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	_, ok := symbols[symbol]
+	return symbol, ok
+}
+
+// Symbols returns every known symbol in alphabetical order
+func Symbols() []string {
+	list := make([]string, 0, len(symbols))
+	for symbol := range symbols {
+		list = append(list, symbol)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// TickSize returns the tick size for a symbol, falling back to DefaultTickSize
+func TickSize(symbol string) float64 {
+	if info, ok := symbols[symbol]; ok && info.TickSize > 0 {
+		return info.TickSize
+	}
+	return DefaultTickSize
+}
+
+// Sector returns the sector for a symbol, or UnknownSector without metadata
+func Sector(symbol string) string {
+	if info, ok := symbols[symbol]; ok && info.Sector != "" {
+		return info.Sector
+	}
+	return UnknownSector
+}
+
+// SnapToTick rounds price to the nearest multiple of tick
+func SnapToTick(price, tick float64) float64 {
+	snapped := math.Round(price/tick) * tick
+	// Trim float noise (e.g. 150.05000000000001) back to cents
+	return math.Round(snapped*100) / 100
+}
+
+// ValidateLimitPrice rejects limit prices that aren't a multiple of the
+// symbol's tick size
+func ValidateLimitPrice(symbol string, price float64) error {
+	tick := TickSize(symbol)
+	steps := price / tick
+	if math.Abs(steps-math.Round(steps)) > 1e-6 {
+		return fmt.Errorf("limit price %.4g is not a multiple of the %.2f tick size for %s",
+			price, tick, symbol)
+	}
+	return nil
+}
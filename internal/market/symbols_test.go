@@ -0,0 +1,97 @@
+package market
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLimitPrice_OnTick(t *testing.T) {
+	if err := ValidateLimitPrice("TSLA", 250.15); err != nil {
+		t.Errorf("Expected 250.15 to be valid for TSLA, got error: %v", err)
+	}
+
+	if err := ValidateLimitPrice("AAPL", 150.01); err != nil {
+		t.Errorf("Expected 150.01 to be valid for AAPL, got error: %v", err)
+	}
+}
+
+func TestValidateLimitPrice_OffTick(t *testing.T) {
+	// TSLA trades in $0.05 increments
+	if err := ValidateLimitPrice("TSLA", 250.12); err == nil {
+		t.Error("Expected 250.12 to be rejected for TSLA")
+	}
+
+	if err := ValidateLimitPrice("AAPL", 150.005); err == nil {
+		t.Error("Expected 150.005 to be rejected for AAPL")
+	}
+}
+
+func TestTickSize_DefaultsForUnknownSymbol(t *testing.T) {
+	if tick := TickSize("UNKNOWN"); tick != DefaultTickSize {
+		t.Errorf("Expected default tick %.2f, got %.2f", DefaultTickSize, tick)
+	}
+}
+
+func TestSnapToTick(t *testing.T) {
+	tests := []struct {
+		price, tick, expected float64
+	}{
+		{150.004, 0.01, 150.00},
+		{150.006, 0.01, 150.01},
+		{250.12, 0.05, 250.10},
+		{250.13, 0.05, 250.15},
+	}
+
+	for _, tt := range tests {
+		if got := SnapToTick(tt.price, tt.tick); got != tt.expected {
+			t.Errorf("SnapToTick(%v, %v): expected %.2f, got %.2f",
+				tt.price, tt.tick, tt.expected, got)
+		}
+	}
+}
+
+func TestCleanSymbol(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"AAPL", "AAPL", true},
+		{" brk2 ", "BRK2", true}, // Needn't be listed
+		{"ABCDEFGHIJ", "ABCDEFGHIJ", true},
+		{"ABCDEFGHIJK", "ABCDEFGHIJK", false}, // Longer than MaxSymbolLength
+		{strings.Repeat("A", 10000), strings.Repeat("A", 10000), false},
+		{"AA PL", "AA PL", false},
+		{"AAPL;--", "AAPL;--", false},
+		{"ÄAPL", "ÄAPL", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := CleanSymbol(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("CleanSymbol(%.20q) = %.20q, %v; want %.20q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"AAPL", "AAPL", true},
+		{"aapl", "AAPL", true},
+		{" Msft ", "MSFT", true},
+		{"BANANA", "BANANA", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizeSymbol(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeSymbol(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package metrics holds the Prometheus collectors served on /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Trade outcomes, the "outcome" label of TradeTotal
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+var (
+	// TradeTotal counts executed trades by type (buy or sell) and outcome
+	TradeTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trade_total",
+		Help: "Trades processed by the worker pool, by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	// TradeDuration is how long a worker spends on each trade, retries and
+	// waiting for the user's lock included
+	TradeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "trade_duration_seconds",
+		Help:    "Time a worker spends processing one trade.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// WebSocketConnections is the number of open WebSocket connections,
+	// kept by the hub
+	WebSocketConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "websocket_connections",
+		Help: "Open WebSocket connections across every endpoint.",
+	})
+)
+
+func init() {
+	// Export every series from the start, so rates work before the first
+	// failure
+	for _, tradeType := range []string{"buy", "sell"} {
+		for _, outcome := range []string{OutcomeSuccess, OutcomeFailure} {
+			TradeTotal.WithLabelValues(tradeType, outcome)
+		}
+	}
+}
+
+// NewRegistry returns a registry holding this package's collectors. Add
+// the trade processor's gauges with its RegisterMetrics.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(TradeTotal, TradeDuration, WebSocketConnections)
+	return reg
+}
+
+// Handler serves reg in the Prometheus text format
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,131 @@
+// Package migrations creates and upgrades the database schema from SQL
+// files embedded in the binary.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the advisory lock key held while a migration is applied, so
+// several API instances starting at once don't race
+const lockID = 727312
+
+// Migration is one versioned schema change. Files are named
+// <version>_<name>.sql and applied in version order.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Load returns the embedded migrations sorted by version
+func Load() ([]Migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version number", entry.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		body, err := files.ReadFile(path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// RunMigrations applies every embedded migration not yet recorded in
+// schema_migrations. Each migration runs in its own transaction, so a
+// failure leaves the schema at the last good version.
+func RunMigrations(db *sql.DB) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            applied_at TIMESTAMP DEFAULT NOW()
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		applied, err := apply(db, m)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+		if applied {
+			log.Printf("Applied migration %s", m.Name)
+		}
+	}
+	return nil
+}
+
+// apply runs one migration unless it is already recorded. The check and
+// the migration share a transaction holding the advisory lock.
+func apply(db *sql.DB, m Migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)",
+		m.Version,
+	).Scan(&exists)
+	if err != nil || exists {
+		return false, err
+	}
+
+	if _, err = tx.Exec(m.SQL); err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+		m.Version, m.Name,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
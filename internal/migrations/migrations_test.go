@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoad_SortedByVersion(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("migration %s out of order after %s", migrations[i].Name, migrations[i-1].Name)
+		}
+	}
+
+	initial := migrations[0]
+	for _, table := range []string{"users", "portfolios", "trades"} {
+		if !strings.Contains(initial.SQL, "CREATE TABLE IF NOT EXISTS "+table) {
+			t.Errorf("initial migration %s does not create %s", initial.Name, table)
+		}
+	}
+}
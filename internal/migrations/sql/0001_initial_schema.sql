@@ -1,5 +1,4 @@
--- Stock Trading Simulator - Database Schema
--- Auto-runs on first container startup
+-- Stock Trading Simulator - initial schema
 
 -- Users table
 CREATE TABLE IF NOT EXISTS users (
@@ -30,14 +29,25 @@ CREATE TABLE IF NOT EXISTS trades (
     quantity INTEGER NOT NULL CHECK (quantity > 0),
     price DECIMAL(10,2) NOT NULL,
     total_amount DECIMAL(15,2) NOT NULL,
+    fee DECIMAL(10,2) NOT NULL DEFAULT 0, -- commission, on top of total_amount for buys and out of it for sells
+    realized_pl DECIMAL(15,2), -- sells only: proceeds less fee less the average cost of the shares sold
     status VARCHAR(20) DEFAULT 'COMPLETED',
-    created_at TIMESTAMP DEFAULT NOW()
+    created_at TIMESTAMP DEFAULT NOW(),
+    seq BIGINT, -- submission order, assigned by the API
+    note TEXT -- e.g. set when a stop order triggered the trade
 );
 
+-- Databases created by the old init.sql may predate these columns
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS seq BIGINT;
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS note TEXT;
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS fee DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS realized_pl DECIMAL(15,2);
+
 -- Indexes for query performance
 CREATE INDEX IF NOT EXISTS idx_trades_user_id ON trades(user_id);
 CREATE INDEX IF NOT EXISTS idx_trades_created_at ON trades(created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_portfolios_user_id ON portfolios(user_id);
+CREATE INDEX IF NOT EXISTS idx_trades_user_seq ON trades(user_id, seq DESC);
 
 -- Function to limit trades per user to 15 most recent
 CREATE OR REPLACE FUNCTION limit_user_trades()
@@ -48,7 +58,7 @@ BEGIN
     WHERE id IN (
         SELECT id FROM trades
         WHERE user_id = NEW.user_id
-        ORDER BY created_at DESC
+        ORDER BY seq DESC NULLS LAST, created_at DESC
         OFFSET 15
     );
     RETURN NEW;
@@ -61,12 +71,3 @@ CREATE TRIGGER enforce_trade_limit
 AFTER INSERT ON trades
 FOR EACH ROW
 EXECUTE FUNCTION limit_user_trades();
-
--- Demo user for testing
-INSERT INTO users (id, username, email, cash_balance) 
-VALUES (1, 'demo_user', 'demo@example.com', 10000.00)
-ON CONFLICT (id) DO UPDATE 
-SET cash_balance = EXCLUDED.cash_balance;
-
--- Success message
-SELECT 'Database initialized successfully with trade limit trigger!' as status;
\ No newline at end of file
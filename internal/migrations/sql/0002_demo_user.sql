@@ -0,0 +1,8 @@
+-- Demo user for testing
+INSERT INTO users (id, username, email, cash_balance)
+VALUES (1, 'demo_user', 'demo@example.com', 10000.00)
+ON CONFLICT (id) DO UPDATE
+SET cash_balance = EXCLUDED.cash_balance;
+
+-- Keep SERIAL ids clear of the explicit id above
+SELECT setval('users_id_seq', GREATEST((SELECT MAX(id) FROM users), 1));
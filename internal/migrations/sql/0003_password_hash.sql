@@ -0,0 +1,3 @@
+-- bcrypt hash set at registration. NULL for accounts created without a
+-- password (the demo user, imports), which can't log in.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash VARCHAR(60);
@@ -0,0 +1,4 @@
+-- Correlation ID of the API call that made the trade, matching the
+-- request_id in logs and the X-Request-ID response header
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS request_id VARCHAR(64);
+CREATE INDEX IF NOT EXISTS idx_trades_request_id ON trades(request_id);
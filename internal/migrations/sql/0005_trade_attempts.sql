@@ -0,0 +1,17 @@
+-- Audit log of every executed trade attempt, including rejections. No
+-- foreign key on user_id: attempts for unknown users are recorded too.
+CREATE TABLE IF NOT EXISTS trade_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    trade_type VARCHAR(4) NOT NULL,
+    quantity INTEGER NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    success BOOLEAN NOT NULL,
+    reason TEXT, -- why it failed; NULL on success
+    trade_id INTEGER, -- the resulting trade on success
+    request_id VARCHAR(64),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_attempts_user_created ON trade_attempts(user_id, created_at DESC);
@@ -0,0 +1,10 @@
+-- Every simulated tick, for charting. Rows older than the configured
+-- retention are deleted by the API.
+CREATE TABLE IF NOT EXISTS price_history (
+    id BIGSERIAL PRIMARY KEY,
+    stock_symbol VARCHAR(10) NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_symbol_time ON price_history(stock_symbol, recorded_at);
@@ -0,0 +1,13 @@
+-- Open buy lots, consumed oldest first by sells. trade_id has no foreign
+-- key because old trades are pruned by the trade limit trigger.
+CREATE TABLE IF NOT EXISTS portfolio_lots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    stock_symbol VARCHAR(10) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0), -- shares not yet sold
+    price DECIMAL(10,2) NOT NULL,
+    trade_id INTEGER,
+    bought_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_portfolio_lots_position ON portfolio_lots(user_id, stock_symbol, bought_at, id);
@@ -0,0 +1,11 @@
+-- Audit log of deposits and withdrawals
+CREATE TABLE IF NOT EXISTS cash_transactions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('DEPOSIT', 'WITHDRAW')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    balance_after DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_transactions_user_created ON cash_transactions(user_id, created_at DESC);
@@ -0,0 +1,10 @@
+-- Results of trades submitted with an Idempotency-Key, so a retry with the
+-- same key replays the result instead of trading again
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    key VARCHAR(64) NOT NULL,
+    result JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, key)
+);
@@ -0,0 +1,3 @@
+-- Currency an account is denominated in. There is no FX conversion; the
+-- code is carried through to responses.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
@@ -0,0 +1,3 @@
+-- Short positions are stored as negative quantities, with
+-- avg_purchase_price holding the average price the shares were sold at
+ALTER TABLE portfolios DROP CONSTRAINT IF EXISTS portfolios_quantity_check;
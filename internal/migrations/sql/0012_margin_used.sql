@@ -0,0 +1,2 @@
+-- Cash borrowed to buy each position, when trading on margin
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS margin_used DECIMAL(15,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,19 @@
+-- Resting limit and stop orders, so the order book survives a restart.
+-- OPEN orders are loaded back into the book on startup; FILLED and
+-- CANCELLED ones are kept for reference.
+CREATE TABLE IF NOT EXISTS orders (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    quantity INTEGER NOT NULL,
+    trade_type VARCHAR(4) NOT NULL,
+    order_type VARCHAR(20) NOT NULL,
+    limit_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    trigger_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'OPEN', -- OPEN, FILLED or CANCELLED
+    trade_id INTEGER, -- the resulting trade once FILLED
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_open ON orders(id) WHERE status = 'OPEN';
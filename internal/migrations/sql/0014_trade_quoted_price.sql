@@ -0,0 +1,3 @@
+-- The market price a MARKET order was quoted at, before slippage moved its
+-- fill. NULL for trades at a price the client gave.
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS quoted_price DECIMAL(10,2);
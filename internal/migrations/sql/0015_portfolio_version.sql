@@ -0,0 +1,3 @@
+-- Bumped on every change to a holding, so a trade that read it without
+-- locking can tell whether it changed before writing it back
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+-- Account resets are recorded in the ledger. A RESET entry's amount is the
+-- balance the account restarted with, and only entries after a user's
+-- latest reset count toward their net deposits.
+ALTER TABLE cash_transactions DROP CONSTRAINT IF EXISTS cash_transactions_type_check;
+ALTER TABLE cash_transactions ADD CONSTRAINT cash_transactions_type_check
+    CHECK (type IN ('DEPOSIT', 'WITHDRAW', 'RESET'));
+
+-- A reset to a zero starting balance is still recorded
+ALTER TABLE cash_transactions DROP CONSTRAINT IF EXISTS cash_transactions_amount_check;
+ALTER TABLE cash_transactions ADD CONSTRAINT cash_transactions_amount_check
+    CHECK (amount > 0 OR type = 'RESET');
@@ -0,0 +1,65 @@
+-- Running per-symbol totals of each user's trades. Trades are pruned to
+-- each user's 15 newest (see limit_user_trades), so anything summarizing
+-- a whole history is kept here instead, updated by trigger in the same
+-- transaction as every trade insert.
+CREATE TABLE IF NOT EXISTS trade_stats (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    trades INTEGER NOT NULL DEFAULT 0,
+    bought DECIMAL(15,2) NOT NULL DEFAULT 0, -- BUY total_amount, before fees
+    sold DECIMAL(15,2) NOT NULL DEFAULT 0,   -- SELL total_amount, before fees
+    fees DECIMAL(15,2) NOT NULL DEFAULT 0,
+    realized_pl DECIMAL(15,2) NOT NULL DEFAULT 0,
+    wins INTEGER NOT NULL DEFAULT 0,   -- Trades realizing a gain
+    losses INTEGER NOT NULL DEFAULT 0, -- Trades realizing a loss
+    UNIQUE(user_id, stock_symbol)
+);
+
+CREATE OR REPLACE FUNCTION record_trade_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO trade_stats (user_id, stock_symbol, trades, bought, sold, fees, realized_pl, wins, losses)
+    VALUES (
+        NEW.user_id,
+        NEW.stock_symbol,
+        1,
+        CASE WHEN NEW.trade_type = 'BUY' THEN NEW.total_amount ELSE 0 END,
+        CASE WHEN NEW.trade_type = 'SELL' THEN NEW.total_amount ELSE 0 END,
+        COALESCE(NEW.fee, 0),
+        COALESCE(NEW.realized_pl, 0),
+        CASE WHEN NEW.realized_pl > 0 THEN 1 ELSE 0 END,
+        CASE WHEN NEW.realized_pl < 0 THEN 1 ELSE 0 END
+    )
+    ON CONFLICT (user_id, stock_symbol) DO UPDATE SET
+        trades = trade_stats.trades + EXCLUDED.trades,
+        bought = trade_stats.bought + EXCLUDED.bought,
+        sold = trade_stats.sold + EXCLUDED.sold,
+        fees = trade_stats.fees + EXCLUDED.fees,
+        realized_pl = trade_stats.realized_pl + EXCLUDED.realized_pl,
+        wins = trade_stats.wins + EXCLUDED.wins,
+        losses = trade_stats.losses + EXCLUDED.losses;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS record_trade_stats ON trades;
+CREATE TRIGGER record_trade_stats
+AFTER INSERT ON trades
+FOR EACH ROW
+EXECUTE FUNCTION record_trade_stats();
+
+-- Start from whatever history survived pruning so far
+INSERT INTO trade_stats (user_id, stock_symbol, trades, bought, sold, fees, realized_pl, wins, losses)
+SELECT user_id,
+       stock_symbol,
+       COUNT(*),
+       COALESCE(SUM(total_amount) FILTER (WHERE trade_type = 'BUY'), 0),
+       COALESCE(SUM(total_amount) FILTER (WHERE trade_type = 'SELL'), 0),
+       COALESCE(SUM(fee), 0),
+       COALESCE(SUM(realized_pl), 0),
+       COUNT(*) FILTER (WHERE realized_pl > 0),
+       COUNT(*) FILTER (WHERE realized_pl < 0)
+FROM trades
+GROUP BY user_id, stock_symbol
+ON CONFLICT (user_id, stock_symbol) DO NOTHING;
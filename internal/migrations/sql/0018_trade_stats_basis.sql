@@ -0,0 +1,51 @@
+-- The cost basis of the shares each user's closing trades closed, so
+-- percent return keeps counting capital from trades since pruned. A sell's
+-- basis is its proceeds less fee and realized P&L; a short cover's is its
+-- cost plus both.
+ALTER TABLE trade_stats ADD COLUMN IF NOT EXISTS closed_basis DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION record_trade_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO trade_stats (user_id, stock_symbol, trades, bought, sold, fees, realized_pl, wins, losses, closed_basis)
+    VALUES (
+        NEW.user_id,
+        NEW.stock_symbol,
+        1,
+        CASE WHEN NEW.trade_type = 'BUY' THEN NEW.total_amount ELSE 0 END,
+        CASE WHEN NEW.trade_type = 'SELL' THEN NEW.total_amount ELSE 0 END,
+        COALESCE(NEW.fee, 0),
+        COALESCE(NEW.realized_pl, 0),
+        CASE WHEN NEW.realized_pl > 0 THEN 1 ELSE 0 END,
+        CASE WHEN NEW.realized_pl < 0 THEN 1 ELSE 0 END,
+        CASE WHEN NEW.realized_pl IS NULL THEN 0
+             WHEN NEW.trade_type = 'SELL' THEN NEW.total_amount - COALESCE(NEW.fee, 0) - NEW.realized_pl
+             ELSE NEW.total_amount + COALESCE(NEW.fee, 0) + NEW.realized_pl END
+    )
+    ON CONFLICT (user_id, stock_symbol) DO UPDATE SET
+        trades = trade_stats.trades + EXCLUDED.trades,
+        bought = trade_stats.bought + EXCLUDED.bought,
+        sold = trade_stats.sold + EXCLUDED.sold,
+        fees = trade_stats.fees + EXCLUDED.fees,
+        realized_pl = trade_stats.realized_pl + EXCLUDED.realized_pl,
+        wins = trade_stats.wins + EXCLUDED.wins,
+        losses = trade_stats.losses + EXCLUDED.losses,
+        closed_basis = trade_stats.closed_basis + EXCLUDED.closed_basis;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Start from whatever history survived pruning so far
+UPDATE trade_stats s
+SET closed_basis = t.basis
+FROM (
+    SELECT user_id,
+           stock_symbol,
+           SUM(CASE WHEN trade_type = 'SELL'
+                    THEN total_amount - fee - realized_pl
+                    ELSE total_amount + fee + realized_pl END) AS basis
+    FROM trades
+    WHERE realized_pl IS NOT NULL
+    GROUP BY user_id, stock_symbol
+) t
+WHERE s.user_id = t.user_id AND s.stock_symbol = t.stock_symbol;
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// BackupVersion is the current account backup format
+const BackupVersion = 1
+
+// AccountBackup is a complete snapshot of one user's state
+type AccountBackup struct {
+	Version          int               `json:"version"`
+	ExportedAt       time.Time         `json:"exported_at"`
+	User             User              `json:"user"`
+	Holdings         []Portfolio       `json:"holdings"`
+	Lots             []Lot             `json:"lots"` // Open buy lots; absent from older backups
+	Trades           []Trade           `json:"trades"`
+	Orders           []Order           `json:"orders"`            // Resting orders; absent from older backups
+	CashTransactions []CashTransaction `json:"cash_transactions"` // The cash ledger, oldest first; absent from older backups
+}
+
+// ImportRequest - restores a backup into a new user
+type ImportRequest struct {
+	Username string         `json:"username" binding:"required"`
+	Email    string         `json:"email" binding:"required"`
+	Backup   *AccountBackup `json:"backup" binding:"required"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Order types
+const (
+	OrderTypeMarket     = "MARKET"
+	OrderTypeLimit      = "LIMIT"
+	OrderTypeStopLoss   = "STOP_LOSS"   // Sell once the price falls to the trigger
+	OrderTypeTakeProfit = "TAKE_PROFIT" // Sell once the price rises to the trigger
+)
+
+// Persisted order statuses
+const (
+	OrderStatusOpen      = "OPEN"
+	OrderStatusFilled    = "FILLED"
+	OrderStatusCancelled = "CANCELLED"
+)
+
+// Order - a resting order waiting for the market price to reach it
+type Order struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	StockSymbol  string    `json:"stock_symbol"`
+	Quantity     int       `json:"quantity"`
+	TradeType    string    `json:"trade_type"` // BUY or SELL
+	OrderType    string    `json:"order_type"`
+	LimitPrice   float64   `json:"limit_price,omitempty"`
+	TriggerPrice float64   `json:"trigger_price,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
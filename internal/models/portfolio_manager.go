@@ -1,46 +1,85 @@
 package models
 
 import (
-	"sync"
+	"fmt"
+	"sync/atomic"
+	"time"
 )
 
+// lockShards is how many mutexes user locks are spread across. Users whose
+// IDs share a shard serialize with each other, which is harmless for
+// correctness; more shards means fewer such collisions.
+const lockShards = 256
+
 // PortfolioManager handles concurrent portfolio updates safely
-// Uses per-user locks instead of global lock
+// Uses per-user locks instead of global lock. Users are hashed onto a
+// fixed set of mutexes, so memory stays constant however many users trade.
 type PortfolioManager struct {
-	userLocks map[int]*sync.Mutex // Map of user_id → mutex
-	mapMutex  sync.RWMutex        // Protects the map itself
+	shards [lockShards]userLock
+}
+
+// userLock is one shard's mutex plus which user holds it, so UnlockUser
+// can catch unlocks that don't match a LockUser. The mutex is a one-slot
+// channel so TryLockUser can give up after a timeout.
+type userLock struct {
+	sem    chan struct{}
+	held   atomic.Bool
+	holder atomic.Int64
 }
 
 // NewPortfolioManager creates a new portfolio manager
 func NewPortfolioManager() *PortfolioManager {
-	return &PortfolioManager{
-		userLocks: make(map[int]*sync.Mutex),
+	pm := &PortfolioManager{}
+	for i := range pm.shards {
+		pm.shards[i].sem = make(chan struct{}, 1)
 	}
+	return pm
 }
 
-// LockUser locks the portfolio for a specific user
+// shard returns the lock guarding userID
+func (pm *PortfolioManager) shard(userID int) *userLock {
+	return &pm.shards[uint(userID)%lockShards]
+}
+
+// LockUser locks the portfolio for a specific user. A goroutine must not
+// lock a second user while holding one: the two may share a shard.
 func (pm *PortfolioManager) LockUser(userID int) {
-	// First, get or create mutex for this user
-	pm.mapMutex.Lock()
+	l := pm.shard(userID)
+	l.sem <- struct{}{}
+	l.acquired(userID)
+}
 
-	if pm.userLocks[userID] == nil {
-		pm.userLocks[userID] = &sync.Mutex{}
-	}
+// TryLockUser is LockUser giving up after timeout. It reports whether the
+// lock was acquired; only then must the caller UnlockUser.
+func (pm *PortfolioManager) TryLockUser(userID int, timeout time.Duration) bool {
+	l := pm.shard(userID)
 
-	userMutex := pm.userLocks[userID]
-	pm.mapMutex.Unlock()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 
-	// Now lock that user's mutex
-	userMutex.Lock()
+	select {
+	case l.sem <- struct{}{}:
+		l.acquired(userID)
+		return true
+	case <-timer.C:
+		return false
+	}
 }
 
-// UnlockUser unlocks the portfolio for a specific user
-func (pm *PortfolioManager) UnlockUser(userID int) {
-	pm.mapMutex.RLock()
-	userMutex := pm.userLocks[userID]
-	pm.mapMutex.RUnlock()
+// acquired records userID as the holder of a just-taken lock
+func (l *userLock) acquired(userID int) {
+	l.holder.Store(int64(userID))
+	l.held.Store(true)
+}
 
-	if userMutex != nil {
-		userMutex.Unlock()
+// UnlockUser unlocks the portfolio for a specific user. It panics if the
+// user's lock isn't held, e.g. on a double unlock, since carrying on would
+// release a lock some other trade relies on.
+func (pm *PortfolioManager) UnlockUser(userID int) {
+	l := pm.shard(userID)
+	if !l.held.Load() || l.holder.Load() != int64(userID) {
+		panic(fmt.Sprintf("UnlockUser(%d): lock not held", userID))
 	}
+	l.held.Store(false)
+	<-l.sem
 }
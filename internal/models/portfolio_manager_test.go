@@ -0,0 +1,154 @@
+package models
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPortfolioManager_MemoryBoundedByShards(t *testing.T) {
+	pm := NewPortfolioManager()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	// A map of per-user mutexes would hold 100k entries, several MB
+	for userID := 1; userID <= 100000; userID++ {
+		pm.LockUser(userID)
+		pm.UnlockUser(userID)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	if grown := int64(after.HeapAlloc) - int64(before.HeapAlloc); grown > 512*1024 {
+		t.Errorf("Expected locking 100k users not to grow the heap, grew %d bytes", grown)
+	}
+	runtime.KeepAlive(pm)
+}
+
+func TestPortfolioManager_DifferentShardsDontBlock(t *testing.T) {
+	pm := NewPortfolioManager()
+
+	pm.LockUser(1)
+	defer pm.UnlockUser(1)
+
+	done := make(chan struct{})
+	go func() {
+		pm.LockUser(2)
+		pm.UnlockUser(2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Locking user 2 blocked behind user 1")
+	}
+}
+
+func TestPortfolioManager_SameUserSerializes(t *testing.T) {
+	pm := NewPortfolioManager()
+
+	pm.LockUser(7)
+	acquired := make(chan struct{})
+	go func() {
+		pm.LockUser(7)
+		close(acquired)
+		pm.UnlockUser(7)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Second lock of user 7 acquired while the first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pm.UnlockUser(7)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Second lock of user 7 never acquired after unlock")
+	}
+}
+
+func TestPortfolioManager_ConcurrentFirstLocks(t *testing.T) {
+	pm := NewPortfolioManager()
+
+	// Run with -race: the unguarded counter is only safe if every
+	// goroutine got the same lock
+	const goroutines = 50
+	counter := 0
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			pm.LockUser(4242)
+			counter++
+			pm.UnlockUser(4242)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if counter != goroutines {
+		t.Errorf("Expected %d increments, got %d", goroutines, counter)
+	}
+}
+
+func TestPortfolioManager_UnlockWithoutLockPanics(t *testing.T) {
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected a panic", name)
+			}
+		}()
+		fn()
+	}
+
+	pm := NewPortfolioManager()
+	expectPanic("never locked", func() { pm.UnlockUser(1) })
+
+	pm.LockUser(1)
+	pm.UnlockUser(1)
+	expectPanic("double unlock", func() { pm.UnlockUser(1) })
+
+	// Users 1 and 1+lockShards share a shard
+	pm.LockUser(1)
+	expectPanic("other user in the shard", func() { pm.UnlockUser(1 + lockShards) })
+	pm.UnlockUser(1)
+}
+
+func TestPortfolioManager_TryLockUserTimesOut(t *testing.T) {
+	pm := NewPortfolioManager()
+
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		pm.LockUser(9)
+		close(held)
+		<-release
+		pm.UnlockUser(9)
+	}()
+	<-held
+
+	start := time.Now()
+	if pm.TryLockUser(9, 50*time.Millisecond) {
+		t.Fatal("Expected TryLockUser to time out while the lock is held")
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("Expected to wait out the timeout, gave up after %v", waited)
+	}
+
+	close(release)
+	if !pm.TryLockUser(9, time.Second) {
+		t.Fatal("Expected TryLockUser to succeed once the lock is released")
+	}
+	pm.UnlockUser(9)
+}
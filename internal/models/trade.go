@@ -8,16 +8,19 @@ type User struct {
 	Username    string    `json:"username"`
 	Email       string    `json:"email"`
 	CashBalance float64   `json:"cash_balance"`
+	Currency    string    `json:"currency"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-// Portfolio represents stocks owned by a user
+// Portfolio represents stocks owned by a user. A short position has a
+// negative Quantity, and AvgPurchasePrice is the average price sold at.
 type Portfolio struct {
 	ID               int       `json:"id"`
 	UserID           int       `json:"user_id"`
 	StockSymbol      string    `json:"stock_symbol"`
 	Quantity         int       `json:"quantity"`
 	AvgPurchasePrice float64   `json:"avg_purchase_price"`
+	MarginUsed       float64   `json:"margin_used"` // Cash borrowed to buy the position
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
@@ -30,21 +33,202 @@ type Trade struct {
 	Quantity    int       `json:"quantity"`
 	Price       float64   `json:"price"`
 	TotalAmount float64   `json:"total_amount"`
+	Fee         float64   `json:"fee"`
+	RealizedPL  *float64  `json:"realized_pl,omitempty"`  // Sells only
+	QuotedPrice *float64  `json:"quoted_price,omitempty"` // MARKET orders only: the price before slippage
 	Status      string    `json:"status"`
+	Note        string    `json:"note,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-// BuyRequest - what client sends to buy stocks
+// TradeAttempt is an audit record of one executed trade attempt, kept
+// whether or not the trade went through
+type TradeAttempt struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	StockSymbol string    `json:"stock_symbol"`
+	TradeType   string    `json:"trade_type"`
+	Quantity    int       `json:"quantity"`
+	Price       float64   `json:"price"`
+	Success     bool      `json:"success"`
+	Reason      string    `json:"reason,omitempty"`   // Failure reason
+	TradeID     *int      `json:"trade_id,omitempty"` // Set on success
+	RequestID   string    `json:"request_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CashTransaction is one deposit, withdrawal or account reset
+type CashTransaction struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	Type         string    `json:"type"` // "DEPOSIT", "WITHDRAW" or "RESET"
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Cash transaction types
+const (
+	CashDeposit  = "DEPOSIT"
+	CashWithdraw = "WITHDRAW"
+	CashReset    = "RESET" // Amount is the balance the account restarted with
+)
+
+// CashRequest is the body of a deposit or withdrawal
+type CashRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0,max=1000000000"`
+}
+
+// Trade types
+const (
+	TradeTypeBuy  = "BUY"
+	TradeTypeSell = "SELL"
+)
+
+// BuyRequest - what client sends to buy or sell stocks
 type BuyRequest struct {
+	UserID         int     `json:"user_id" binding:"required"`
+	StockSymbol    string  `json:"stock_symbol" binding:"required,max=10,alphanum"`
+	Quantity       int     `json:"quantity" binding:"required,min=1"`
+	Price          float64 `json:"price" binding:"required_unless=OrderType MARKET,omitempty,min=0.01"`
+	TradeType      string  `json:"trade_type" binding:"omitempty,oneof=BUY SELL"`                           // Empty means BUY
+	OrderType      string  `json:"order_type" binding:"omitempty,oneof=MARKET LIMIT STOP_LOSS TAKE_PROFIT"` // MARKET fills at the live price, ignoring Price; empty trusts Price; others rest Price as the limit or trigger
+	Note           string  `json:"-"`                                                                       // Set server-side and recorded on the trade
+	RequestID      string  `json:"-"`                                                                       // Correlation ID, set server-side and recorded on the trade
+	SellAll        bool    `json:"-"`                                                                       // Sell the whole position, whatever Quantity says
+	IdempotencyKey string  `json:"-"`                                                                       // From the Idempotency-Key header; repeats replay the first result
+	QuotedPrice    float64 `json:"-"`                                                                       // Set for MARKET orders: the market price Price was slipped from
+	DryRun         bool    `json:"-"`                                                                       // Validate and price the trade, then roll it back
+}
+
+// BatchRequest is a list of buys and sells submitted together. Items are
+// validated one by one, so an invalid item fails alone.
+type BatchRequest struct {
+	Trades []BuyRequest `json:"trades" binding:"required,min=1"`
+}
+
+// Lot is the unsold remainder of one buy, for FIFO cost basis
+type Lot struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	StockSymbol string    `json:"stock_symbol"`
+	Quantity    int       `json:"quantity"` // Shares not yet sold
+	Price       float64   `json:"price"`
+	BoughtAt    time.Time `json:"bought_at"`
+}
+
+// Holding - a portfolio position valued at the latest market price
+type Holding struct {
+	Portfolio
+	CurrentPrice     float64 `json:"current_price"`
+	CurrentValue     float64 `json:"current_value"`
+	UnrealizedPL     float64 `json:"unrealized_pl"`
+	PriceUnavailable bool    `json:"price_unavailable"` // No market price yet, valued at avg purchase price
+}
+
+// PortfolioResponse - what we send back to client
+type PortfolioResponse struct {
+	Portfolio         []Holding `json:"portfolio"`
+	CashBalance       float64   `json:"cash_balance"`
+	TotalValue        float64   `json:"total_value"`
+	TotalUnrealizedPL float64   `json:"total_unrealized_pl"`
+	Currency          string    `json:"currency"`
+}
+
+// LiquidateRequest - sell a whole position, or every position when
+// StockSymbol is empty
+type LiquidateRequest struct {
+	UserID      int    `json:"user_id" binding:"required"`
+	StockSymbol string `json:"stock_symbol" binding:"omitempty,max=10,alphanum"`
+}
+
+// ImpactRequest - a hypothetical trade to analyze without executing
+type ImpactRequest struct {
 	UserID      int     `json:"user_id" binding:"required"`
-	StockSymbol string  `json:"stock_symbol" binding:"required"`
+	StockSymbol string  `json:"stock_symbol" binding:"required,max=10,alphanum"`
+	TradeType   string  `json:"trade_type" binding:"required,oneof=BUY SELL"`
 	Quantity    int     `json:"quantity" binding:"required,min=1"`
 	Price       float64 `json:"price" binding:"required,min=0.01"`
 }
 
-// PortfolioResponse - what we send back to client
-type PortfolioResponse struct {
-	Portfolio   []Portfolio `json:"portfolio"`
-	CashBalance float64     `json:"cash_balance"`
-	TotalValue  float64     `json:"total_value"`
+// ImpactMetrics - account metrics at one point in time
+type ImpactMetrics struct {
+	BuyingPower      float64 `json:"buying_power"`
+	TotalValue       float64 `json:"total_value"`
+	PositionQuantity int     `json:"position_quantity"`
+	PositionValue    float64 `json:"position_value"`
+	PositionPercent  float64 `json:"position_percent"`  // Traded symbol's share of total value
+	MaxConcentration float64 `json:"max_concentration"` // Largest single holding's share of total value
+	NumHoldings      int     `json:"num_holdings"`
+	Diversification  float64 `json:"diversification"` // 1 - HHI of holding weights, 0 when undiversified
+}
+
+// ImpactResponse - account metrics before and after a hypothetical trade
+type ImpactResponse struct {
+	Before   ImpactMetrics `json:"before"`
+	After    ImpactMetrics `json:"after"`
+	Fee      float64       `json:"fee"` // Commission the trade would pay
+	Feasible bool          `json:"feasible"`
+	Reason   string        `json:"reason,omitempty"`
+}
+
+// ExposureGroup - holdings value for one symbol or sector
+type ExposureGroup struct {
+	Group       string  `json:"group"`
+	MarketValue float64 `json:"market_value"`
+	Percent     float64 `json:"percent"` // Share of total holdings value
+}
+
+// ExposureResponse - holdings grouped by symbol or sector
+type ExposureResponse struct {
+	By         string          `json:"by"`
+	Groups     []ExposureGroup `json:"groups"`
+	TotalValue float64         `json:"total_value"`
+}
+
+// AllocationSlice - one holding's, or cash's, share of the account
+type AllocationSlice struct {
+	Name        string  `json:"name"` // Stock symbol, or "CASH"
+	MarketValue float64 `json:"market_value"`
+	Percent     float64 `json:"percent"` // Share of total_value, rounded to 0.01
+}
+
+// AllocationResponse - an account split into holdings and cash, for a pie
+// chart
+type AllocationResponse struct {
+	Slices     []AllocationSlice `json:"slices"`
+	TotalValue float64           `json:"total_value"`
+}
+
+// BreakEvenResponse - the sell price at which a holding nets zero P&L
+type BreakEvenResponse struct {
+	StockSymbol      string  `json:"stock_symbol"`
+	Quantity         int     `json:"quantity"`
+	AvgPurchasePrice float64 `json:"avg_purchase_price"`
+	BreakEvenPrice   float64 `json:"break_even_price"`
+}
+
+// TradeSummary - aggregate statistics over a user's trade history
+type TradeSummary struct {
+	TotalTrades       int     `json:"total_trades"`
+	TotalBought       float64 `json:"total_bought"` // Spent on buys, before fees
+	TotalSold         float64 `json:"total_sold"`   // Received from sells, before fees
+	TotalFees         float64 `json:"total_fees"`
+	TotalRealizedPL   float64 `json:"total_realized_pl"`
+	MostTradedSymbol  string  `json:"most_traded_symbol,omitempty"` // By number of trades
+	MostTradedSymbolN int     `json:"most_traded_symbol_trades,omitempty"`
+	Wins              int     `json:"wins"`   // Closing trades with a realized gain
+	Losses            int     `json:"losses"` // Closing trades with a realized loss
+}
+
+// PerformanceResponse - account-level performance summary
+type PerformanceResponse struct {
+	CashBalance   float64 `json:"cash_balance"`
+	MarketValue   float64 `json:"market_value"` // Holdings at latest prices
+	CostBasis     float64 `json:"cost_basis"`   // Holdings at avg purchase price
+	UnrealizedPL  float64 `json:"unrealized_pl"`
+	RealizedPL    float64 `json:"realized_pl"`
+	TotalValue    float64 `json:"total_value"`
+	PercentReturn float64 `json:"percent_return"` // Total P&L over capital invested
+	Currency      string  `json:"currency"`
 }
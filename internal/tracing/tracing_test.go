@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSetup_NoEndpoint(t *testing.T) {
+	saved := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(saved)
+
+	shutdown, err := Setup(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); ok {
+		t.Error("Expected the no-op provider to stay installed without an endpoint")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
+
+func TestSetup_Endpoint(t *testing.T) {
+	saved := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(saved)
+
+	// The exporter doesn't connect until spans are flushed
+	shutdown, err := Setup(context.Background(), "http://localhost:4318")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Errorf("Expected an SDK provider to be installed, got %T", otel.GetTracerProvider())
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}